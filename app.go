@@ -10,12 +10,16 @@ package fiber
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"os"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,6 +31,7 @@ import (
 
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
 )
 
 // Version of current fiber package
@@ -62,6 +67,14 @@ type Storage interface {
 	Close() error
 }
 
+// StorageWriter is an optional interface a Storage can implement
+// to store large values without loading them into memory.
+type StorageWriter interface {
+	// Writer returns a writer for the given key along with an expiration value,
+	// 0 means no expiration. The value is stored when the writer is closed.
+	Writer(key string, exp time.Duration) (io.WriteCloser, error)
+}
+
 // ErrorHandler defines a function that will process all errors
 // returned from any handlers in the stack
 //
@@ -79,13 +92,23 @@ type Storage interface {
 type ErrorHandler = func(*Ctx, error) error
 
 // Error represents an error that occurred while handling a request.
+// ErrorCode and Details optionally describe the error for machines,
+// they are rendered by the DefaultErrorHandler for clients accepting JSON.
 type Error struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code      int                    `json:"code"`
+	Message   string                 `json:"message"`
+	ErrorCode string                 `json:"error_code,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
 }
 
 // App denotes the Fiber application.
 type App struct {
+	// Request statistics, see Stats.
+	// 64-bit fields first to keep them aligned for atomic access on 32-bit platforms
+	requestsServed uint64
+	bytesIn        uint64
+	bytesOut       uint64
+
 	mutex sync.Mutex
 	// Route stack divided by HTTP methods
 	stack [][]*Route
@@ -114,8 +137,30 @@ type App struct {
 	// Latest route & group
 	latestRoute *Route
 	latestGroup *Group
+	// Routes of the latest AddMethods call, the chained route options apply to all of them
+	latestRoutes []*Route
 	// TLS handler
 	tlsHandler *TLSHandler
+	// Number of requests currently being handled
+	inFlight int32
+	// Handlers that run before routing, see UsePre
+	preRoute *Route
+	// Signal which triggers a graceful restart, see EnableGracefulRestart
+	restartSignal os.Signal
+	// Open connections of the server
+	conns      map[net.Conn]struct{}
+	connsMutex sync.Mutex
+	// Indicates whether a route has its own body limit
+	hasRouteBodyLimit bool
+	// Contexts to look up the route of a request header, see headerReceived
+	headerCtxPool sync.Pool
+	// Indicates whether a route limits the number of uploaded files
+	hasRouteMaxFiles bool
+	// Global variables of the views, see SetViewGlobal
+	viewGlobals      Map
+	viewGlobalsMutex sync.RWMutex
+	// Custom route constraints by lowercase name, see RegisterCustomConstraint
+	customConstraints map[string]CustomConstraint
 }
 
 // Config is a struct holding the server settings.
@@ -159,23 +204,62 @@ type Config struct {
 	// Default: false
 	UnescapePath bool `json:"unescape_path"`
 
+	// When set to true, registering a route for a method and path which already has a route
+	// panics, instead of adding the handlers to the existing route. Paths are compared after
+	// the CaseSensitive and StrictRouting normalization, middleware registered with Use is ignored.
+	//
+	// Default: false
+	DetectRouteConflicts bool `json:"detect_route_conflicts"`
+
 	// Enable or disable ETag header generation, since both weak and strong etags are generated
 	// using the same hashing method (CRC-32). Weak ETags are the default when enabled.
 	//
 	// Default: false
 	ETag bool `json:"etag"`
 
+	// ETagGenerator overrides the default CRC-32 based ETag generation when ETag is enabled.
+	// It receives the response body and whether a weak ETag is requested and must return
+	// the complete quoted ETag value, e.g. `W/"abc"`. Returning an empty string omits the header.
+	//
+	// Default: nil
+	ETagGenerator func(body []byte, weak bool) string `json:"-"`
+
 	// Max body size that the server accepts.
 	// -1 will decline any body size
+	// Routes and groups can override the limit with BodyLimit.
 	//
 	// Default: 4 * 1024 * 1024
 	BodyLimit int `json:"body_limit"`
 
+	// fasthttp reads the body of requests with several Content-Length headers with the last length,
+	// while a proxy in front of the app may have used another one, so that both disagree about
+	// where the next request on the connection starts (request smuggling). When set to true,
+	// requests with differing lengths are answered with 400 Bad Request before any handler
+	// is executed and the connection is closed, so that no further request is read from it.
+	// The request itself has already been read with the last length by then.
+	// Identical lengths are accepted, comma separated lists are rejected by fasthttp anyway.
+	//
+	// Default: false
+	RejectConflictingContentLength bool `json:"reject_conflicting_content_length"`
+
 	// Maximum number of concurrent connections.
 	//
 	// Default: 256 * 1024
 	Concurrency int `json:"concurrency"`
 
+	// Maximum number of requests that are handled at the same time.
+	// Requests exceeding this limit are answered with 503 Service Unavailable
+	// instead of being queued. A value of 0 disables the limit.
+	//
+	// Default: 0
+	MaxConcurrentRequests int `json:"max_concurrent_requests"`
+
+	// Value of the Retry-After header that is sent along with the 503 Service
+	// Unavailable response when the MaxConcurrentRequests limit is exceeded.
+	//
+	// Default: 1 * time.Second
+	ConcurrencyRetryAfter time.Duration `json:"concurrency_retry_after"`
+
 	// Views is the interface that wraps the Render function.
 	//
 	// Default: nil
@@ -218,6 +302,14 @@ type Config struct {
 	// Default: 4096
 	ReadBufferSize int `json:"read_buffer_size"`
 
+	// Maximum length of the request URI. Requests with a longer URI are
+	// answered with 414 Request-URI Too Long before routing. The ReadBufferSize
+	// is increased if it is too small to read a request line of this length.
+	// A value of 0 disables the limit.
+	//
+	// Default: 0
+	MaxURLLength int `json:"max_url_length"`
+
 	// Per-connection buffer size for responses' writing.
 	//
 	// Default: 4096
@@ -250,6 +342,33 @@ type Config struct {
 	// Default: DefaultErrorHandler
 	ErrorHandler ErrorHandler `json:"-"`
 
+	// PanicResponseBody is the response body the DefaultErrorHandler sends along with
+	// the 500 status code for panics recovered by the recover middleware.
+	// The panic value itself is never sent to the client unless ExposePanicMessage is set.
+	//
+	// Default: "Internal Server Error"
+	PanicResponseBody string `json:"panic_response_body"`
+
+	// When set to true, the DefaultErrorHandler sends the message of recovered panics
+	// to the client instead of PanicResponseBody. This may leak internal information.
+	//
+	// Default: false
+	ExposePanicMessage bool `json:"expose_panic_message"`
+
+	// NotFoundHandler is executed instead of returning the default 404 error when no route
+	// matches the request. The status code is set to 404, or to 405 Method Not Allowed
+	// along with the Allow header if routes for other methods match the path.
+	//
+	// Default: nil
+	NotFoundHandler Handler `json:"-"`
+
+	// UnsupportedMediaTypeHandler is executed when c.BodyParser encounters a content type
+	// it cannot decode. The returned error is passed back to the caller of c.BodyParser,
+	// which allows to describe the supported content types in the response.
+	//
+	// Default: nil, which results in ErrUnprocessableEntity
+	UnsupportedMediaTypeHandler func(c *Ctx, contentType string) error `json:"-"`
+
 	// When set to true, disables keep-alive connections.
 	// The server will close incoming connections after sending the first response to client.
 	//
@@ -295,6 +414,21 @@ type Config struct {
 	// Server pre parses multipart form data by default.
 	DisablePreParseMultipartForm bool
 
+	// When set to true, c.Body() returns the request body as it was sent,
+	// even if it is compressed according to the Content-Encoding header,
+	// and c.BodyParser() rejects compressed bodies with 415 Unsupported Media Type.
+	//
+	// Default: false
+	DisableBodyDecompression bool `json:"disable_body_decompression"`
+
+	// Max size of request bodies after decompression, which guards against decompression bombs.
+	// Decompression stops as soon as the limit is exceeded and c.BodyParser() and
+	// c.DecompressedBody() return ErrRequestEntityTooLarge, c.Body() returns its message.
+	// -1 disables the limit.
+	//
+	// Default: 0, which uses the BodyLimit
+	MaxDecompressedBodySize int `json:"max_decompressed_body_size"`
+
 	// Aggressively reduces memory usage at the cost of higher CPU usage
 	// if set to true.
 	//
@@ -320,6 +454,19 @@ type Config struct {
 	// Default: json.Marshal
 	JSONEncoder utils.JSONMarshal `json:"-"`
 
+	// JSONStreamEncoder is used by c.JSONStream to encode values while the response body is sent,
+	// without marshaling them into an intermediate buffer first.
+	//
+	// Default: json.NewEncoder(w).Encode
+	JSONStreamEncoder func(w io.Writer, v interface{}) error `json:"-"`
+
+	// When set to true, the default JSON encoders don't escape the HTML characters <, > and &
+	// in strings, which is only needed if the JSON is embedded into HTML, e.g. for API responses.
+	// It has no effect on a custom JSONEncoder or JSONStreamEncoder.
+	//
+	// Default: false
+	DisableJSONEscapeHTML bool `json:"disable_json_escape_html"`
+
 	// When set by an external client of Fiber it will use the provided implementation of a
 	// JSONUnmarshal
 	//
@@ -327,6 +474,13 @@ type Config struct {
 	// Default: json.Unmarshal
 	JSONDecoder utils.JSONUnmarshal `json:"-"`
 
+	// When set to true, the default JSON decoder decodes numbers into a json.Number
+	// instead of a float64, so large integers and precise decimals are not mangled.
+	// It has no effect if a custom JSONDecoder is provided.
+	//
+	// Default: false
+	JSONDecoderUseNumber bool `json:"json_decoder_use_number"`
+
 	// XMLEncoder set by an external client of Fiber it will use the provided implementation of a
 	// XMLMarshal
 	//
@@ -350,14 +504,17 @@ type Config struct {
 	// all headers that could be spoofed.
 	// If request ip in TrustedProxies whitelist then:
 	//   1. c.Protocol() get value from X-Forwarded-Proto, X-Forwarded-Protocol, X-Forwarded-Ssl or X-Url-Scheme header
-	//   2. c.IP() get value from ProxyHeader header.
+	//   2. c.IP() get value from ProxyHeader header. For X-Forwarded-For, the chain is walked from right to left
+	//    and the first address which is not in TrustedProxies is returned.
 	//   3. c.Hostname() get value from X-Forwarded-Host header
+	//   4. c.Port() get value from X-Forwarded-Port header
 	// But if request ip NOT in Trusted Proxies whitelist then:
 	//   1. c.Protocol() WON't get value from X-Forwarded-Proto, X-Forwarded-Protocol, X-Forwarded-Ssl or X-Url-Scheme header,
 	//    will return https in case when tls connection is handled by the app, of http otherwise
 	//   2. c.IP() WON'T get value from ProxyHeader header, will return RemoteIP() from fasthttp context
 	//   3. c.Hostname() WON'T get value from X-Forwarded-Host header, fasthttp.Request.URI().Host()
 	//    will be used to get the hostname.
+	//   4. c.Port() WON'T get value from X-Forwarded-Port header, will return the remote port
 	//
 	// Default: false
 	EnableTrustedProxyCheck bool `json:"enable_trusted_proxy_check"`
@@ -384,6 +541,46 @@ type Config struct {
 	//
 	// Optional. Default: DefaultColors
 	ColorScheme Colors `json:"color_scheme"`
+
+	// When set to true, c.Accepts, c.AcceptsCharsets, c.AcceptsEncodings and c.AcceptsLanguages
+	// return an empty string if the request has no corresponding Accept header,
+	// instead of the first offer. Clients have to specify what they accept.
+	//
+	// Default: false
+	StrictNegotiation bool `json:"strict_negotiation"`
+
+	// Browsers reject cookies with SameSite=None that aren't Secure, therefore c.Cookie sets
+	// the Secure attribute automatically for them. When set to true, the cookie isn't set
+	// instead and c.SetCookie returns ErrCookieSameSiteNoneInsecure.
+	//
+	// Default: false
+	RejectInsecureSameSiteNone bool `json:"reject_insecure_same_site_none"`
+
+	// TimeLayouts are tried in order to parse time.Time fields by c.BodyParser for form bodies,
+	// c.QueryParser and c.ReqHeaderParser, e.g. time.RFC3339 and "2006-01-02".
+	// Values consisting of digits only are Unix timestamps in seconds if no layout matches.
+	// JSON and XML bodies are decoded by their decoders.
+	//
+	// Default: nil, which parses time.Time fields as RFC 3339
+	TimeLayouts []string `json:"time_layouts"`
+
+	// SendFile defines the options used when serving files with c.SendFile.
+	//
+	// Optional. Default: SendFile{IndexNames: []string{"index.html"}, CompressibleTypes: DefaultCompressibleTypes,
+	// CompressMinSize: DefaultSendFileCompressMinSize, CompressMaxSize: DefaultSendFileCompressMaxSize}
+	SendFile SendFile `json:"send_file"`
+
+	// CORSPreflight defines how CORS preflight requests are answered
+	// without invoking any handler, if enabled.
+	//
+	// Optional. Default: CORSPreflight{AllowOrigins: []string{"*"}}
+	CORSPreflight CORSPreflight `json:"cors_preflight"`
+
+	// MethodOverride defines how POST requests, e.g. of HTML forms,
+	// can emulate other HTTP methods before routing, if enabled.
+	//
+	// Optional. Default: MethodOverride{FormField: "_method", Header: HeaderXHTTPMethodOverride}
+	MethodOverride MethodOverride `json:"method_override"`
 }
 
 // Static defines configuration options when defining static assets.
@@ -427,6 +624,104 @@ type Static struct {
 	Next func(c *Ctx) bool
 }
 
+// SendFile defines configuration options when serving files with c.SendFile.
+type SendFile struct {
+	// The directory c.SendFile is restricted to. Relative paths are resolved against it
+	// and files outside of it, also when reached via symlinks, are rejected with 403 Forbidden.
+	// Optional. Default value "" (no restriction).
+	Root string `json:"root"`
+
+	// The names of the index files to look for, in order, when the given path is a directory.
+	// If none of them exists, c.SendFile returns a 404 error.
+	// Optional. Default value []string{"index.html"}.
+	IndexNames []string `json:"index_names"`
+
+	// The content types that are compressed on the fly when compression is enabled
+	// and the client accepts it. A trailing "/" matches all subtypes, e.g. "text/".
+	// Files of other types, like images or videos, are always sent as they are.
+	// Optional. Default value DefaultCompressibleTypes.
+	CompressibleTypes []string `json:"compressible_types"`
+
+	// Files smaller than this size (in bytes) are never compressed,
+	// as the compression overhead exceeds the benefit.
+	// Optional. Default value DefaultSendFileCompressMinSize.
+	CompressMinSize int64 `json:"compress_min_size"`
+
+	// Files larger than this size (in bytes) are gzipped while they are streamed to the client,
+	// instead of being compressed once into a cached file next to the original file.
+	// Range requests for these files are answered uncompressed.
+	// Optional. Default value DefaultSendFileCompressMaxSize.
+	CompressMaxSize int64 `json:"compress_max_size"`
+
+	// When set to true, files are sent with "Cache-Control: public, max-age=31536000, immutable",
+	// e.g. for fingerprinted assets whose URL changes with the content.
+	// No ETag is generated and conditional requests are always answered with the file.
+	// Optional. Default value false.
+	Immutable bool `json:"immutable"`
+}
+
+// CORSPreflight defines configuration options for answering CORS preflight requests.
+type CORSPreflight struct {
+	// When set to true, OPTIONS requests with an Access-Control-Request-Method header
+	// are answered with 204 No Content if routes for other methods match the path.
+	// The allowed methods are the methods of these routes. Paths with their own
+	// OPTIONS route are left to its handler.
+	// Optional. Default value false.
+	Enable bool `json:"enable"`
+
+	// The origins that may access the resources, "*" allows all origins.
+	// Disallowed origins get no Access-Control-Allow-* headers.
+	// Optional. Default value []string{"*"}.
+	AllowOrigins []string `json:"allow_origins"`
+
+	// The request headers that can be used when making the actual request.
+	// If empty, the Access-Control-Request-Headers of the preflight are allowed.
+	// Optional. Default value nil.
+	AllowHeaders []string `json:"allow_headers"`
+
+	// When set to true, the actual request can be made using credentials.
+	// New panics if the wildcard origin "*" is allowed as well, set the origins explicitly.
+	// Optional. Default value false.
+	AllowCredentials bool `json:"allow_credentials"`
+
+	// How long (in seconds) the results of a preflight request can be cached.
+	// Optional. Default value 0.
+	MaxAge int `json:"max_age"`
+}
+
+// MethodOverride defines configuration options for overriding the method of POST requests.
+type MethodOverride struct {
+	// When set to true, the method of POST requests is replaced by the method
+	// given in the header or the form field before routing.
+	// Unknown methods are ignored and the request keeps its original method.
+	// Optional. Default value false.
+	Enable bool `json:"enable"`
+
+	// The form field containing the method, the header takes precedence.
+	// Only urlencoded bodies are read, after the body limit of the route has been checked.
+	// Optional. Default value "_method".
+	FormField string `json:"form_field"`
+
+	// The request header containing the method.
+	// Optional. Default value HeaderXHTTPMethodOverride.
+	Header string `json:"header"`
+}
+
+// DefaultCompressibleTypes are the content types c.SendFile compresses by default,
+// New copies them, so changes only affect apps created afterwards.
+var DefaultCompressibleTypes = []string{
+	"text/",
+	MIMEApplicationJavaScript,
+	MIMEApplicationJSON,
+	MIMEApplicationXML,
+	"application/xhtml+xml",
+	"application/wasm",
+	"image/svg+xml",
+	"image/x-icon",
+	"font/ttf",
+	"font/otf",
+}
+
 // RouteMessage is some message need to be print when server starts
 type RouteMessage struct {
 	name     string
@@ -437,22 +732,34 @@ type RouteMessage struct {
 
 // Default Config values
 const (
-	DefaultBodyLimit            = 4 * 1024 * 1024
-	DefaultConcurrency          = 256 * 1024
-	DefaultReadBufferSize       = 4096
-	DefaultWriteBufferSize      = 4096
-	DefaultCompressedFileSuffix = ".fiber.gz"
+	DefaultBodyLimit             = 4 * 1024 * 1024
+	DefaultConcurrency           = 256 * 1024
+	DefaultConcurrencyRetryAfter = 1 * time.Second
+	DefaultReadBufferSize        = 4096
+	DefaultWriteBufferSize       = 4096
+	DefaultCompressedFileSuffix  = ".fiber.gz"
+
+	DefaultSendFileCompressMinSize = 1024
+	DefaultSendFileCompressMaxSize = 8 * 1024 * 1024
 )
 
-// DefaultErrorHandler that process return errors from handlers
+// DefaultErrorHandler that process return errors from handlers,
+// the error is rendered as JSON if the client prefers application/json over text/plain
 var DefaultErrorHandler = func(c *Ctx, err error) error {
-	code := StatusInternalServerError
+	res := &Error{Code: StatusInternalServerError, Message: err.Error()}
+	var p *PanicError
 	var e *Error
-	if errors.As(err, &e) {
-		code = e.Code
+	if errors.As(err, &p) && !c.app.config.ExposePanicMessage {
+		// never leak the panic value by default
+		res.Message = c.app.config.PanicResponseBody
+	} else if errors.As(err, &e) {
+		res.Code, res.ErrorCode, res.Details = e.Code, e.ErrorCode, e.Details
+	}
+	if c.Accepts(MIMETextPlain, MIMEApplicationJSON) == MIMEApplicationJSON {
+		return c.Status(res.Code).JSON(res)
 	}
 	c.Set(HeaderContentType, MIMETextPlainCharsetUTF8)
-	return c.Status(code).SendString(err.Error())
+	return c.Status(res.Code).SendString(res.Message)
 }
 
 // New creates a new Fiber named instance.
@@ -477,6 +784,11 @@ func New(config ...Config) *App {
 				return new(Ctx)
 			},
 		},
+		headerCtxPool: sync.Pool{
+			New: func() interface{} {
+				return new(fasthttp.RequestCtx)
+			},
+		},
 		// Create config
 		config:      Config{},
 		getBytes:    utils.UnsafeBytes,
@@ -484,6 +796,7 @@ func New(config ...Config) *App {
 		appList:     make(map[string]*App),
 		latestRoute: &Route{},
 		latestGroup: &Group{},
+		conns:       make(map[net.Conn]struct{}),
 	}
 
 	// Define hooks
@@ -507,9 +820,16 @@ func New(config ...Config) *App {
 	if app.config.Concurrency <= 0 {
 		app.config.Concurrency = DefaultConcurrency
 	}
+	if app.config.ConcurrencyRetryAfter <= 0 {
+		app.config.ConcurrencyRetryAfter = DefaultConcurrencyRetryAfter
+	}
 	if app.config.ReadBufferSize <= 0 {
 		app.config.ReadBufferSize = DefaultReadBufferSize
 	}
+	if app.config.MaxURLLength > 0 && app.config.ReadBufferSize < app.config.MaxURLLength {
+		// leave room for the request headers next to the request line
+		app.config.ReadBufferSize = app.config.MaxURLLength + DefaultReadBufferSize
+	}
 	if app.config.WriteBufferSize <= 0 {
 		app.config.WriteBufferSize = DefaultWriteBufferSize
 	}
@@ -523,12 +843,30 @@ func New(config ...Config) *App {
 	if app.config.ErrorHandler == nil {
 		app.config.ErrorHandler = DefaultErrorHandler
 	}
+	if app.config.PanicResponseBody == "" {
+		app.config.PanicResponseBody = utils.StatusMessage(StatusInternalServerError)
+	}
 
 	if app.config.JSONEncoder == nil {
-		app.config.JSONEncoder = json.Marshal
+		if app.config.DisableJSONEscapeHTML {
+			app.config.JSONEncoder = jsonMarshalNoEscapeHTML
+		} else {
+			app.config.JSONEncoder = json.Marshal
+		}
+	}
+	if app.config.JSONStreamEncoder == nil {
+		if app.config.DisableJSONEscapeHTML {
+			app.config.JSONStreamEncoder = jsonStreamEncodeNoEscapeHTML
+		} else {
+			app.config.JSONStreamEncoder = jsonStreamEncode
+		}
 	}
 	if app.config.JSONDecoder == nil {
-		app.config.JSONDecoder = json.Unmarshal
+		if app.config.JSONDecoderUseNumber {
+			app.config.JSONDecoder = jsonUnmarshalUseNumber
+		} else {
+			app.config.JSONDecoder = json.Unmarshal
+		}
 	}
 	if app.config.XMLEncoder == nil {
 		app.config.XMLEncoder = xml.Marshal
@@ -545,6 +883,37 @@ func New(config ...Config) *App {
 	// Override colors
 	app.config.ColorScheme = defaultColors(app.config.ColorScheme)
 
+	if app.config.SendFile.IndexNames == nil {
+		app.config.SendFile.IndexNames = []string{"index.html"}
+	}
+	if app.config.SendFile.CompressibleTypes == nil {
+		// the app gets its own copy, so that neither side can change the other
+		app.config.SendFile.CompressibleTypes = append([]string(nil), DefaultCompressibleTypes...)
+	}
+	if app.config.SendFile.CompressMinSize <= 0 {
+		app.config.SendFile.CompressMinSize = DefaultSendFileCompressMinSize
+	}
+	if app.config.SendFile.CompressMaxSize <= 0 {
+		app.config.SendFile.CompressMaxSize = DefaultSendFileCompressMaxSize
+	}
+	if app.config.CORSPreflight.AllowOrigins == nil {
+		app.config.CORSPreflight.AllowOrigins = []string{"*"}
+	}
+	// reflecting any origin with credentials would allow every site to make credentialed requests
+	if app.config.CORSPreflight.Enable && app.config.CORSPreflight.AllowCredentials {
+		for _, o := range app.config.CORSPreflight.AllowOrigins {
+			if o == "*" {
+				panic("config: the wildcard origin of CORSPreflight can't be used with AllowCredentials\n")
+			}
+		}
+	}
+	if app.config.MethodOverride.FormField == "" {
+		app.config.MethodOverride.FormField = "_method"
+	}
+	if app.config.MethodOverride.Header == "" {
+		app.config.MethodOverride.Header = HeaderXHTTPMethodOverride
+	}
+
 	// Init appList
 	app.appList[""] = app
 
@@ -562,6 +931,7 @@ func (app *App) handleTrustedProxy(ipAddress string) {
 
 		if err != nil {
 			fmt.Printf("[Warning] IP range `%s` could not be parsed. \n", ipAddress)
+			return
 		}
 
 		app.config.trustedProxyRanges = append(app.config.trustedProxyRanges, ipNet)
@@ -585,7 +955,7 @@ func (app *App) SetTLSHandler(tlsHandler *TLSHandler) {
 // to be invoked on errors that happen within the prefix route.
 func (app *App) Mount(prefix string, fiber *App) Router {
 	stack := fiber.Stack()
-	prefix = strings.TrimRight(prefix, "/")
+	prefix = strings.TrimRight(JoinPath("", prefix), "/")
 	if prefix == "" {
 		prefix = "/"
 	}
@@ -593,7 +963,9 @@ func (app *App) Mount(prefix string, fiber *App) Router {
 	for m := range stack {
 		for r := range stack[m] {
 			route := app.copyRoute(stack[m][r])
-			app.addRoute(route.Method, app.addPrefixToRoute(prefix, route))
+			if err := app.addRoute(route.Method, app.addPrefixToRoute(prefix, route)); err != nil {
+				panic(err)
+			}
 		}
 	}
 
@@ -608,23 +980,159 @@ func (app *App) Mount(prefix string, fiber *App) Router {
 	return app
 }
 
+// RegisterCustomConstraint registers a route constraint, e.g. "slug" for ":title<slug>".
+// The data of the constraint like "2" and "8" for ":code<code(2,8)>" is passed to fn.
+// Names are case-insensitive and built-in constraints take precedence.
+// Custom constraints must be registered before the routes using them, a name can't be
+// registered twice. Mounted routes keep the custom constraints of their app.
+//
+//	app.RegisterCustomConstraint("slug", func(param string, _ ...string) bool {
+//		return slugRegex.MatchString(param)
+//	})
+func (app *App) RegisterCustomConstraint(name string, fn func(param string, constraintData ...string) bool) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	name = utils.ToLower(name)
+	if _, ok := app.customConstraints[name]; ok {
+		panic(fmt.Sprintf("constraint: custom constraint %q is already registered\n", name))
+	}
+	if app.customConstraints == nil {
+		app.customConstraints = make(map[string]CustomConstraint)
+	}
+	app.customConstraints[name] = fn
+}
+
+// CompileConstraint parses constraints, which are written like the constraints of route params,
+// e.g. "alpha;minLen(3)", once, so that values can be checked repeatedly, e.g. with c.CookieConstraint.
+// Custom constraints have to be registered before. Unlike for routes, an unknown constraint
+// or an invalid regex returns an error instead of panicking.
+//
+//	sessionConstraint, err := app.CompileConstraint(fiber.ConstraintGuid)
+func (app *App) CompileConstraint(constraint string) (*CompiledConstraint, error) {
+	app.mutex.Lock()
+	constraints, err := parseConstraints(constraint, app.customConstraints)
+	app.mutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("constraint: %s", strings.TrimPrefix(err.Error(), "constraint: "))
+	}
+	return &CompiledConstraint{raw: constraint, constraints: constraints}, nil
+}
+
 // Assign name to specific route.
 func (app *App) Name(name string) Router {
 	app.mutex.Lock()
-	if strings.HasPrefix(app.latestRoute.path, app.latestGroup.Prefix) {
-		app.latestRoute.Name = app.latestGroup.name + name
-	} else {
-		app.latestRoute.Name = name
+	for _, route := range app.chainedRoutes() {
+		if strings.HasPrefix(route.path, app.latestGroup.Prefix) {
+			route.Name = app.latestGroup.name + name
+		} else {
+			route.Name = name
+		}
+
+		if err := app.hooks.executeOnNameHooks(*route); err != nil {
+			panic(err)
+		}
+	}
+	app.mutex.Unlock()
+
+	return app
+}
+
+// BodyLimit overrides the body limit of the app for the latest route,
+// -1 declines any body size and 0 keeps the inherited limit.
+// The route is looked up as soon as the request header has been received, so that
+// the server reads the body only up to the limit of the route.
+//
+//	app.Post("/upload", handler).BodyLimit(100 * 1024 * 1024)
+func (app *App) BodyLimit(limit int) Router {
+	app.mutex.Lock()
+	for _, route := range app.chainedRoutes() {
+		app.setRouteBodyLimit(route, limit)
 	}
+	app.mutex.Unlock()
 
-	if err := app.hooks.executeOnNameHooks(*app.latestRoute); err != nil {
-		panic(err)
+	return app
+}
+
+// MaxFiles limits the number of files of multipart requests to the latest route,
+// -1 declines any file and 0 keeps the inherited limit. Requests with more files are
+// rejected with ErrTooManyFiles before any handler is executed. The body has already been read
+// by then, unless StreamRequestBody and DisablePreParseMultipartForm are enabled, which stops
+// reading the body at the first file above the limit.
+//
+//	app.Post("/upload", handler).MaxFiles(5)
+func (app *App) MaxFiles(n int) Router {
+	app.mutex.Lock()
+	for _, route := range app.chainedRoutes() {
+		app.setRouteMaxFiles(route, n)
+	}
+	app.mutex.Unlock()
+
+	return app
+}
+
+// DisableRecover opts the latest route out of panic recovery, so a panic is not
+// turned into an error response by the recover middleware but propagates to the caller,
+// e.g. to a test harness with app.Test.
+//
+//	app.Get("/crash", handler).DisableRecover()
+func (app *App) DisableRecover() Router {
+	app.mutex.Lock()
+	for _, route := range app.chainedRoutes() {
+		route.noRecover = true
 	}
 	app.mutex.Unlock()
 
 	return app
 }
 
+// setRouteMaxFiles sets the maximum number of uploaded files of the route,
+// the files are counted before routing, see checkMaxFiles
+func (app *App) setRouteMaxFiles(route *Route, n int) {
+	if n == 0 {
+		return
+	}
+	route.maxFiles = n
+	app.hasRouteMaxFiles = true
+}
+
+// setRouteBodyLimit sets the body limit of the route, the server reads the body of the
+// route only up to the limit, see headerReceived, and the limit is checked before routing,
+// see checkBodyLimit.
+func (app *App) setRouteBodyLimit(route *Route, limit int) {
+	if limit == 0 {
+		return
+	}
+	route.bodyLimit = limit
+	app.hasRouteBodyLimit = true
+}
+
+// headerReceived lets the server read the body of a request only up to the body limit of its route
+func (app *App) headerReceived(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	if !app.hasRouteBodyLimit {
+		return fasthttp.RequestConfig{}
+	}
+	fctx := app.headerCtxPool.Get().(*fasthttp.RequestCtx)
+	header.CopyTo(&fctx.Request.Header)
+	c := app.AcquireCtx(fctx)
+	if app.config.MethodOverride.Enable {
+		app.overrideMethod(c)
+	}
+	limit := app.config.BodyLimit
+	if c.methodINT != -1 {
+		if route := app.firstRoute(c); route != nil && route.bodyLimit != 0 {
+			limit = route.bodyLimit
+		}
+	}
+	app.ReleaseCtx(c)
+	fctx.Request.Reset()
+	app.headerCtxPool.Put(fctx)
+	// the server can't decline every body, so it reads at most one byte, which checkBodyLimit rejects
+	if limit < 0 {
+		limit = 1
+	}
+	return fasthttp.RequestConfig{MaxRequestBodySize: limit}
+}
+
 // Get route by name
 func (app *App) GetRoute(name string) Route {
 	for _, routes := range app.stack {
@@ -638,6 +1146,129 @@ func (app *App) GetRoute(name string) Route {
 	return Route{}
 }
 
+// GetRoutes Get all routes. When filterUseOption equal to true, it will filter the routes registered by the middleware.
+func (app *App) GetRoutes(filterUseOption ...bool) []Route {
+	var rs []Route
+	var filterUse bool
+	if len(filterUseOption) != 0 {
+		filterUse = filterUseOption[0]
+	}
+	for _, routes := range app.stack {
+		for _, route := range routes {
+			if filterUse && route.use {
+				continue
+			}
+			rs = append(rs, *route)
+		}
+	}
+	return rs
+}
+
+// GetRouteURL generates the URL of a named route, the parameters are substituted into the route path.
+// Unlike c.GetRouteURL, an error is returned if the route does not exist, a required parameter
+// is missing or a value does not satisfy the constraints of its parameter.
+//
+//	app.Get("/user/:id<int>", handler).Name("user")
+//	app.GetRouteURL("user", fiber.Map{"id": 42}) // "/user/42"
+func (app *App) GetRouteURL(name string, params Map) (string, error) {
+	route := app.GetRoute(name)
+	if route.Path == "" {
+		return "", fmt.Errorf("routeurl: route %q not found", name)
+	}
+	for _, segment := range route.routeParser.segs {
+		if !segment.IsParam {
+			continue
+		}
+		var value interface{}
+		var found bool
+		for key, val := range params {
+			if app.isRouteParam(segment, key) {
+				value, found = val, true
+				break
+			}
+		}
+		if !found {
+			if !segment.IsOptional {
+				return "", fmt.Errorf("routeurl: missing parameter %q for route %q", segment.ParamName, name)
+			}
+			continue
+		}
+		for _, constraint := range segment.Constraints {
+			if !constraint.CheckConstraint(utils.ToString(value)) {
+				return "", fmt.Errorf("routeurl: value %q of parameter %q does not satisfy its constraints", utils.ToString(value), segment.ParamName)
+			}
+		}
+	}
+	return app.getLocationFromRoute(route, params)
+}
+
+// RouteHandler describes a single handler in the handler chain of a route
+type RouteHandler struct {
+	Name string `json:"name"` // Function name of the handler
+	Path string `json:"path"` // Path of the route the handler is registered on
+	Use  bool   `json:"use"`  // Handler is a middleware registered with Use or Group
+}
+
+// HandlerChain returns the ordered handlers which are executed for a request to the given route
+// when every handler calls c.Next(): the matching middleware followed by the handlers of the route.
+// Middleware whose path contains parameters is matched against the path of the route as registered.
+//
+//	for _, route := range app.GetRoutes(true) {
+//	     fmt.Println(route.Method, route.Path, app.HandlerChain(route))
+//	}
+func (app *App) HandlerChain(route Route) []RouteHandler {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	app.buildTree()
+
+	// find the method stack of the route, the position is unique across all stacks
+	m := -1
+	for i := range app.stack {
+		for _, r := range app.stack[i] {
+			if r.pos == route.pos && r.Path == route.Path {
+				m = i
+				break
+			}
+		}
+	}
+	if m == -1 {
+		return nil
+	}
+
+	treePath := ""
+	if len(route.routeParser.segs) > 0 && len(route.routeParser.segs[0].Const) >= 3 {
+		treePath = route.routeParser.segs[0].Const[:3]
+	}
+	tree, ok := app.treeStack[m][treePath]
+	if !ok {
+		tree = app.treeStack[m][""]
+	}
+
+	var chain []*Route
+	var values [maxParams]string
+	for _, r := range tree {
+		if r.pos == route.pos {
+			chain = append(chain, r)
+			break
+		}
+		if r.use && (r.host == "" || r.host == route.host) && r.match(route.path, route.path, &values) {
+			chain = append(chain, r)
+		}
+	}
+
+	var handlers []RouteHandler
+	for _, r := range uniqueRouteStack(chain) {
+		for _, handler := range r.Handlers {
+			handlers = append(handlers, RouteHandler{
+				Name: runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(),
+				Path: r.Path,
+				Use:  r.use,
+			})
+		}
+	}
+	return handlers
+}
+
 // Use registers a middleware route that will match requests
 // with the provided prefix (which is optional and defaults to "/").
 //
@@ -670,6 +1301,36 @@ func (app *App) Use(args ...interface{}) Router {
 	return app
 }
 
+// UsePre registers handlers that run on every request before the router
+// matches a route, even if no route matches the path at all.
+// They can short-circuit the request by not calling c.Next(),
+// e.g. to answer all requests with 503 in maintenance mode.
+//
+//	app.UsePre(func(c *fiber.Ctx) error {
+//	     if maintenance {
+//	          return fiber.ErrServiceUnavailable
+//	     }
+//	     return c.Next()
+//	})
+func (app *App) UsePre(handlers ...Handler) Router {
+	if len(handlers) == 0 {
+		panic("usepre: missing handler\n")
+	}
+	app.mutex.Lock()
+	if app.preRoute == nil {
+		app.preRoute = &Route{
+			use:    true,
+			star:   true,
+			path:   "*",
+			Method: methodUse,
+			Path:   "*",
+		}
+	}
+	app.preRoute.Handlers = append(app.preRoute.Handlers, handlers...)
+	app.mutex.Unlock()
+	return app
+}
+
 // Get registers a route for GET methods that requests a representation
 // of the specified resource. Requests using GET should only retrieve data.
 func (app *App) Get(path string, handlers ...Handler) Router {
@@ -728,9 +1389,58 @@ func (app *App) Add(method, path string, handlers ...Handler) Router {
 	return app.register(method, path, handlers...)
 }
 
+// AddMethods registers the route for each of the given HTTP methods,
+// it panics before registering anything if one of the methods is invalid.
+// The chained route options like Name apply to the routes of all methods.
+//
+//	app.AddMethods([]string{fiber.MethodGet, fiber.MethodPost}, "/search", handler).Name("search")
+func (app *App) AddMethods(methods []string, path string, handlers ...Handler) Router {
+	checkMethods(methods)
+	routes := make([]*Route, 0, len(methods))
+	for _, method := range methods {
+		_ = app.Add(method, path, handlers...)
+		routes = append(routes, app.latestRoute)
+	}
+	app.setChainedRoutes(routes)
+	return app
+}
+
+// setChainedRoutes sets the routes of the latest AddMethods call
+func (app *App) setChainedRoutes(routes []*Route) {
+	app.mutex.Lock()
+	app.latestRoutes = routes
+	app.mutex.Unlock()
+}
+
+// chainedRoutes returns the routes the chained route options apply to,
+// the routes of the latest AddMethods call or the latest route
+func (app *App) chainedRoutes() []*Route {
+	if app.latestRoutes != nil {
+		return app.latestRoutes
+	}
+	return []*Route{app.latestRoute}
+}
+
+// AddRoute works like Add, but returns an error instead of panicking if the route
+// can't be registered, e.g. because of an invalid method or regex constraint.
+// This allows to validate dynamic route sets without crashing the application.
+func (app *App) AddRoute(method, path string, handlers ...Handler) error {
+	// the route is only added to the stack after all checks and hooks passed
+	routes, err := app.newRoutes("", method, path, handlers)
+	if err != nil {
+		return fmt.Errorf("addroute: %w", err)
+	}
+	if err := app.addRoutes(routes); err != nil {
+		return fmt.Errorf("addroute: %w", err)
+	}
+	// Increment global handler count
+	atomic.AddUint32(&app.handlersCount, uint32(len(handlers)))
+	return nil
+}
+
 // Static will create a file server serving static files
 func (app *App) Static(prefix, root string, config ...Static) Router {
-	return app.registerStatic(prefix, root, config...)
+	return app.registerStatic("", prefix, root, config...)
 }
 
 // All will register the handler on all HTTP methods
@@ -757,6 +1467,19 @@ func (app *App) Group(prefix string, handlers ...Handler) Router {
 	return grp
 }
 
+// Host is used for routes that only match requests for the given host pattern.
+// The pattern may contain parameters, which are available via c.Params like path parameters.
+// Routes are matched in the order of registration, requests for other hosts
+// are handled by the routes which are not restricted to a host.
+//
+//	tenant := app.Host(":tenant.example.com")
+//	tenant.Get("/", func(c *fiber.Ctx) error {
+//	     return c.SendString(c.Params("tenant"))
+//	})
+func (app *App) Host(pattern string) Router {
+	return &Group{app: app, host: pattern}
+}
+
 // Route is used to define routes with a common prefix inside the common function.
 // Uses Group method to define new sub-router.
 func (app *App) Route(prefix string, fn func(router Router), name ...string) Router {
@@ -777,6 +1500,23 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// PanicError wraps a value recovered from a panic in a handler,
+// see the PanicResponseBody config
+type PanicError struct {
+	Value interface{}
+}
+
+// Error returns the formatted panic value
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
 // NewError creates a new Error instance with an optional message
 func NewError(code int, message ...string) *Error {
 	err := &Error{
@@ -789,6 +1529,17 @@ func NewError(code int, message ...string) *Error {
 	return err
 }
 
+// SetViewGlobal sets a variable which is passed to every template rendered with c.Render,
+// e.g. the site name or the asset version. The data passed to c.Render takes precedence.
+func (app *App) SetViewGlobal(key string, value interface{}) {
+	app.viewGlobalsMutex.Lock()
+	if app.viewGlobals == nil {
+		app.viewGlobals = make(Map)
+	}
+	app.viewGlobals[key] = value
+	app.viewGlobalsMutex.Unlock()
+}
+
 // Config returns the app config as value ( read-only ).
 func (app *App) Config() Config {
 	return app.config
@@ -818,7 +1569,18 @@ func (app *App) HandlersCount() uint32 {
 //
 // Shutdown does not close keepalive connections so its recommended to set ReadTimeout to something else than 0.
 func (app *App) Shutdown() error {
+	return app.ShutdownWithTimeout(0)
+}
+
+// ShutdownWithTimeout gracefully shuts down the server like Shutdown, but waits at most
+// the given timeout for the active connections. The connections which are still open after
+// the timeout are closed forcibly and an error wrapping context.DeadlineExceeded is returned.
+// A timeout of 0 waits indefinitely.
+//
+// The OnPreShutdown hooks are executed once draining begins, the OnShutdown hooks after it ended.
+func (app *App) ShutdownWithTimeout(timeout time.Duration) error {
 	if app.hooks != nil {
+		app.hooks.executeOnPreShutdownHooks()
 		defer app.hooks.executeOnShutdownHooks()
 	}
 
@@ -827,7 +1589,42 @@ func (app *App) Shutdown() error {
 	if app.server == nil {
 		return fmt.Errorf("shutdown: server is not running")
 	}
-	return app.server.Shutdown()
+	if timeout <= 0 {
+		return app.server.Shutdown()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.server.Shutdown()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+	}
+
+	// close the connections which haven't finished in time
+	app.connsMutex.Lock()
+	for conn := range app.conns {
+		_ = conn.Close()
+	}
+	app.connsMutex.Unlock()
+	return fmt.Errorf("shutdown: failed to drain connections within %v: %w", timeout, context.DeadlineExceeded)
+}
+
+// trackConn keeps track of the open connections, so that they can be closed
+// if they don't finish in time when shutting down
+func (app *App) trackConn(conn net.Conn, state fasthttp.ConnState) {
+	app.connsMutex.Lock()
+	switch state {
+	case fasthttp.StateNew:
+		app.conns[conn] = struct{}{}
+	case fasthttp.StateClosed, fasthttp.StateHijacked:
+		delete(app.conns, conn)
+	}
+	app.connsMutex.Unlock()
 }
 
 // Server returns the underlying fasthttp server
@@ -835,6 +1632,45 @@ func (app *App) Server() *fasthttp.Server {
 	return app.server
 }
 
+// Stats holds the request statistics of an application since it was created.
+type Stats struct {
+	// Number of requests which have been handled
+	Requests uint64 `json:"requests"`
+	// Number of requests which are being handled right now
+	InFlight int32 `json:"in_flight"`
+	// Total size of the request bodies in bytes
+	BytesIn uint64 `json:"bytes_in"`
+	// Total size of the response bodies in bytes, streamed bodies are only
+	// counted if their size is known from the Content-Length header
+	BytesOut uint64 `json:"bytes_out"`
+}
+
+// Stats returns the request statistics, e.g. for a health endpoint.
+func (app *App) Stats() Stats {
+	return Stats{
+		Requests: atomic.LoadUint64(&app.requestsServed),
+		InFlight: atomic.LoadInt32(&app.inFlight),
+		BytesIn:  atomic.LoadUint64(&app.bytesIn),
+		BytesOut: atomic.LoadUint64(&app.bytesOut),
+	}
+}
+
+// requestDone updates the request statistics once a request has been handled
+func (app *App) requestDone(fctx *fasthttp.RequestCtx) {
+	atomic.AddUint64(&app.requestsServed, 1)
+	// don't read streamed bodies, only their size is known
+	if !fctx.Request.IsBodyStream() {
+		atomic.AddUint64(&app.bytesIn, uint64(len(fctx.Request.Body())))
+	} else if n := fctx.Request.Header.ContentLength(); n > 0 {
+		atomic.AddUint64(&app.bytesIn, uint64(n))
+	}
+	if !fctx.Response.IsBodyStream() {
+		atomic.AddUint64(&app.bytesOut, uint64(len(fctx.Response.Body())))
+	} else if n := fctx.Response.Header.ContentLength(); n > 0 {
+		atomic.AddUint64(&app.bytesOut, uint64(n))
+	}
+}
+
 // Hooks returns the hook struct to register hooks.
 func (app *App) Hooks() *Hooks {
 	return app.hooks
@@ -881,6 +1717,11 @@ func (app *App) Test(req *http.Request, msTimeout ...int) (resp *http.Response,
 		var returned bool
 		defer func() {
 			if !returned {
+				// pass an unrecovered panic of a handler to the caller
+				if r := recover(); r != nil {
+					channel <- &PanicError{Value: r}
+					return
+				}
 				channel <- fmt.Errorf("runtime.Goexit() called in handler or server panic")
 			}
 		}()
@@ -902,6 +1743,11 @@ func (app *App) Test(req *http.Request, msTimeout ...int) (resp *http.Response,
 		err = <-channel
 	}
 
+	// Panic like the handler
+	if panicErr, ok := err.(*PanicError); ok {
+		panic(panicErr.Value)
+	}
+
 	// Check for errors
 	if err != nil && err != fasthttp.ErrGetOnly {
 		return nil, err
@@ -910,8 +1756,54 @@ func (app *App) Test(req *http.Request, msTimeout ...int) (resp *http.Response,
 	// Read response
 	buffer := bufio.NewReader(&conn.w)
 
-	// Convert raw http response to *http.Response
-	return http.ReadResponse(buffer, req)
+	// Convert raw http response to *http.Response, informational responses
+	// of c.SendInformational ahead of the final response are skipped
+	for {
+		resp, err = http.ReadResponse(buffer, req)
+		if err != nil || resp.StatusCode >= StatusOK || (conn.w.Len() == 0 && buffer.Buffered() == 0) {
+			return resp, err
+		}
+	}
+}
+
+// TestListener is an in-memory net.Listener serving the app, see NewTestListener.
+type TestListener struct {
+	*fasthttputil.InmemoryListener
+}
+
+// NewTestListener serves the app on an in-memory listener for integration tests,
+// e.g. of keep-alive connections and streamed responses, without a network socket.
+// Connections are created with Dial or the http.Client of Client,
+// closing the listener stops serving. Prefork is not supported.
+//
+//	ln, _ := app.NewTestListener()
+//	defer ln.Close()
+//	resp, err := ln.Client().Get("http://localhost/stream")
+func (app *App) NewTestListener() (*TestListener, error) {
+	if app.config.Prefork {
+		return nil, errors.New("test: prefork is not supported for in-memory listeners")
+	}
+	if err := app.startup(); err != nil {
+		return nil, err
+	}
+
+	ln := &TestListener{InmemoryListener: fasthttputil.NewInmemoryListener()}
+	go func() {
+		_ = app.server.Serve(ln)
+	}()
+	return ln, nil
+}
+
+// Client returns a http.Client whose connections are dialed to the listener,
+// the host of the request URLs is ignored.
+func (ln *TestListener) Client() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				return ln.Dial()
+			},
+		},
+	}
 }
 
 type disableLogger struct{}
@@ -947,6 +1839,7 @@ func (app *App) init() *App {
 	app.server.DisableHeaderNamesNormalizing = app.config.DisableHeaderNormalizing
 	app.server.DisableKeepalive = app.config.DisableKeepalive
 	app.server.MaxRequestBodySize = app.config.BodyLimit
+	app.server.HeaderReceived = app.headerReceived
 	app.server.NoDefaultServerHeader = app.config.ServerHeader == ""
 	app.server.ReadTimeout = app.config.ReadTimeout
 	app.server.WriteTimeout = app.config.WriteTimeout
@@ -957,6 +1850,7 @@ func (app *App) init() *App {
 	app.server.ReduceMemoryUsage = app.config.ReduceMemoryUsage
 	app.server.StreamRequestBody = app.config.StreamRequestBody
 	app.server.DisablePreParseMultipartForm = app.config.DisablePreParseMultipartForm
+	app.server.ConnState = app.trackConn
 
 	// unlock application
 	app.mutex.Unlock()
@@ -1019,12 +1913,20 @@ func (app *App) serverErrorHandler(fctx *fasthttp.RequestCtx, err error) {
 
 // startupProcess Is the method which executes all the necessary processes just before the start of the server.
 func (app *App) startupProcess() *App {
-	if err := app.hooks.executeOnListenHooks(); err != nil {
+	if err := app.startup(); err != nil {
 		panic(err)
 	}
+	return app
+}
+
+// startup executes the listen hooks and builds the route tree before serving
+func (app *App) startup() error {
+	if err := app.hooks.executeOnListenHooks(); err != nil {
+		return err
+	}
 
 	app.mutex.Lock()
 	app.buildTree()
 	app.mutex.Unlock()
-	return app
+	return nil
 }
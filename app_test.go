@@ -5,7 +5,9 @@
 package fiber
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -18,12 +20,16 @@ import (
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
 )
 
 var testEmptyHandler = func(c *Ctx) error {
@@ -284,6 +290,49 @@ func Test_App_ErrorHandler_GroupMountRootLevel(t *testing.T) {
 	testErrorResponse(t, err, resp, "1: custom error")
 }
 
+// go test -run Test_App_ErrorHandler_JSON
+func Test_App_ErrorHandler_JSON(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		err := NewError(StatusBadRequest, "invalid user")
+		err.ErrorCode = "user_invalid"
+		err.Details = map[string]interface{}{"field": "name"}
+		return err
+	})
+	app.Get("/plain", func(c *Ctx) error {
+		return NewError(StatusNotFound, "no user")
+	})
+	app.Get("/other", func(c *Ctx) error {
+		return errors.New("boom")
+	})
+
+	testCases := []struct {
+		path, accept, contentType, body string
+		code                            int
+	}{
+		{"/", MIMEApplicationJSON, MIMEApplicationJSON, `{"code":400,"message":"invalid user","error_code":"user_invalid","details":{"field":"name"}}`, StatusBadRequest},
+		{"/", "", MIMETextPlainCharsetUTF8, "invalid user", StatusBadRequest},
+		{"/", "*/*", MIMETextPlainCharsetUTF8, "invalid user", StatusBadRequest},
+		{"/", "text/plain, application/json;q=0.9", MIMETextPlainCharsetUTF8, "invalid user", StatusBadRequest},
+		{"/plain", "text/html, application/json;q=0.9", MIMEApplicationJSON, `{"code":404,"message":"no user"}`, StatusNotFound},
+		{"/other", MIMEApplicationJSON, MIMEApplicationJSON, `{"code":500,"message":"boom"}`, StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(MethodGet, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set(HeaderAccept, tc.accept)
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.code, resp.StatusCode)
+		utils.AssertEqual(t, tc.contentType, resp.Header.Get(HeaderContentType))
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.body, string(body))
+	}
+}
+
 func Test_App_Nested_Params(t *testing.T) {
 	app := New()
 
@@ -433,6 +482,163 @@ func Test_App_Add_Method_Test(t *testing.T) {
 	app.Add("JOHN", "/doe", testEmptyHandler)
 }
 
+// go test -run Test_App_AddRoute
+func Test_App_AddRoute(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	utils.AssertEqual(t, nil, app.AddRoute(MethodGet, "/users/:id<int>", testEmptyHandler))
+
+	err := app.AddRoute(MethodGet, "/users/:name<regex([a-z)>", testEmptyHandler)
+	utils.AssertEqual(t, true, err != nil)
+	utils.AssertEqual(t, true, strings.HasPrefix(err.Error(), "addroute: regexp: Compile(`[a-z`): "))
+
+	err = app.AddRoute("JOHN", "/doe", testEmptyHandler)
+	utils.AssertEqual(t, "addroute: add: invalid http method JOHN", err.Error())
+
+	err = app.AddRoute(MethodGet, "/doe")
+	utils.AssertEqual(t, "addroute: missing handler in route: /doe", err.Error())
+
+	// only the valid route has been registered
+	utils.AssertEqual(t, uint32(1), app.HandlersCount())
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	// a failing OnRoute hook neither registers the route nor blocks further registrations
+	app.Hooks().OnRoute(func(r Route) error {
+		if strings.HasPrefix(r.Path, "/admin") {
+			return errors.New("admin routes are not allowed")
+		}
+		return nil
+	})
+	utils.AssertEqual(t, "addroute: admin routes are not allowed", app.AddRoute(methodUse, "/admin", testEmptyHandler).Error())
+	utils.AssertEqual(t, nil, app.AddRoute(MethodGet, "/public", testEmptyHandler))
+	utils.AssertEqual(t, uint32(2), app.HandlersCount())
+	for _, route := range app.GetRoutes() {
+		utils.AssertEqual(t, false, strings.HasPrefix(route.Path, "/admin"))
+	}
+}
+
+// go test -run Test_App_AddMethods
+func Test_App_AddMethods(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	app.AddMethods([]string{MethodGet, "post"}, "/search", func(c *Ctx) error {
+		return c.SendString(c.Method())
+	}).Name("search")
+	app.Group("/api").AddMethods([]string{MethodPut, MethodPatch}, "/user", testEmptyHandler)
+
+	for _, method := range []string{MethodGet, MethodPost} {
+		resp, err := app.Test(httptest.NewRequest(method, "/search", nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, method)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, method, string(body))
+	}
+	resp, err := app.Test(httptest.NewRequest(MethodDelete, "/search", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+
+	for _, method := range []string{MethodPut, MethodPatch} {
+		resp, err = app.Test(httptest.NewRequest(method, "/api/user", nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, method)
+	}
+
+	// the routes of all methods are named
+	var named []string
+	for _, route := range app.GetRoutes() {
+		if route.Name == "search" {
+			named = append(named, route.Method)
+		}
+	}
+	sort.Strings(named)
+	utils.AssertEqual(t, []string{MethodGet, MethodPost}, named)
+	// and the chained options apply to all of them
+	app.Group("/v2").AddMethods([]string{MethodGet, MethodPost}, "/upload", testEmptyHandler).BodyLimit(16)
+	utils.AssertEqual(t, 16, app.stack[methodInt(MethodGet)][len(app.stack[methodInt(MethodGet)])-1].bodyLimit)
+	utils.AssertEqual(t, 16, app.stack[methodInt(MethodPost)][len(app.stack[methodInt(MethodPost)])-1].bodyLimit)
+	app.Get("/single", testEmptyHandler).Name("single")
+	utils.AssertEqual(t, MethodGet, app.GetRoute("single").Method)
+
+	// nothing is registered if one of the methods is invalid
+	count := app.HandlersCount()
+	defer func() {
+		utils.AssertEqual(t, "add: invalid http method JOHN\n", recover())
+		utils.AssertEqual(t, count, app.HandlersCount())
+	}()
+	app.AddMethods([]string{MethodGet, "JOHN"}, "/doe", testEmptyHandler)
+}
+
+// go test -run Test_App_CompileConstraint
+func Test_App_CompileConstraint(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.RegisterCustomConstraint("even", func(param string, _ ...string) bool {
+		n, err := strconv.Atoi(param)
+		return err == nil && n%2 == 0
+	})
+
+	cc, err := app.CompileConstraint("even;max(10)")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "even;max(10)", cc.String())
+	utils.AssertEqual(t, true, cc.Check("8"))
+	utils.AssertEqual(t, false, cc.Check("7"))
+	utils.AssertEqual(t, false, cc.Check("12"))
+
+	// invalid constraints return an error instead of panicking
+	_, err = app.CompileConstraint("slug")
+	utils.AssertEqual(t, `constraint: unknown constraint "slug"`, err.Error())
+	_, err = app.CompileConstraint("regex([a-z)")
+	utils.AssertEqual(t, true, strings.HasPrefix(err.Error(), "constraint: regexp: Compile"))
+}
+
+// go test -run Test_App_DetectRouteConflicts
+func Test_App_DetectRouteConflicts(t *testing.T) {
+	t.Parallel()
+	handler := func(c *Ctx) error {
+		return nil
+	}
+
+	app := New(Config{DetectRouteConflicts: true})
+	app.Use(handler)
+	app.Use("/users", handler)
+	app.Get("/users", handler)
+	app.Post("/users", handler)
+	app.Get("/users/:id", handler)
+	app.Group("/admin").Get("/users", handler)
+	app.Host("api.example.com").Get("/users", handler)
+
+	count := app.HandlersCount()
+	defer func() {
+		utils.AssertEqual(t, "add: route conflict, POST /Users/ is already registered\n", recover())
+		utils.AssertEqual(t, count, app.HandlersCount())
+	}()
+	// same path after the normalization
+	app.Post("/Users/", handler)
+}
+
+// go test -run Test_App_DetectRouteConflicts_Disabled
+func Test_App_DetectRouteConflicts_Disabled(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		return c.Next()
+	})
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString("second")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "second", string(body))
+}
+
 // go test -run Test_App_GETOnly
 func Test_App_GETOnly(t *testing.T) {
 	app := New(Config{
@@ -467,6 +673,494 @@ func Test_App_Use_Params_Group(t *testing.T) {
 	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
 }
 
+// go test -run Test_App_UsePre
+func Test_App_UsePre(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	maintenance := true
+	var order []string
+	app.UsePre(func(c *Ctx) error {
+		order = append(order, "pre1")
+		if maintenance {
+			return ErrServiceUnavailable
+		}
+		return c.Next()
+	}, func(c *Ctx) error {
+		order = append(order, "pre2")
+		return c.Next()
+	})
+	app.Use(func(c *Ctx) error {
+		order = append(order, "use")
+		return c.Next()
+	})
+	app.Get("/", func(c *Ctx) error {
+		order = append(order, "get")
+		return c.SendString("ok")
+	})
+
+	// short-circuits before routing, even for unmatched paths
+	for _, path := range []string{"/", "/not-found"} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusServiceUnavailable, resp.StatusCode, "Status code")
+	}
+	utils.AssertEqual(t, []string{"pre1", "pre1"}, order)
+
+	// continues with the router
+	maintenance, order = false, nil
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, []string{"pre1", "pre2", "use", "get"}, order)
+
+	order = nil
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/not-found", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, []string{"pre1", "pre2", "use"}, order)
+}
+
+// go test -run Test_App_CORSPreflight
+func Test_App_CORSPreflight(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		CORSPreflight: CORSPreflight{
+			Enable:       true,
+			AllowOrigins: []string{"https://gofiber.io"},
+			MaxAge:       600,
+		},
+	})
+
+	handlerCalled := false
+	app.Use(func(c *Ctx) error {
+		handlerCalled = true
+		return c.Next()
+	})
+	app.Get("/user", testEmptyHandler)
+	app.Post("/user", testEmptyHandler)
+
+	preflight := func(path, origin string) *http.Response {
+		req := httptest.NewRequest(MethodOptions, path, nil)
+		req.Header.Set(HeaderOrigin, origin)
+		req.Header.Set(HeaderAccessControlRequestMethod, MethodPost)
+		req.Header.Set(HeaderAccessControlRequestHeaders, "Content-Type")
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		return resp
+	}
+
+	// allowed origin
+	resp := preflight("/user", "https://gofiber.io")
+	utils.AssertEqual(t, StatusNoContent, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "https://gofiber.io", resp.Header.Get(HeaderAccessControlAllowOrigin))
+	utils.AssertEqual(t, "GET, HEAD, POST", resp.Header.Get(HeaderAccessControlAllowMethods))
+	utils.AssertEqual(t, "Content-Type", resp.Header.Get(HeaderAccessControlAllowHeaders))
+	utils.AssertEqual(t, "600", resp.Header.Get(HeaderAccessControlMaxAge))
+	utils.AssertEqual(t, false, handlerCalled)
+
+	// disallowed origin
+	resp = preflight("/user", "https://example.com")
+	utils.AssertEqual(t, StatusNoContent, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderAccessControlAllowOrigin))
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderAccessControlAllowMethods))
+	utils.AssertEqual(t, false, handlerCalled)
+
+	// no registered route, the request is routed as usual
+	resp = preflight("/unknown", "https://gofiber.io")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, true, handlerCalled)
+
+	// a registered OPTIONS route answers the preflight itself
+	app.Get("/custom", testEmptyHandler)
+	app.Options("/custom", func(c *Ctx) error {
+		return c.SendString("custom")
+	})
+	resp = preflight("/custom", "https://gofiber.io")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderAccessControlAllowOrigin))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "custom", string(body))
+}
+
+// go test -run Test_App_CORSPreflight_WildcardCredentials
+func Test_App_CORSPreflight_WildcardCredentials(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		utils.AssertEqual(t, "config: the wildcard origin of CORSPreflight can't be used with AllowCredentials\n", recover())
+	}()
+	New(Config{
+		CORSPreflight: CORSPreflight{
+			Enable:           true,
+			AllowCredentials: true,
+		},
+	})
+}
+
+// go test -run Test_App_MethodOverride
+func Test_App_MethodOverride(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		MethodOverride: MethodOverride{Enable: true},
+	})
+
+	app.Post("/user", func(c *Ctx) error {
+		return c.SendString("POST")
+	})
+	app.Put("/user", func(c *Ctx) error {
+		return c.SendString("PUT")
+	})
+	app.Delete("/user", func(c *Ctx) error {
+		return c.SendString(c.Method())
+	})
+	app.Get("/user", func(c *Ctx) error {
+		return c.SendString("GET")
+	})
+
+	testOverride := func(req *http.Request, status int, expected string) {
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, status, resp.StatusCode, "Status code")
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected, string(body))
+	}
+
+	// form field
+	req := httptest.NewRequest(MethodPost, "/user", strings.NewReader("_method=delete"))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	testOverride(req, StatusOK, MethodDelete)
+
+	// header takes precedence over the form field
+	req = httptest.NewRequest(MethodPost, "/user", strings.NewReader("_method=delete"))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	req.Header.Set(HeaderXHTTPMethodOverride, MethodPut)
+	testOverride(req, StatusOK, "PUT")
+
+	// unknown methods keep the original method
+	req = httptest.NewRequest(MethodPost, "/user", nil)
+	req.Header.Set(HeaderXHTTPMethodOverride, "UNKNOWN")
+	testOverride(req, StatusOK, "POST")
+
+	// only POST requests can be overridden
+	req = httptest.NewRequest(MethodGet, "/user", nil)
+	req.Header.Set(HeaderXHTTPMethodOverride, MethodDelete)
+	testOverride(req, StatusOK, "GET")
+
+	// disabled by default
+	app = New()
+	app.Post("/user", func(c *Ctx) error {
+		return c.SendString("POST")
+	})
+	req = httptest.NewRequest(MethodPost, "/user", nil)
+	req.Header.Set(HeaderXHTTPMethodOverride, MethodDelete)
+	testOverride(req, StatusOK, "POST")
+}
+
+// go test -run Test_App_BodyLimit_Route
+func Test_App_BodyLimit_Route(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		BodyLimit: 4,
+	})
+
+	handlerCalled := false
+	app.Use(func(c *Ctx) error {
+		handlerCalled = true
+		return c.Next()
+	})
+	handler := func(c *Ctx) error {
+		return c.Send(c.Body())
+	}
+	app.Post("/", handler)
+	app.Post("/large", handler).BodyLimit(16)
+	app.Post("/none", handler).BodyLimit(-1)
+
+	upload := app.Group("/upload").BodyLimit(8)
+	upload.Post("/", handler)
+	upload.Post("/large", handler).BodyLimit(16)
+	upload.Post("/inherit", handler).BodyLimit(0)
+	upload.Group("/sub").Post("/", handler)
+
+	ln, err := app.NewTestListener()
+	utils.AssertEqual(t, nil, err)
+	defer ln.Close()
+	post := func(path, body string) int {
+		resp, err := ln.Client().Post("http://example.com"+path, MIMETextPlain, strings.NewReader(body))
+		utils.AssertEqual(t, nil, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	utils.AssertEqual(t, StatusOK, post("/", "1234"))
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, post("/", "12345"))
+	utils.AssertEqual(t, StatusOK, post("/large", "1234567890123456"))
+	utils.AssertEqual(t, StatusOK, post("/none", ""))
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, post("/none", "1"))
+
+	utils.AssertEqual(t, StatusOK, post("/upload", "12345678"))
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, post("/upload", "123456789"))
+	utils.AssertEqual(t, StatusOK, post("/upload/large", "1234567890123456"))
+	utils.AssertEqual(t, StatusOK, post("/upload/inherit", "12345678"))
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, post("/upload/inherit", "123456789"))
+	utils.AssertEqual(t, StatusOK, post("/upload/sub", "12345678"))
+
+	// the body is rejected before any handler is executed
+	handlerCalled = false
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, post("/", "12345"))
+	utils.AssertEqual(t, false, handlerCalled)
+
+	// the server reads each body only up to the limit of its route
+	utils.AssertEqual(t, 4, app.Server().MaxRequestBodySize)
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, post("/large", "12345678901234567"))
+	for path, limit := range map[string]int{"/": 4, "/large": 16, "/none": 1, "/upload": 8, "/upload/sub": 8, "/unknown": 4} {
+		var header fasthttp.RequestHeader
+		header.SetMethod(MethodPost)
+		header.SetRequestURI(path)
+		header.SetHost("example.com")
+		utils.AssertEqual(t, limit, app.headerReceived(&header).MaxRequestBodySize, path)
+	}
+}
+
+// go test -run Test_App_BodyLimit_Route_StreamRequestBody
+func Test_App_BodyLimit_Route_StreamRequestBody(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		BodyLimit:         4,
+		StreamRequestBody: true,
+	})
+	app.Post("/", func(c *Ctx) error {
+		return c.Send(c.Body())
+	}).BodyLimit(1024 * 1024)
+	ln, err := app.NewTestListener()
+	utils.AssertEqual(t, nil, err)
+	defer ln.Close()
+
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+	// only the start of the body is sent, too large bodies are rejected by their Content-Length
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 1048577\r\n\r\n" + strings.Repeat("a", 16*1024)))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, resp.StatusCode)
+
+	body := strings.Repeat("a", 1024*1024)
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/", strings.NewReader(body)))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	b, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, len(body), len(b))
+}
+
+// go test -run Test_App_MaxFiles_Route
+func Test_App_MaxFiles_Route(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	handlerCalled := false
+	handler := func(c *Ctx) error {
+		handlerCalled = true
+		form, err := c.MultipartForm()
+		if err != nil {
+			return err
+		}
+		return c.SendString(strconv.Itoa(len(form.File["file"])))
+	}
+	app.Post("/", handler).MaxFiles(2)
+	app.Post("/none", handler).MaxFiles(-1)
+	app.Post("/unlimited", handler)
+	upload := app.Group("/upload").MaxFiles(1)
+	upload.Post("/", handler)
+
+	body := func(files int, field bool) string {
+		var b strings.Builder
+		if field {
+			b.WriteString("--b\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\njohn\r\n")
+		}
+		for i := 0; i < files; i++ {
+			b.WriteString("--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"" + strconv.Itoa(i) + ".txt\"\r\n\r\ncontent\r\n")
+		}
+		b.WriteString("--b--\r\n")
+		return b.String()
+	}
+	post := func(path, body string) (int, string) {
+		req := httptest.NewRequest(MethodPost, path, strings.NewReader(body))
+		req.Header.Set(HeaderContentType, MIMEMultipartForm+`; boundary="b"`)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		b, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return resp.StatusCode, string(b)
+	}
+
+	code, res := post("/", body(2, true))
+	utils.AssertEqual(t, StatusOK, code)
+	utils.AssertEqual(t, "2", res)
+	code, res = post("/", body(3, true))
+	utils.AssertEqual(t, StatusBadRequest, code)
+	utils.AssertEqual(t, "multipart: too many files", res)
+	code, _ = post("/none", body(0, true))
+	utils.AssertEqual(t, StatusOK, code)
+	code, _ = post("/none", body(1, false))
+	utils.AssertEqual(t, StatusBadRequest, code)
+	code, res = post("/unlimited", body(10, false))
+	utils.AssertEqual(t, StatusOK, code)
+	utils.AssertEqual(t, "10", res)
+	code, _ = post("/upload", body(2, false))
+	utils.AssertEqual(t, StatusBadRequest, code)
+
+	// the request is rejected before any handler is executed
+	handlerCalled = false
+	code, _ = post("/", body(3, false))
+	utils.AssertEqual(t, StatusBadRequest, code)
+	utils.AssertEqual(t, false, handlerCalled)
+}
+
+// go test -run Test_App_MaxFiles_Route_StreamRequestBody
+func Test_App_MaxFiles_Route_StreamRequestBody(t *testing.T) {
+	t.Parallel()
+	// the method override must not consume the multipart body before the files are counted
+	for _, override := range []bool{false, true} {
+		app := New(Config{
+			StreamRequestBody:            true,
+			DisablePreParseMultipartForm: true,
+			MethodOverride:               MethodOverride{Enable: override},
+		})
+		app.Post("/", func(c *Ctx) error {
+			form, err := c.MultipartForm()
+			if err != nil {
+				return err
+			}
+			return c.SendString(strconv.Itoa(len(form.File["file"])))
+		}).MaxFiles(1)
+		app.Post("/small", func(c *Ctx) error {
+			return c.SendString(strconv.Itoa(len(c.Body())))
+		}).MaxFiles(1).BodyLimit(4 * 1024)
+		ln, err := app.NewTestListener()
+		utils.AssertEqual(t, nil, err)
+
+		part := func(i int) string {
+			return "--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"" + strconv.Itoa(i) + ".txt\"\r\n\r\n" + strings.Repeat("a", 8*1024) + "\r\n"
+		}
+		send := func(body string, contentLength int) *http.Response {
+			conn, err := ln.Dial()
+			utils.AssertEqual(t, nil, err)
+			defer conn.Close()
+			_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nContent-Type: multipart/form-data; boundary=b\r\n" +
+				"Content-Length: " + strconv.Itoa(contentLength) + "\r\n\r\n" + body))
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, nil, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			utils.AssertEqual(t, nil, err)
+			return resp
+		}
+
+		body := part(0) + "--b--\r\n"
+		resp := send(body, len(body))
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+		b, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "1", string(b))
+
+		// the rest of the body after the exceeding file is never sent
+		resp = send(part(0)+part(1), 1024*1024)
+		utils.AssertEqual(t, StatusBadRequest, resp.StatusCode)
+		b, err = ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "multipart: too many files", string(b))
+
+		body = part(0) + part(1) + part(2) + "--b--\r\n"
+		resp = send(body, len(body))
+		utils.AssertEqual(t, StatusBadRequest, resp.StatusCode)
+
+		// chunked bodies are buffered up to the body limit of the route only
+		conn, err := ln.Dial()
+		utils.AssertEqual(t, nil, err)
+		body = part(0) + "--b--\r\n"
+		_, err = conn.Write([]byte("POST /small HTTP/1.1\r\nHost: example.com\r\nContent-Type: multipart/form-data; boundary=b\r\n" +
+			"Transfer-Encoding: chunked\r\n\r\n" + strconv.FormatInt(int64(len(body)), 16) + "\r\n" + body + "\r\n0\r\n\r\n"))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, nil, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		resp, err = http.ReadResponse(bufio.NewReader(conn), nil)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusRequestEntityTooLarge, resp.StatusCode)
+		utils.AssertEqual(t, nil, conn.Close())
+		utils.AssertEqual(t, nil, ln.Close())
+	}
+}
+
+// go test -run Test_App_RejectConflictingContentLength
+func Test_App_RejectConflictingContentLength(t *testing.T) {
+	t.Parallel()
+	app := New(Config{RejectConflictingContentLength: true})
+	app.Post("/", func(c *Ctx) error {
+		return c.Send(c.Body())
+	})
+	ln, err := app.NewTestListener()
+	utils.AssertEqual(t, nil, err)
+	defer func() {
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
+
+	send := func(headers string) *http.Response {
+		conn, err := ln.Dial()
+		utils.AssertEqual(t, nil, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: example.com\r\n" + headers + "\r\nhello"))
+		utils.AssertEqual(t, nil, err)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		utils.AssertEqual(t, nil, err)
+		return resp
+	}
+
+	resp := send("Content-Length: 5\r\nContent-Length: 3\r\n")
+	utils.AssertEqual(t, StatusBadRequest, resp.StatusCode)
+	utils.AssertEqual(t, true, resp.Close)
+	// comma separated lengths are already rejected by fasthttp
+	resp = send("Content-Length: 5, 3\r\n")
+	utils.AssertEqual(t, StatusBadRequest, resp.StatusCode)
+
+	// identical lengths are folded into one
+	resp = send("Content-Length: 5\r\ncontent-length: 5\r\n")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "hello", string(body))
+	resp = send("Content-Length: 5\r\n")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+}
+
+// go test -run Test_App_DisableRecover
+func Test_App_DisableRecover(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	app.Get("/crash", func(c *Ctx) error {
+		panic("crash")
+	}).DisableRecover()
+	grp := app.Group("/api").DisableRecover().(*Group)
+	grp.Get("/crash", func(c *Ctx) error {
+		return nil
+	})
+
+	for _, route := range app.GetRoutes() {
+		if route.Method == MethodGet {
+			utils.AssertEqual(t, true, route.RecoverDisabled())
+		}
+	}
+
+	defer func() {
+		utils.AssertEqual(t, "crash", recover())
+	}()
+	_, _ = app.Test(httptest.NewRequest(MethodGet, "/crash", nil))
+	t.Fatal("panic was not passed to the caller of app.Test")
+}
+
 func Test_App_Chaining(t *testing.T) {
 	n := func(c *Ctx) error {
 		return c.Next()
@@ -595,6 +1289,86 @@ func Test_App_Route_Naming(t *testing.T) {
 	utils.AssertEqual(t, "test", app.GetRoute("test").Name)
 }
 
+// go test -run Test_App_GetRouteURL
+func Test_App_GetRouteURL(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/user/:id<int>", testEmptyHandler).Name("user")
+	app.Get("/posts/:slug?", testEmptyHandler).Name("posts")
+	app.Get("/files/*", testEmptyHandler).Name("files")
+	app.Get("/about", testEmptyHandler).Name("about")
+
+	url, err := app.GetRouteURL("user", Map{"id": 42})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/user/42", url)
+
+	url, err = app.GetRouteURL("user", Map{"ID": "7"})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/user/7", url)
+
+	url, err = app.GetRouteURL("posts", Map{"slug": "hello"})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/posts/hello", url)
+
+	url, err = app.GetRouteURL("posts", Map{})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/posts/", url)
+
+	url, err = app.GetRouteURL("files", Map{"*": "css/style.css"})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/files/css/style.css", url)
+
+	url, err = app.GetRouteURL("about", nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/about", url)
+
+	_, err = app.GetRouteURL("user", Map{"name": "john"})
+	utils.AssertEqual(t, `routeurl: missing parameter "id" for route "user"`, err.Error())
+
+	_, err = app.GetRouteURL("user", Map{"id": "john"})
+	utils.AssertEqual(t, `routeurl: value "john" of parameter "id" does not satisfy its constraints`, err.Error())
+
+	_, err = app.GetRouteURL("unknown", Map{})
+	utils.AssertEqual(t, `routeurl: route "unknown" not found`, err.Error())
+}
+
+// go test -run Test_App_HandlerChain
+func Test_App_HandlerChain(t *testing.T) {
+	t.Parallel()
+	app := New()
+	logger := func(c *Ctx) error { return c.Next() }
+	auth := func(c *Ctx) error { return c.Next() }
+	app.Use(logger)
+	app.Use("/api", auth)
+	app.Use("/admin", auth)
+	app.Get("/api/users/:id", testEmptyHandler, testEmptyHandler).Name("user")
+	app.Post("/", testEmptyHandler)
+
+	routes := app.GetRoutes(true)
+	utils.AssertEqual(t, 3, len(routes))
+	utils.AssertEqual(t, 3+3*len(intMethod), len(app.GetRoutes()))
+
+	chain := app.HandlerChain(app.GetRoute("user"))
+	utils.AssertEqual(t, 4, len(chain))
+	utils.AssertEqual(t, RouteHandler{Name: chain[0].Name, Path: "/", Use: true}, chain[0])
+	utils.AssertEqual(t, true, strings.HasPrefix(chain[0].Name, "github.com/gofiber/fiber/v2.Test_App_HandlerChain"))
+	utils.AssertEqual(t, RouteHandler{Name: chain[1].Name, Path: "/api", Use: true}, chain[1])
+	utils.AssertEqual(t, false, chain[0].Name == chain[1].Name)
+	utils.AssertEqual(t, RouteHandler{Name: chain[2].Name, Path: "/api/users/:id"}, chain[2])
+	utils.AssertEqual(t, chain[2], chain[3])
+
+	for _, route := range routes {
+		if route.Method == MethodPost {
+			chain = app.HandlerChain(route)
+			utils.AssertEqual(t, 2, len(chain))
+			utils.AssertEqual(t, "/", chain[0].Path)
+			utils.AssertEqual(t, false, chain[1].Use)
+		}
+	}
+
+	utils.AssertEqual(t, 0, len(app.HandlerChain(Route{Path: "/unknown"})))
+}
+
 func Test_App_New(t *testing.T) {
 	app := New()
 	app.Get("/", testEmptyHandler)
@@ -612,6 +1386,16 @@ func Test_App_Config(t *testing.T) {
 	utils.AssertEqual(t, true, app.Config().DisableStartupMessage)
 }
 
+// go test -run Test_App_Config_CompressibleTypes
+func Test_App_Config_CompressibleTypes(t *testing.T) {
+	t.Parallel()
+	app := New()
+	utils.AssertEqual(t, DefaultCompressibleTypes, app.config.SendFile.CompressibleTypes)
+	// the defaults aren't shared with the config of the app
+	app.config.SendFile.CompressibleTypes[0] = "video/"
+	utils.AssertEqual(t, "text/", DefaultCompressibleTypes[0])
+}
+
 func Test_App_Shutdown(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		app := New(Config{
@@ -630,6 +1414,89 @@ func Test_App_Shutdown(t *testing.T) {
 	})
 }
 
+// go test -run Test_App_ShutdownWithTimeout
+func Test_App_ShutdownWithTimeout(t *testing.T) {
+	t.Parallel()
+	app := New(Config{DisableStartupMessage: true})
+	draining := make(chan struct{})
+	app.Hooks().OnPreShutdown(func() error {
+		close(draining)
+		return nil
+	})
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	app.Get("/", func(c *Ctx) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		utils.AssertEqual(t, nil, app.Listener(ln))
+	}()
+
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+	<-started
+
+	start := time.Now()
+	err = app.ShutdownWithTimeout(200 * time.Millisecond)
+	utils.AssertEqual(t, true, errors.Is(err, context.DeadlineExceeded))
+	utils.AssertEqual(t, true, time.Since(start) >= 200*time.Millisecond)
+	<-draining
+
+	// the connection has been closed without response
+	_, err = conn.Read(make([]byte, 1))
+	utils.AssertEqual(t, io.EOF, err)
+}
+
+// go test -run Test_App_ShutdownWithTimeout_Drained
+func Test_App_ShutdownWithTimeout_Drained(t *testing.T) {
+	t.Parallel()
+	app := New(Config{DisableStartupMessage: true})
+	var finished, finishedOnPreShutdown, finishedOnShutdown int32
+	app.Get("/", func(c *Ctx) error {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return c.SendString("done")
+	})
+	// the OnPreShutdown hooks run once draining begins, the OnShutdown hooks after it ended
+	app.Hooks().OnPreShutdown(func() error {
+		atomic.StoreInt32(&finishedOnPreShutdown, atomic.LoadInt32(&finished))
+		return nil
+	})
+	app.Hooks().OnShutdown(func() error {
+		atomic.StoreInt32(&finishedOnShutdown, atomic.LoadInt32(&finished))
+		return nil
+	})
+
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		utils.AssertEqual(t, nil, app.Listener(ln))
+	}()
+
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+	time.Sleep(10 * time.Millisecond)
+
+	// the in-flight request finishes within the timeout
+	utils.AssertEqual(t, nil, app.ShutdownWithTimeout(time.Second))
+	utils.AssertEqual(t, int32(0), atomic.LoadInt32(&finishedOnPreShutdown))
+	utils.AssertEqual(t, int32(1), atomic.LoadInt32(&finishedOnShutdown))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "done", string(body))
+}
+
 // go test -run Test_App_Static_Index_Default
 func Test_App_Static_Index_Default(t *testing.T) {
 	app := New()
@@ -1037,6 +1904,49 @@ func Test_App_Group(t *testing.T) {
 	// utils.AssertEqual(t, "/test/v1/users", resp.Header.Get("Location"), "Location")
 }
 
+// go test -run Test_App_Host
+func Test_App_Host(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	tenant := app.Host(":tenant.example.com")
+	tenant.Get("/", func(c *Ctx) error {
+		return c.SendString("tenant " + c.Params("tenant"))
+	})
+	tenant.Group("/api").Get("/users/:id", func(c *Ctx) error {
+		return c.SendString(c.Params("tenant") + " user " + c.Params("id"))
+	})
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString("default")
+	})
+
+	tests := []struct {
+		host, path, body string
+	}{
+		{host: "acme.example.com", path: "/", body: "tenant acme"},
+		{host: "ACME.example.com:8080", path: "/", body: "tenant acme"},
+		{host: "acme.example.com", path: "/api/users/1", body: "acme user 1"},
+		{host: "example.org", path: "/", body: "default"},
+		{host: "example.com", path: "/", body: "default"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(MethodGet, tt.path, nil)
+		req.Host = tt.host
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tt.body, string(body), tt.host)
+	}
+
+	req := httptest.NewRequest(MethodGet, "/api/users/1", nil)
+	req.Host = "example.org"
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+}
+
 func Test_App_Route(t *testing.T) {
 	dummyHandler := testEmptyHandler
 
@@ -1294,6 +2204,170 @@ func Test_App_ReadTimeout(t *testing.T) {
 	utils.AssertEqual(t, nil, app.Listen(":4004"))
 }
 
+// go test -run Test_App_MaxConcurrentRequests
+func Test_App_MaxConcurrentRequests(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		MaxConcurrentRequests: 1,
+		ConcurrencyRetryAfter: 2 * time.Second,
+	})
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		entered <- struct{}{}
+		<-release
+		return c.SendString("ok")
+	})
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil), -1)
+		utils.AssertEqual(t, nil, err)
+		done <- resp
+	}()
+	<-entered
+
+	// the limit is reached, so the second request is shed
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusServiceUnavailable, resp.StatusCode)
+	utils.AssertEqual(t, "2", resp.Header.Get(HeaderRetryAfter))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, utils.StatusMessage(StatusServiceUnavailable), string(body))
+
+	close(release)
+	resp = <-done
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	// the in-flight request finished, so new requests are served again
+	go func() { <-entered }()
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+}
+
+// go test -run Test_App_MaxConcurrentRequests_Burst
+func Test_App_MaxConcurrentRequests_Burst(t *testing.T) {
+	t.Parallel()
+	const limit, requests = 4, 16
+	app := New(Config{MaxConcurrentRequests: limit})
+
+	entered, release := make(chan struct{}, requests), make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		entered <- struct{}{}
+		<-release
+		return c.SendString("ok")
+	})
+
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil), -1)
+			utils.AssertEqual(t, nil, err)
+			codes <- resp.StatusCode
+		}()
+	}
+	// exactly the requests above the limit are shed, however they interleave
+	for i := 0; i < requests-limit; i++ {
+		utils.AssertEqual(t, StatusServiceUnavailable, <-codes)
+	}
+	for i := 0; i < limit; i++ {
+		<-entered
+	}
+	close(release)
+	for i := 0; i < limit; i++ {
+		utils.AssertEqual(t, StatusOK, <-codes)
+	}
+}
+
+// go test -run Test_App_NotFoundHandler
+func Test_App_NotFoundHandler(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		NotFoundHandler: func(c *Ctx) error {
+			return c.JSON(Map{"status": c.Response().StatusCode(), "path": c.Path()})
+		},
+	})
+	app.Use(func(c *Ctx) error {
+		c.Set("X-Middleware", "1")
+		return c.Next()
+	})
+	app.Get("/users", testEmptyHandler)
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/unknown", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, MIMEApplicationJSON, resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "1", resp.Header.Get("X-Middleware"))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"path":"/unknown","status":404}`, string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/users", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "GET, HEAD", resp.Header.Get(HeaderAllow))
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"path":"/users","status":405}`, string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/users", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+}
+
+// go test -run Test_App_Stats
+func Test_App_Stats(t *testing.T) {
+	t.Parallel()
+	app := New()
+	inFlight := make(chan int32, 1)
+	app.Post("/", func(c *Ctx) error {
+		inFlight <- app.Stats().InFlight
+		return c.Send(c.Body())
+	})
+	utils.AssertEqual(t, Stats{}, app.Stats())
+
+	for i := 0; i < 3; i++ {
+		resp, err := app.Test(httptest.NewRequest(MethodPost, "/", strings.NewReader("hello")))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+		utils.AssertEqual(t, int32(1), <-inFlight)
+	}
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/unknown", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+
+	stats := app.Stats()
+	utils.AssertEqual(t, uint64(4), stats.Requests)
+	utils.AssertEqual(t, int32(0), stats.InFlight)
+	utils.AssertEqual(t, uint64(15), stats.BytesIn)
+	utils.AssertEqual(t, uint64(15+len("Cannot GET /unknown")), stats.BytesOut)
+}
+
+// go test -run Test_App_MaxURLLength
+func Test_App_MaxURLLength(t *testing.T) {
+	t.Parallel()
+	app := New(Config{MaxURLLength: 8192})
+	utils.AssertEqual(t, 8192+DefaultReadBufferSize, app.config.ReadBufferSize)
+
+	app.Get("/*", func(c *Ctx) error {
+		return c.SendString("ok")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/"+strings.Repeat("a", 8191), nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/"+strings.Repeat("a", 8192), nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusRequestURITooLong, resp.StatusCode, "Status code")
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/?q="+strings.Repeat("a", 8192), nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusRequestURITooLong, resp.StatusCode, "Status code")
+}
+
 // go test -run Test_App_BadRequest
 func Test_App_BadRequest(t *testing.T) {
 	app := New(Config{
@@ -1565,6 +2639,56 @@ func Test_App_Test_no_timeout_infinitely(t *testing.T) {
 	}
 }
 
+// go test -run Test_App_NewTestListener
+func Test_App_NewTestListener(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString("hello")
+	})
+	app.Get("/stream", func(c *Ctx) error {
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			for i := 0; i < 3; i++ {
+				fmt.Fprintf(w, "chunk %d\n", i)
+				_ = w.Flush()
+			}
+		})
+		return nil
+	})
+
+	ln, err := app.NewTestListener()
+	utils.AssertEqual(t, nil, err)
+	defer ln.Close()
+
+	// streamed response
+	resp, err := ln.Client().Get("http://localhost/stream")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, []string{"chunked"}, resp.TransferEncoding)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "chunk 0\nchunk 1\nchunk 2\n", string(body))
+	utils.AssertEqual(t, nil, resp.Body.Close())
+
+	// keep-alive connection
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ {
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		utils.AssertEqual(t, nil, err)
+		resp, err = http.ReadResponse(br, nil)
+		utils.AssertEqual(t, nil, err)
+		body, err = ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "hello", string(body))
+		utils.AssertEqual(t, false, resp.Close)
+	}
+
+	_, err = New(Config{Prefork: true}).NewTestListener()
+	utils.AssertEqual(t, "test: prefork is not supported for in-memory listeners", err.Error())
+}
+
 func Test_App_SetTLSHandler(t *testing.T) {
 	tlsHandler := &TLSHandler{clientHelloInfo: &tls.ClientHelloInfo{
 		ServerName: "example.golang",
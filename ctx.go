@@ -5,23 +5,31 @@
 package fiber
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"mime"
 	"mime/multipart"
 	"net"
 	"net/http"
+	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -38,6 +46,7 @@ const maxParams = 30
 // Some constants for BodyParser, QueryParser and ReqHeaderParser.
 const (
 	queryTag     = "query"
+	headerTag    = "header"
 	reqHeaderTag = "reqHeader"
 	bodyTag      = "form"
 	paramsTag    = "params"
@@ -49,23 +58,30 @@ const userContextKey = "__local_user_context__"
 // Ctx represents the Context which hold the HTTP request and response.
 // It has methods for the request query string, parameters, body, HTTP headers and so on.
 type Ctx struct {
-	app                 *App                 // Reference to *App
-	route               *Route               // Reference to *Route
-	indexRoute          int                  // Index of the current route
-	indexHandler        int                  // Index of the current handler
-	method              string               // HTTP method
-	methodINT           int                  // HTTP method INT equivalent
-	baseURI             string               // HTTP base uri
-	path                string               // HTTP path with the modifications by the configuration -> string copy from pathBuffer
-	pathBuffer          []byte               // HTTP path buffer
-	detectionPath       string               // Route detection path                                  -> string copy from detectionPathBuffer
-	detectionPathBuffer []byte               // HTTP detectionPath buffer
-	treePath            string               // Path for the search in the tree
-	pathOriginal        string               // Original HTTP path
-	values              [maxParams]string    // Route parameter values
-	fasthttp            *fasthttp.RequestCtx // Reference to *fasthttp.RequestCtx
-	matched             bool                 // Non use route matched
-	viewBindMap         *dictpool.Dict       // Default view map to bind template engine
+	app                 *App                        // Reference to *App
+	route               *Route                      // Reference to *Route
+	indexRoute          int                         // Index of the current route
+	indexHandler        int                         // Index of the current handler
+	method              string                      // HTTP method
+	methodINT           int                         // HTTP method INT equivalent
+	baseURI             string                      // HTTP base uri
+	ip                  string                      // Resolved client IP, cached for the request
+	path                string                      // HTTP path with the modifications by the configuration -> string copy from pathBuffer
+	pathBuffer          []byte                      // HTTP path buffer
+	detectionPath       string                      // Route detection path                                  -> string copy from detectionPathBuffer
+	detectionPathBuffer []byte                      // HTTP detectionPath buffer
+	treePath            string                      // Path for the search in the tree
+	pathOriginal        string                      // Original HTTP path
+	values              [maxParams]string           // Route parameter values
+	fasthttp            *fasthttp.RequestCtx        // Reference to *fasthttp.RequestCtx
+	matched             bool                        // Non use route matched
+	viewBindMap         *dictpool.Dict              // Default view map to bind template engine
+	stream              *bufio.Writer               // Response body writer while the stream writer runs
+	streaming           bool                        // Ctx is used by a stream writer and isn't put back into the pool
+	skipETag            bool                        // No ETag is generated for the response, e.g. for immutable files
+	written             *bodyCounter                // Running count of the streamed response body bytes
+	onBodySent          []func(bytesWritten int)    // Callbacks of c.OnBodySent
+	locals              map[interface{}]interface{} // Locals with non-string keys
 }
 
 // TLSHandler object
@@ -100,6 +116,7 @@ type Cookie struct {
 	HTTPOnly    bool      `json:"http_only"`
 	SameSite    string    `json:"same_site"`
 	SessionOnly bool      `json:"session_only"`
+	Partitioned bool      `json:"partitioned"`
 }
 
 // Views is the interface that wraps the Render function.
@@ -142,6 +159,18 @@ func (app *App) AcquireCtx(fctx *fasthttp.RequestCtx) *Ctx {
 	c.fasthttp = fctx
 	// reset base uri
 	c.baseURI = ""
+	// reset cached client ip
+	c.ip = ""
+	// reset streaming state
+	c.stream = nil
+	c.streaming = false
+	c.skipETag = false
+	c.written = nil
+	c.onBodySent = nil
+	// reset locals with non-string keys
+	for key := range c.locals {
+		delete(c.locals, key)
+	}
 	// Prettify path
 	c.configDependentPaths()
 	return c
@@ -149,6 +178,14 @@ func (app *App) AcquireCtx(fctx *fasthttp.RequestCtx) *Ctx {
 
 // ReleaseCtx releases the ctx back into the pool.
 func (app *App) ReleaseCtx(c *Ctx) {
+	// pass the final body size to the callbacks, for streams once they ended
+	if len(c.onBodySent) > 0 {
+		c.bodySent()
+	}
+	// the stream writer may still use the ctx after the handler returned
+	if c.streaming {
+		return
+	}
 	// Reset values
 	c.route = nil
 	c.fasthttp = nil
@@ -159,74 +196,53 @@ func (app *App) ReleaseCtx(c *Ctx) {
 }
 
 // Accepts checks if the specified extensions or content types are acceptable.
+// The offer preferred by the client according to the quality values of the Accept header is returned.
 func (c *Ctx) Accepts(offers ...string) string {
-	if len(offers) == 0 {
-		return ""
-	}
+	return c.getOffer(c.Get(HeaderAccept), acceptsOfferType, offers...)
+}
+
+// AcceptsDefault works like Accepts, but returns the given default type
+// instead of the first offer if the client sends no Accept header.
+//
+//	c.AcceptsDefault("json", "html", "json") // "json" without Accept header
+func (c *Ctx) AcceptsDefault(defaultType string, offers ...string) string {
 	header := c.Get(HeaderAccept)
 	if header == "" {
-		return offers[0]
-	}
-
-	spec, commaPos := "", 0
-	for len(header) > 0 && commaPos != -1 {
-		commaPos = strings.IndexByte(header, ',')
-		if commaPos != -1 {
-			spec = utils.Trim(header[:commaPos], ' ')
-		} else {
-			spec = utils.TrimLeft(header, ' ')
-		}
-		if factorSign := strings.IndexByte(spec, ';'); factorSign != -1 {
-			spec = spec[:factorSign]
-		}
-
-		var mimetype string
-		for _, offer := range offers {
-			if len(offer) == 0 {
-				continue
-				// Accept: */*
-			} else if spec == "*/*" {
-				return offer
-			}
-
-			if strings.IndexByte(offer, '/') != -1 {
-				mimetype = offer // MIME type
-			} else {
-				mimetype = utils.GetMIME(offer) // extension
-			}
-
-			if spec == mimetype {
-				// Accept: <MIME_type>/<MIME_subtype>
-				return offer
-			}
-
-			s := strings.IndexByte(mimetype, '/')
-			// Accept: <MIME_type>/*
-			if strings.HasPrefix(spec, mimetype[:s]) && (spec[s:] == "/*" || mimetype[s:] == "/*") {
-				return offer
-			}
-		}
-		if commaPos != -1 {
-			header = header[commaPos+1:]
-		}
+		return defaultType
 	}
-
-	return ""
+	return getOffer(header, acceptsOfferType, offers...)
 }
 
 // AcceptsCharsets checks if the specified charset is acceptable.
 func (c *Ctx) AcceptsCharsets(offers ...string) string {
-	return getOffer(c.Get(HeaderAcceptCharset), offers...)
+	return c.getOffer(c.Get(HeaderAcceptCharset), acceptsOffer, offers...)
 }
 
 // AcceptsEncodings checks if the specified encoding is acceptable.
+// The parsed Accept-Encoding headers are cached, as clients send only a few distinct values.
 func (c *Ctx) AcceptsEncodings(offers ...string) string {
-	return getOffer(c.Get(HeaderAcceptEncoding), offers...)
+	header := c.Get(HeaderAcceptEncoding)
+	if header == "" {
+		return c.getOffer(header, acceptsOffer, offers...)
+	}
+	return negotiateOffer(parseAcceptEncoding(header), acceptsOffer, offers...)
 }
 
-// AcceptsLanguages checks if the specified language is acceptable.
+// AcceptsLanguages returns the offered language tag which is accepted best by the
+// Accept-Language header, weighted by the q values. Tags are compared case-insensitively and
+// a language range like "en" accepts "en-US" and vice versa, a more specific range is preferred.
+// The wildcard "*" accepts the first offer, an empty string is returned if no offer is accepted.
 func (c *Ctx) AcceptsLanguages(offers ...string) string {
-	return getOffer(c.Get(HeaderAcceptLanguage), offers...)
+	return c.getOffer(c.Get(HeaderAcceptLanguage), acceptsLanguageOffer, offers...)
+}
+
+// getOffer negotiates the offers with the Accept header, without header
+// the first offer is returned unless StrictNegotiation is enabled
+func (c *Ctx) getOffer(header string, isAccepted func(spec, offer string) bool, offers ...string) string {
+	if header == "" && c.app.config.StrictNegotiation {
+		return ""
+	}
+	return getOffer(header, isAccepted, offers...)
 }
 
 // App returns the *App reference to the instance of the Fiber application
@@ -236,10 +252,18 @@ func (c *Ctx) App() *App {
 
 // Append the specified value to the HTTP response header field.
 // If the header is not already set, it creates the header with the specified value.
+// Set-Cookie values are added as separate headers instead.
 func (c *Ctx) Append(field string, values ...string) {
 	if len(values) == 0 {
 		return
 	}
+	// cookies can't be folded into one header, every value gets its own Set-Cookie header
+	if utils.EqualFold(field, HeaderSetCookie) {
+		for _, value := range values {
+			c.fasthttp.Response.Header.Set(HeaderSetCookie, value)
+		}
+		return
+	}
 	h := c.app.getString(c.fasthttp.Response.Header.Peek(field))
 	originalH := h
 	for _, value := range values {
@@ -256,12 +280,13 @@ func (c *Ctx) Append(field string, values ...string) {
 }
 
 // Attachment sets the HTTP response Content-Disposition header field to attachment.
+// Non-ASCII filenames are additionally sent RFC 5987 encoded in the filename* parameter.
 func (c *Ctx) Attachment(filename ...string) {
-	if len(filename) > 0 {
+	if len(filename) > 0 && filename[0] != "" {
 		fname := filepath.Base(filename[0])
 		c.Type(filepath.Ext(fname))
 
-		c.setCanonical(HeaderContentDisposition, `attachment; filename="`+c.app.quoteString(fname)+`"`)
+		c.setCanonical(HeaderContentDisposition, c.app.contentDisposition(fname))
 		return
 	}
 	c.setCanonical(HeaderContentDisposition, "attachment")
@@ -279,35 +304,91 @@ func (c *Ctx) BaseURL() string {
 }
 
 // Body contains the raw body submitted in a POST request.
+// Compressed bodies are decompressed, if that fails the error message is returned as body,
+// use c.DecompressedBody to detect it.
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting instead.
 func (c *Ctx) Body() []byte {
-	var err error
-	var encoding string
-	var body []byte
+	if c.app.config.DisableBodyDecompression {
+		return c.fasthttp.Request.Body()
+	}
+
+	body, err := c.decodeBody(c.contentEncoding())
+	if err != nil {
+		return []byte(err.Error())
+	}
+
+	return body
+}
+
+// DecompressedBody returns the body decompressed according to the Content-Encoding header
+// like c.Body, but returns the error if the body can't be decompressed, e.g.
+// ErrRequestEntityTooLarge if it exceeds Config.MaxDecompressedBodySize, which can be
+// returned by the handler to answer with 413 Request Entity Too Large.
+// The raw body is returned if DisableBodyDecompression is set.
+// Returned value is only valid within the handler. Do not store any references.
+// Make copies or use the Immutable setting instead.
+//
+//	body, err := c.DecompressedBody()
+//	if err != nil {
+//		return err
+//	}
+func (c *Ctx) DecompressedBody() ([]byte, error) {
+	if c.app.config.DisableBodyDecompression {
+		return c.fasthttp.Request.Body(), nil
+	}
+	return c.decodeBody(c.contentEncoding())
+}
+
+// contentEncoding returns the Content-Encoding header of the request
+func (c *Ctx) contentEncoding() (encoding string) {
 	// faster than peek
 	c.Request().Header.VisitAll(func(key, value []byte) {
 		if utils.UnsafeString(key) == HeaderContentEncoding {
 			encoding = utils.UnsafeString(value)
 		}
 	})
+	return encoding
+}
 
+// decodeBody returns the request body decompressed according to the content encoding,
+// bodies of unknown encodings are returned as they are. Decompression stops with
+// ErrRequestEntityTooLarge once the body exceeds Config.MaxDecompressedBodySize.
+func (c *Ctx) decodeBody(encoding string) ([]byte, error) {
+	var decompress func(w io.Writer, p []byte) (int, error)
 	switch encoding {
 	case StrGzip:
-		body, err = c.fasthttp.Request.BodyGunzip()
+		decompress = fasthttp.WriteGunzip
 	case StrBr, StrBrotli:
-		body, err = c.fasthttp.Request.BodyUnbrotli()
+		decompress = fasthttp.WriteUnbrotli
 	case StrDeflate:
-		body, err = c.fasthttp.Request.BodyInflate()
+		decompress = fasthttp.WriteInflate
 	default:
-		body = c.fasthttp.Request.Body()
+		return c.fasthttp.Request.Body(), nil
 	}
-
-	if err != nil {
-		return []byte(err.Error())
+	limit := c.app.config.MaxDecompressedBodySize
+	if limit == 0 {
+		limit = c.app.config.BodyLimit
 	}
+	w := &limitedBuffer{limit: limit}
+	if _, err := decompress(w, c.fasthttp.Request.Body()); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
 
-	return body
+// limitedBuffer collects written data up to the limit, a negative limit disables it
+type limitedBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	if lb.limit >= 0 && len(lb.buf)+len(p) > lb.limit {
+		return 0, ErrRequestEntityTooLarge
+	}
+	lb.buf = append(lb.buf, p...)
+	return len(p), nil
 }
 
 // decoderPool helps to improve BodyParser's, QueryParser's and ReqHeaderParser's performance
@@ -338,61 +419,108 @@ func decoderBuilder(parserConfig ParserConfig) interface{} {
 	return decoder
 }
 
+// BodyType is the kind of request body detected by BodyParserWithInfo.
+type BodyType string
+
+// Kinds of request bodies supported by BodyParser
+const (
+	BodyTypeUnknown   BodyType = ""
+	BodyTypeJSON      BodyType = "json"
+	BodyTypeXML       BodyType = "xml"
+	BodyTypeForm      BodyType = "form"
+	BodyTypeMultipart BodyType = "multipart"
+)
+
 // BodyParser binds the request body to a struct.
 // It supports decoding the following content types based on the Content-Type header:
 // application/json, application/xml, application/x-www-form-urlencoded, multipart/form-data
 // If none of the content types above are matched, it will return a ErrUnprocessableEntity error
+// or the error of Config.UnsupportedMediaTypeHandler if it is set.
+// Bodies which are shorter than the Content-Length header return an error wrapping io.ErrUnexpectedEOF.
 func (c *Ctx) BodyParser(out interface{}) error {
+	_, err := c.BodyParserWithInfo(out)
+	return err
+}
+
+// BodyParserWithInfo works like BodyParser, but also returns the kind of body
+// detected by the Content-Type header, e.g. to label metrics by payload type.
+// BodyTypeUnknown is returned if none of the supported content types matched.
+func (c *Ctx) BodyParserWithInfo(out interface{}) (BodyType, error) {
 	// Get content-type
 	ctype := utils.ToLower(utils.UnsafeString(c.fasthttp.Request.Header.ContentType()))
 
 	ctype = utils.ParseVendorSpecificContentType(ctype)
 
-	// Parse body accordingly
-	if strings.HasPrefix(ctype, MIMEApplicationJSON) {
-		return c.app.config.JSONDecoder(c.Body(), out)
+	bodyType := getBodyType(ctype)
+	if bodyType == BodyTypeUnknown {
+		// No suitable content type found
+		if c.app.config.UnsupportedMediaTypeHandler != nil {
+			return bodyType, c.app.config.UnsupportedMediaTypeHandler(c, ctype)
+		}
+		return bodyType, ErrUnprocessableEntity
 	}
-	if strings.HasPrefix(ctype, MIMEApplicationForm) {
-		data := make(map[string][]string)
-		var err error
-
-		c.fasthttp.PostArgs().VisitAll(func(key, val []byte) {
-			if err != nil {
-				return
-			}
-
-			k := utils.UnsafeString(key)
-			v := utils.UnsafeString(val)
 
-			if strings.Contains(k, "[") {
-				k, err = parseParamSquareBrackets(k)
-			}
-
-			if strings.Contains(v, ",") && equalFieldType(out, reflect.Slice, k) {
-				values := strings.Split(v, ",")
-				for i := 0; i < len(values); i++ {
-					data[k] = append(data[k], values[i])
-				}
-			} else {
-				data[k] = append(data[k], v)
-			}
-
-		})
+	// Reject truncated bodies instead of parsing partial data
+	body := c.fasthttp.Request.Body()
+	if length := c.fasthttp.Request.Header.ContentLength(); length > 0 && len(body) < length {
+		return bodyType, fmt.Errorf("bodyparser: body of %d bytes is shorter than the Content-Length of %d bytes: %w",
+			len(body), length, io.ErrUnexpectedEOF)
+	}
 
-		return c.parseToStruct(bodyTag, out, data)
+	// Decompress the body according to the content encoding
+	encoding := c.contentEncoding()
+	compressed := encoding == StrGzip || encoding == StrBr || encoding == StrBrotli || encoding == StrDeflate
+	if compressed {
+		if c.app.config.DisableBodyDecompression {
+			return bodyType, NewError(StatusUnsupportedMediaType,
+				fmt.Sprintf("bodyparser: cannot parse %s encoded body, body decompression is disabled", encoding))
+		}
+		var err error
+		if body, err = c.decodeBody(encoding); err != nil {
+			return bodyType, fmt.Errorf("bodyparser: failed to decompress %s body: %w", encoding, err)
+		}
 	}
-	if strings.HasPrefix(ctype, MIMEMultipartForm) {
+
+	// Parse body accordingly
+	switch bodyType {
+	case BodyTypeJSON:
+		return bodyType, c.app.config.JSONDecoder(body, out)
+	case BodyTypeForm:
+		postArgs := c.fasthttp.PostArgs()
+		if compressed {
+			postArgs = fasthttp.AcquireArgs()
+			defer fasthttp.ReleaseArgs(postArgs)
+			postArgs.ParseBytes(body)
+		}
+		return bodyType, c.parseArgs(bodyTag, postArgs, out)
+	case BodyTypeMultipart:
 		data, err := c.fasthttp.MultipartForm()
 		if err != nil {
-			return err
+			return bodyType, err
+		}
+		return bodyType, c.parseToStruct(bodyTag, out, data.Value)
+	default:
+		// namespaced fields are matched through "ns-url name" xml tags
+		if err := xml.Unmarshal(body, out); err != nil {
+			return bodyType, fmt.Errorf("bodyparser: failed to decode xml: %w", err)
 		}
-		return c.parseToStruct(bodyTag, out, data.Value)
+		return bodyType, nil
 	}
-	if strings.HasPrefix(ctype, MIMETextXML) || strings.HasPrefix(ctype, MIMEApplicationXML) {
-		return xml.Unmarshal(c.Body(), out)
+}
+
+// getBodyType returns the kind of body of the lowercase content type
+func getBodyType(ctype string) BodyType {
+	switch {
+	case strings.HasPrefix(ctype, MIMEApplicationJSON):
+		return BodyTypeJSON
+	case strings.HasPrefix(ctype, MIMEApplicationForm):
+		return BodyTypeForm
+	case strings.HasPrefix(ctype, MIMEMultipartForm):
+		return BodyTypeMultipart
+	case strings.HasPrefix(ctype, MIMETextXML) || strings.HasPrefix(ctype, MIMEApplicationXML):
+		return BodyTypeXML
 	}
-	// No suitable content type found
-	return ErrUnprocessableEntity
+	return BodyTypeUnknown
 }
 
 // ClearCookie expires a specific cookie by key on the client side.
@@ -409,6 +537,22 @@ func (c *Ctx) ClearCookie(key ...string) {
 	})
 }
 
+// ClearScopedCookie expires a cookie which was set with a path or domain on the client side,
+// browsers only remove such a cookie if the path and domain match.
+//
+//	c.ClearScopedCookie("session", "/admin", "example.com")
+func (c *Ctx) ClearScopedCookie(key, path, domain string) {
+	c.fasthttp.Response.Header.DelCookie(key)
+
+	fcookie := fasthttp.AcquireCookie()
+	fcookie.SetKey(key)
+	fcookie.SetPath(path)
+	fcookie.SetDomain(domain)
+	fcookie.SetExpire(fasthttp.CookieExpireDelete)
+	c.fasthttp.Response.Header.SetCookie(fcookie)
+	fasthttp.ReleaseCookie(fcookie)
+}
+
 // Context returns *fasthttp.RequestCtx that carries a deadline
 // a cancellation signal, and other values across API boundaries.
 func (c *Ctx) Context() *fasthttp.RequestCtx {
@@ -432,9 +576,57 @@ func (c *Ctx) SetUserContext(ctx context.Context) {
 	c.fasthttp.SetUserValue(userContextKey, ctx)
 }
 
+// ErrCookieSameSiteNoneInsecure is returned by c.SetCookie for a cookie with SameSite=None
+// that isn't Secure, if the RejectInsecureSameSiteNone config is set.
+var ErrCookieSameSiteNoneInsecure = errors.New("cookie: SameSite=None requires the Secure attribute")
+
 // Cookie sets a cookie by passing a cookie struct.
+// Unknown SameSite values fall back to Lax and SameSite=None implies Secure,
+// see the RejectInsecureSameSiteNone config, use c.SetCookie to get its error.
 func (c *Ctx) Cookie(cookie *Cookie) {
+	_ = c.SetCookie(cookie)
+}
+
+// SetCookie works like c.Cookie, but returns ErrCookieSameSiteNoneInsecure instead of
+// setting the cookie if it's rejected because of the RejectInsecureSameSiteNone config.
+func (c *Ctx) SetCookie(cookie *Cookie) error {
+	if c.app.config.RejectInsecureSameSiteNone && !cookie.Secure &&
+		utils.EqualFold(cookie.SameSite, CookieSameSiteNoneMode) {
+		return ErrCookieSameSiteNoneInsecure
+	}
+
+	fcookie := fasthttp.AcquireCookie()
+	setFasthttpCookie(fcookie, cookie)
+
+	if cookie.Partitioned {
+		// fasthttp doesn't know the Partitioned attribute, so the header value is set directly
+		c.fasthttp.Response.Header.DelCookie(cookie.Name)
+		c.fasthttp.Response.Header.SetBytesV(HeaderSetCookie, appendPartitioned(fcookie.Cookie()))
+	} else {
+		c.fasthttp.Response.Header.SetCookie(fcookie)
+	}
+	fasthttp.ReleaseCookie(fcookie)
+	return nil
+}
+
+// BuildSetCookie returns the Set-Cookie header value for the given cookie.
+// It doesn't need a Ctx, e.g. to set cookies on proxied responses.
+func BuildSetCookie(cookie Cookie) string {
 	fcookie := fasthttp.AcquireCookie()
+	setFasthttpCookie(fcookie, &cookie)
+
+	var setCookie string
+	if cookie.Partitioned {
+		setCookie = string(appendPartitioned(fcookie.Cookie()))
+	} else {
+		setCookie = string(fcookie.Cookie())
+	}
+	fasthttp.ReleaseCookie(fcookie)
+	return setCookie
+}
+
+// setFasthttpCookie copies the attributes of the cookie struct to the fasthttp cookie
+func setFasthttpCookie(fcookie *fasthttp.Cookie, cookie *Cookie) {
 	fcookie.SetKey(cookie.Name)
 	fcookie.SetValue(cookie.Value)
 	fcookie.SetPath(cookie.Path)
@@ -453,15 +645,19 @@ func (c *Ctx) Cookie(cookie *Cookie) {
 	case CookieSameSiteStrictMode:
 		fcookie.SetSameSite(fasthttp.CookieSameSiteStrictMode)
 	case CookieSameSiteNoneMode:
+		// implies the Secure attribute
 		fcookie.SetSameSite(fasthttp.CookieSameSiteNoneMode)
 	case CookieSameSiteDisabled:
 		fcookie.SetSameSite(fasthttp.CookieSameSiteDisabled)
 	default:
 		fcookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
 	}
+}
 
-	c.fasthttp.Response.Header.SetCookie(fcookie)
-	fasthttp.ReleaseCookie(fcookie)
+// appendPartitioned appends the Partitioned attribute to a serialized cookie
+// https://developer.mozilla.org/en-US/docs/Web/Privacy/Partitioned_cookies
+func appendPartitioned(setCookie []byte) []byte {
+	return append(setCookie, "; Partitioned"...)
 }
 
 // Cookies is used for getting a cookie value by key.
@@ -473,10 +669,50 @@ func (c *Ctx) Cookies(key string, defaultValue ...string) string {
 	return defaultString(c.app.getString(c.fasthttp.Request.Header.Cookie(key)), defaultValue)
 }
 
+// CookieInt returns the cookie value converted to an int, validated like the int route constraint.
+// The defaultValue or 0 is returned if the cookie is missing or the value isn't an int.
+func (c *Ctx) CookieInt(key string, defaultValue ...int) int {
+	value, err := strconv.Atoi(c.app.getString(c.fasthttp.Request.Header.Cookie(key)))
+	return defaultInt(value, err, defaultValue)
+}
+
+// CookieBool returns the cookie value converted to a bool, validated like the bool route constraint.
+// The defaultValue or false is returned if the cookie is missing or the value isn't a bool.
+func (c *Ctx) CookieBool(key string, defaultValue ...bool) bool {
+	value, err := strconv.ParseBool(c.app.getString(c.fasthttp.Request.Header.Cookie(key)))
+	return defaultBool(value, err, defaultValue)
+}
+
+// CookieFloat returns the cookie value converted to a float64, validated like the float route constraint.
+// The defaultValue or 0 is returned if the cookie is missing or the value isn't a float.
+func (c *Ctx) CookieFloat(key string, defaultValue ...float64) float64 {
+	value, err := strconv.ParseFloat(c.app.getString(c.fasthttp.Request.Header.Cookie(key)), 64)
+	return defaultFloat(value, err, defaultValue)
+}
+
+// CookieConstraint returns the cookie value if it satisfies the constraints compiled once
+// with app.CompileConstraint, e.g. "guid" or "alpha;minLen(3)". An error is returned
+// if the value, which is empty for a missing cookie, doesn't satisfy them.
+//
+//	sessionConstraint, _ := app.CompileConstraint(fiber.ConstraintGuid)
+//	session, err := c.CookieConstraint("session", sessionConstraint)
+//
+// The returned value is only valid within the handler. Do not store any references.
+func (c *Ctx) CookieConstraint(key string, constraint *CompiledConstraint) (string, error) {
+	value := c.app.getString(c.fasthttp.Request.Header.Cookie(key))
+	if !constraint.Check(value) {
+		return "", fmt.Errorf("cookie: %q does not satisfy the constraint %q", key, constraint)
+	}
+	return value, nil
+}
+
 // Download transfers the file from path as an attachment.
 // Typically, browsers will prompt the user for download.
 // By default, the Content-Disposition header filename= parameter is the filepath (this typically appears in the browser dialog).
 // Override this default with the filename parameter.
+// Non-ASCII filenames are encoded as described in RFC 5987.
+// Like c.SendFile, relative paths are resolved against Config.SendFile.Root.
+// If the file can't be sent, the error of c.SendFile is returned and no attachment is sent.
 func (c *Ctx) Download(file string, filename ...string) error {
 	var fname string
 	if len(filename) > 0 {
@@ -484,8 +720,13 @@ func (c *Ctx) Download(file string, filename ...string) error {
 	} else {
 		fname = filepath.Base(file)
 	}
-	c.setCanonical(HeaderContentDisposition, `attachment; filename="`+c.app.quoteString(fname)+`"`)
-	return c.SendFile(file)
+	c.setCanonical(HeaderContentDisposition, c.app.contentDisposition(fname))
+	// an error response must not be sent as attachment
+	if err := c.SendFile(file); err != nil {
+		c.fasthttp.Response.Header.Del(HeaderContentDisposition)
+		return err
+	}
+	return nil
 }
 
 // Request return the *fasthttp.Request object
@@ -554,6 +795,7 @@ func (c *Ctx) FormValue(key string, defaultValue ...string) string {
 // and the full response should be sent.
 // When a client sends the Cache-Control: no-cache request header to indicate an end-to-end
 // reload request, this module will return false to make handling these requests transparent.
+// If it returns true, the handler can answer with c.SendStatus(StatusNotModified).
 // https://github.com/jshttp/fresh/blob/10e0471669dbbfbfd8de65bc6efac2ddd0bfa057/index.js#L33
 func (c *Ctx) Fresh() bool {
 	// fields
@@ -576,26 +818,56 @@ func (c *Ctx) Fresh() bool {
 	// if-none-match
 	if noneMatch != "" && noneMatch != "*" {
 		etag := c.app.getString(c.fasthttp.Response.Header.Peek(HeaderETag))
-		if etag == "" {
+		if etag == "" || c.app.isEtagStale(etag, c.app.getBytes(noneMatch)) {
+			return false
+		}
+	}
+
+	// if-modified-since, both conditions must be fresh if both headers are present
+	if modifiedSince != "" {
+		lastModified := c.app.getString(c.fasthttp.Response.Header.Peek(HeaderLastModified))
+		if lastModified == "" {
+			return false
+		}
+		lastModifiedTime, err := http.ParseTime(lastModified)
+		if err != nil {
 			return false
 		}
-		if c.app.isEtagStale(etag, c.app.getBytes(noneMatch)) {
+		modifiedSinceTime, err := http.ParseTime(modifiedSince)
+		if err != nil {
 			return false
 		}
+		// the client echoes the Last-Modified date, so the same time is fresh
+		return !lastModifiedTime.After(modifiedSinceTime)
+	}
+	return true
+}
 
-		if modifiedSince != "" {
-			lastModified := c.app.getString(c.fasthttp.Response.Header.Peek(HeaderLastModified))
-			if lastModified != "" {
-				lastModifiedTime, err := http.ParseTime(lastModified)
-				if err != nil {
-					return false
-				}
-				modifiedSinceTime, err := http.ParseTime(modifiedSince)
-				if err != nil {
-					return false
-				}
-				return lastModifiedTime.Before(modifiedSinceTime)
+// CheckPreconditions evaluates the If-Match and If-Unmodified-Since request headers
+// against the current ETag and modification time of the resource, e.g. before a PUT or PATCH
+// to implement optimistic concurrency. Pass an empty etag or a zero lastModified if the value
+// is unknown, "If-Match: *" only succeeds if one of them is given. If a precondition fails, the status 412 Precondition Failed is set
+// and false is returned, so the handler can abort.
+// https://www.rfc-editor.org/rfc/rfc7232#section-6
+func (c *Ctx) CheckPreconditions(etag string, lastModified time.Time) bool {
+	if ifMatch := c.Get(HeaderIfMatch); ifMatch != "" {
+		// "*" matches any current representation
+		if utils.Trim(ifMatch, ' ') == "*" {
+			if etag != "" || !lastModified.IsZero() {
+				return true
 			}
+		} else if etag != "" && isEtagMatch(etag, ifMatch) {
+			return true
+		}
+		c.Status(StatusPreconditionFailed)
+		return false
+	}
+	// If-Unmodified-Since is ignored when If-Match is present or the date is invalid
+	if unmodifiedSince := c.Get(HeaderIfUnmodifiedSince); unmodifiedSince != "" && !lastModified.IsZero() {
+		unmodifiedSinceTime, err := http.ParseTime(unmodifiedSince)
+		if err == nil && lastModified.Truncate(time.Second).After(unmodifiedSinceTime) {
+			c.Status(StatusPreconditionFailed)
+			return false
 		}
 	}
 	return true
@@ -609,6 +881,21 @@ func (c *Ctx) Get(key string, defaultValue ...string) string {
 	return defaultString(c.app.getString(c.fasthttp.Request.Header.Peek(key)), defaultValue)
 }
 
+// GetAll returns every value of the HTTP request header specified by field,
+// in the order they were sent, e.g. to walk a chain of Via or Forwarded headers.
+// Field names are case-insensitive
+// Returned value is only valid within the handler. Do not store any references.
+// Make copies or use the Immutable setting instead.
+func (c *Ctx) GetAll(key string) []string {
+	var values []string
+	c.fasthttp.Request.Header.VisitAll(func(k, v []byte) {
+		if utils.EqualFold(c.app.getString(k), key) {
+			values = append(values, c.app.getString(v))
+		}
+	})
+	return values
+}
+
 // GetRespHeader returns the HTTP response header specified by field.
 // Field names are case-insensitive
 // Returned value is only valid within the handler. Do not store any references.
@@ -617,6 +904,19 @@ func (c *Ctx) GetRespHeader(key string, defaultValue ...string) string {
 	return defaultString(c.app.getString(c.fasthttp.Response.Header.Peek(key)), defaultValue)
 }
 
+// localsKey is the type of the Locals keys of fiber, which can't collide with the keys of other packages
+type localsKey int
+
+// LocalsKeyRequestID is the Locals key of the ID of the request, which is set by the requestid middleware
+const LocalsKeyRequestID localsKey = 0
+
+// RequestID returns the ID of the request, which is set by the requestid middleware,
+// independent of the configured header. An empty string is returned if no ID is set.
+func (c *Ctx) RequestID() string {
+	rid, _ := c.Locals(LocalsKeyRequestID).(string)
+	return rid
+}
+
 // GetReqHeaders returns the HTTP request headers.
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting instead.
@@ -655,20 +955,50 @@ func (c *Ctx) Hostname() string {
 }
 
 // Port returns the remote port of the request.
+// If EnableTrustedProxyCheck is enabled and the request comes from a trusted proxy,
+// the port of the X-Forwarded-Port header is returned instead, which is the port
+// the client connected to on the proxy, falling back to the local port of the connection.
+// An empty string is returned for connections without ports, e.g. on unix sockets.
 func (c *Ctx) Port() string {
-	port := c.fasthttp.RemoteAddr().(*net.TCPAddr).Port
-	return strconv.Itoa(port)
+	addr := c.fasthttp.RemoteAddr()
+	if c.app.config.EnableTrustedProxyCheck && c.IsProxyTrusted() {
+		if port := c.Get(HeaderXForwardedPort); port != "" {
+			// ignore invalid ports
+			if n, err := strconv.Atoi(port); err == nil && n > 0 && n <= 65535 {
+				return port
+			}
+		}
+		addr = c.fasthttp.LocalAddr()
+	}
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return strconv.Itoa(tcpAddr.Port)
+	}
+	return ""
 }
 
 // IP returns the remote IP address of the request.
 // If ProxyHeader and IP Validation is configured, it will parse that header and return the first valid IP address.
 // Please use Config.EnableTrustedProxyCheck to prevent header spoofing, in case when your app is behind the proxy.
+// With EnableTrustedProxyCheck and X-Forwarded-For as ProxyHeader, the X-Forwarded-For chain is walked
+// from right to left and the first address that is not a trusted proxy is returned.
+// The resolved IP is cached for the rest of the request, so repeated calls do not parse the headers again.
 func (c *Ctx) IP() string {
+	if c.ip != "" {
+		return c.ip
+	}
+
 	if c.IsProxyTrusted() && len(c.app.config.ProxyHeader) > 0 {
-		return c.extractIPFromHeader(c.app.config.ProxyHeader)
+		// copy the header value, the request header buffer may be rewritten during the request
+		if c.app.config.EnableTrustedProxyCheck && utils.EqualFold(c.app.config.ProxyHeader, HeaderXForwardedFor) {
+			c.ip = utils.CopyString(c.forwardedForClientIP())
+		} else {
+			c.ip = utils.CopyString(c.extractIPFromHeader(c.app.config.ProxyHeader))
+		}
+	} else {
+		c.ip = c.fasthttp.RemoteIP().String()
 	}
 
-	return c.fasthttp.RemoteIP().String()
+	return c.ip
 }
 
 // validateIPIfEnabled will return the input IP when validation is disabled.
@@ -747,8 +1077,55 @@ func (c *Ctx) extractIPFromHeader(header string) string {
 
 // IPs returns a string slice of IP addresses specified in the X-Forwarded-For request header.
 // When IP validation is enabled, only valid IPs are returned.
+// When EnableTrustedProxyCheck is enabled, the header is ignored if the request does not come from
+// a trusted proxy, and the trusted proxies at the end of the chain are omitted, so the last address is c.IP().
 func (c *Ctx) IPs() (ips []string) {
-	return c.extractIPsFromHeader(HeaderXForwardedFor)
+	if !c.app.config.EnableTrustedProxyCheck {
+		return c.extractIPsFromHeader(HeaderXForwardedFor)
+	}
+	if !c.IsProxyTrusted() {
+		return nil
+	}
+	ips = c.extractIPsFromHeader(HeaderXForwardedFor)
+	if len(ips) == 0 {
+		return ips
+	}
+	return ips[:c.app.untrustedForwardedFor(ips)+1]
+}
+
+// forwardedForClientIP returns the first address of the X-Forwarded-For header, from right to left,
+// that is not a trusted proxy, or the remote IP if the header is empty
+func (c *Ctx) forwardedForClientIP() string {
+	ips := c.extractIPsFromHeader(HeaderXForwardedFor)
+	if len(ips) == 0 {
+		return c.fasthttp.RemoteIP().String()
+	}
+	ip := ips[c.app.untrustedForwardedFor(ips)]
+	if parsed := parseForwardedIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// parseForwardedIP parses an address of the X-Forwarded-For header, which may contain a port
+func parseForwardedIP(addr string) net.IP {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip
+	}
+	host, _ := parseAddr(addr)
+	return net.ParseIP(strings.Trim(host, "[]"))
+}
+
+// untrustedForwardedFor returns the index of the last address of the X-Forwarded-For chain
+// that is not a trusted proxy. If all addresses are trusted proxies, the first one is the client.
+func (app *App) untrustedForwardedFor(ips []string) int {
+	for i := len(ips) - 1; i > 0; i-- {
+		ip := parseForwardedIP(ips[i])
+		if ip == nil || !app.isTrustedProxy(ip) {
+			return i
+		}
+	}
+	return 0
 }
 
 // Is returns the matching content type,
@@ -770,7 +1147,9 @@ func (c *Ctx) Is(extension string) bool {
 // except that []byte encodes as a base64-encoded string,
 // and a nil slice encodes as the null JSON value.
 // This method also sets the content header to application/json.
+// If the encoding fails, the response is left untouched and the error is returned.
 func (c *Ctx) JSON(data interface{}) error {
+	// encode completely before writing anything to the response
 	raw, err := c.app.config.JSONEncoder(data)
 	if err != nil {
 		return err
@@ -780,6 +1159,56 @@ func (c *Ctx) JSON(data interface{}) error {
 	return nil
 }
 
+// JSONStream encodes data with the JSONStreamEncoder while the response body is sent,
+// so that large values are neither marshaled into an intermediate buffer nor into the body.
+// The content type is set to application/json, unless another one is given.
+// The encoding starts after the handler returned, data must not be modified until then.
+// As the status and the headers have been sent by then, an encoding error can't be passed to
+// the error handler, instead the chunked body is aborted without its final chunk, so that
+// the client notices the incomplete response.
+func (c *Ctx) JSONStream(data interface{}, ctype ...string) error {
+	if len(ctype) > 0 {
+		c.fasthttp.Response.Header.SetContentType(ctype[0])
+	} else {
+		c.fasthttp.Response.Header.SetContentType(MIMEApplicationJSON)
+	}
+	// an ETag would require to read the whole body
+	c.skipETag = true
+	c.written = &bodyCounter{}
+	c.fasthttp.Response.SetBodyStream(&countingReader{
+		r: &jsonStreamReader{encode: c.app.config.JSONStreamEncoder, data: data},
+		n: c.written,
+	}, -1)
+	return nil
+}
+
+// jsonStreamReader encodes the value into a pipe as soon as the response body is read
+type jsonStreamReader struct {
+	encode func(w io.Writer, v interface{}) error
+	data   interface{}
+	pipe   *io.PipeReader
+}
+
+func (jr *jsonStreamReader) Read(p []byte) (int, error) {
+	if jr.pipe == nil {
+		pr, pw := io.Pipe()
+		jr.pipe = pr
+		go func() {
+			// an encoding error is returned by the next read
+			_ = pw.CloseWithError(jr.encode(pw, jr.data))
+		}()
+	}
+	return jr.pipe.Read(p)
+}
+
+// Close stops the encoding, e.g. if the response body is replaced or the client disconnected
+func (jr *jsonStreamReader) Close() error {
+	if jr.pipe != nil {
+		return jr.pipe.Close()
+	}
+	return nil
+}
+
 // JSONP sends a JSON response with JSONP support.
 // This method is identical to JSON, except that it opts-in to JSONP callback support.
 // By default, the callback name is simply callback.
@@ -835,13 +1264,76 @@ func (c *Ctx) Links(link ...string) {
 	bytebufferpool.Put(bb)
 }
 
-// Locals makes it possible to pass interface{} values under string keys scoped to the request
+// PushOption describes the asset of c.Push.
+type PushOption struct {
+	// As is the destination of the asset, e.g. "style", "script" or "font", omitted if empty
+	As string
+	// CrossOrigin sets the crossorigin attribute, required for fonts, e.g. "anonymous"
+	CrossOrigin string
+	// NoPush asks proxies and CDNs which push preloaded assets over HTTP/2 not to push this one
+	NoPush bool
+}
+
+// ErrPushInvalidPath is returned by c.Push for paths which are not absolute or contain invalid characters.
+var ErrPushInvalidPath = errors.New("push: path must start with a '/' and must not contain '<', '>' or line breaks")
+
+// ErrPushInvalidOption is returned by c.Push if PushOption.As or PushOption.CrossOrigin is not a token.
+var ErrPushInvalidOption = errors.New("push: as and crossorigin must be tokens without separators like ',', ';' or line breaks")
+
+// Push announces the asset at the given path, e.g. critical CSS or JS, to be loaded along with the response.
+// The server doesn't speak HTTP/2, so the asset isn't pushed by the connection itself, instead a
+// "Link: </app.css>; rel=preload" header is added, which lets browsers fetch the asset early and
+// HTTP/2 proxies or CDNs push it. Multiple assets are added to the same Link header.
+//
+//	c.Push("/app.css", fiber.PushOption{As: "style"})
+func (c *Ctx) Push(path string, opts ...PushOption) error {
+	if len(path) == 0 || path[0] != '/' || strings.ContainsAny(path, "<>\r\n") {
+		return ErrPushInvalidPath
+	}
+	var opt PushOption
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if (opt.As != "" && !isToken(opt.As)) || (opt.CrossOrigin != "" && !isToken(opt.CrossOrigin)) {
+		return ErrPushInvalidOption
+	}
+	link := "<" + path + ">; rel=preload"
+	if opt.As != "" {
+		link += "; as=" + opt.As
+	}
+	if opt.CrossOrigin != "" {
+		link += "; crossorigin=" + opt.CrossOrigin
+	}
+	if opt.NoPush {
+		link += "; nopush"
+	}
+	if h := c.fasthttp.Response.Header.Peek(HeaderLink); len(h) > 0 {
+		link = c.app.getString(h) + ", " + link
+	}
+	c.setCanonical(HeaderLink, link)
+	return nil
+}
+
+// Locals makes it possible to pass interface{} values under keys scoped to the request
 // and therefore available to all following routes that match the request.
-func (c *Ctx) Locals(key string, value ...interface{}) (val interface{}) {
+// Like the keys of context.WithValue, the key may be any comparable value.
+// Packages should use an unexported key type, e.g. "type ctxKey struct{}", to avoid collisions.
+func (c *Ctx) Locals(key interface{}, value ...interface{}) (val interface{}) {
+	// string keys are stored as user values of the fasthttp request
+	if k, ok := key.(string); ok {
+		if len(value) == 0 {
+			return c.fasthttp.UserValue(k)
+		}
+		c.fasthttp.SetUserValue(k, value[0])
+		return value[0]
+	}
 	if len(value) == 0 {
-		return c.fasthttp.UserValue(key)
+		return c.locals[key]
 	}
-	c.fasthttp.SetUserValue(key, value[0])
+	if c.locals == nil {
+		c.locals = make(map[interface{}]interface{})
+	}
+	c.locals[key] = value[0]
 	return value[0]
 }
 
@@ -879,6 +1371,27 @@ func (c *Ctx) ClientHelloInfo() *tls.ClientHelloInfo {
 	return nil
 }
 
+// ClientCertificates returns the verified certificate chain of the client, beginning with the
+// leaf certificate, e.g. for mutual TLS with tls.RequireAndVerifyClientCert.
+// It returns nil for plain connections or if no client certificate was verified.
+func (c *Ctx) ClientCertificates() []*x509.Certificate {
+	state := c.fasthttp.TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0]
+}
+
+// TLSVersion returns the negotiated TLS version of the connection, e.g. tls.VersionTLS13,
+// and 0 for plain connections.
+func (c *Ctx) TLSVersion() uint16 {
+	state := c.fasthttp.TLSConnectionState()
+	if state == nil {
+		return 0
+	}
+	return state.Version
+}
+
 // Next executes the next method in the stack that matches the current route.
 func (c *Ctx) Next() (err error) {
 	// Increment handler index
@@ -912,6 +1425,11 @@ func (c *Ctx) OriginalURL() string {
 // Params is used to get the route parameters.
 // Defaults to empty string "" if the param doesn't exist.
 // If a default value is given, it will return that value if the param doesn't exist.
+// A trailing wildcard ("/static/*" or the named "/static/:path*") captures the remaining path without leading slashes,
+// the value is decoded only if the UnescapePath setting is enabled.
+// Capture groups of a regex constraint are available as params as well, named groups by their name,
+// e.g. "/:ver<regex(v\\(\\?P\\<major\\>[0-9]+\\))>" provides "major", and unnamed groups by the
+// parameter name and their number, e.g. "/:date<regex(\\([0-9]{4}\\)\\-\\([0-9]{2}\\))>" provides "date.1" and "date.2".
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting to use the value outside the Handler.
 func (c *Ctx) Params(key string, defaultValue ...string) string {
@@ -957,7 +1475,10 @@ func (c *Ctx) ParamsParser(out interface{}) error {
 // it defaults to zero if the parameter is not found or if the
 // parameter cannot be converted to an integer
 // If a default value is given, it will return that value in case the param
-// doesn't exist or cannot be converted to an integer
+// doesn't exist or cannot be converted to an integer, negative defaults are allowed.
+// The value is parsed as base-10 int, values out of the int range can't be converted.
+// If the route guarantees an integer with the int constraint ("/user/:id<int>"),
+// no error is returned. Otherwise the error names the parameter and the parsed value.
 func (c *Ctx) ParamsInt(key string, defaultValue ...int) (int, error) {
 	// Use Atoi to convert the param to an int or return zero and an error
 	value, err := strconv.Atoi(c.Params(key))
@@ -965,7 +1486,7 @@ func (c *Ctx) ParamsInt(key string, defaultValue ...int) (int, error) {
 		if len(defaultValue) > 0 {
 			return defaultValue[0], nil
 		} else {
-			return 0, err
+			return 0, fmt.Errorf("params: failed to convert %q to int: %w", key, err)
 		}
 	}
 
@@ -1024,12 +1545,40 @@ func (c *Ctx) Query(key string, defaultValue ...string) string {
 	return defaultString(c.app.getString(c.fasthttp.QueryArgs().Peek(key)), defaultValue)
 }
 
+// QueryInt returns the query string parameter converted to an int.
+// The defaultValue or 0 is returned if the key is missing or the value isn't an int.
+func (c *Ctx) QueryInt(key string, defaultValue ...int) int {
+	value, err := strconv.Atoi(c.app.getString(c.fasthttp.QueryArgs().Peek(key)))
+	return defaultInt(value, err, defaultValue)
+}
+
+// QueryBool returns the query string parameter converted to a bool,
+// "1", "t" and "true" are true, "0", "f" and "false" are false, see strconv.ParseBool.
+// The defaultValue or false is returned if the key is missing or the value isn't a bool.
+func (c *Ctx) QueryBool(key string, defaultValue ...bool) bool {
+	value, err := strconv.ParseBool(c.app.getString(c.fasthttp.QueryArgs().Peek(key)))
+	return defaultBool(value, err, defaultValue)
+}
+
+// QueryFloat returns the query string parameter converted to a float64, validated like the float route constraint.
+// The defaultValue or 0 is returned if the key is missing or the value isn't a float.
+func (c *Ctx) QueryFloat(key string, defaultValue ...float64) float64 {
+	value, err := strconv.ParseFloat(c.app.getString(c.fasthttp.QueryArgs().Peek(key)), 64)
+	return defaultFloat(value, err, defaultValue)
+}
+
 // QueryParser binds the query string to a struct.
 func (c *Ctx) QueryParser(out interface{}) error {
+	return c.parseArgs(queryTag, c.fasthttp.QueryArgs(), out)
+}
+
+// parseArgs binds query or form arguments to out. Repeated keys and comma separated values
+// are collected into slices, "ids[0]" style keys into ordered slices and "meta[key]" style keys into maps.
+func (c *Ctx) parseArgs(aliasTag string, args *fasthttp.Args, out interface{}) error {
 	data := make(map[string][]string)
 	var err error
 
-	c.fasthttp.QueryArgs().VisitAll(func(key, val []byte) {
+	args.VisitAll(func(key, val []byte) {
 		if err != nil {
 			return
 		}
@@ -1055,8 +1604,10 @@ func (c *Ctx) QueryParser(out interface{}) error {
 	if err != nil {
 		return err
 	}
+	bindMapKeys(aliasTag, out, data)
+	mergeIndexedKeys(data)
 
-	return c.parseToStruct(queryTag, out, data)
+	return c.parseToStruct(aliasTag, out, data)
 }
 
 func parseParamSquareBrackets(k string) (string, error) {
@@ -1085,17 +1636,124 @@ func parseParamSquareBrackets(k string) (string, error) {
 	return bb.String(), nil
 }
 
-// ReqHeaderParser binds the request header strings to a struct.
-func (c *Ctx) ReqHeaderParser(out interface{}) error {
-	data := make(map[string][]string)
-	c.fasthttp.Request.Header.VisitAll(func(key, val []byte) {
-		k := utils.UnsafeString(key)
-		v := utils.UnsafeString(val)
-
-		if strings.Contains(v, ",") && equalFieldType(out, reflect.Slice, k) {
+// mergeIndexedKeys merges the values of indexed keys like "ids.0" and "ids.1",
+// which are parsed from "ids[0]" and "ids[1]", into the key "ids" ordered by their index
+func mergeIndexedKeys(data map[string][]string) {
+	type indexedValues struct {
+		index  int
+		values []string
+	}
+	var merged map[string][]indexedValues
+	for k, v := range data {
+		dot := strings.LastIndexByte(k, '.')
+		if dot == -1 {
+			continue
+		}
+		index, err := strconv.Atoi(k[dot+1:])
+		if err != nil || index < 0 {
+			continue
+		}
+		if merged == nil {
+			merged = make(map[string][]indexedValues)
+		}
+		merged[k[:dot]] = append(merged[k[:dot]], indexedValues{index: index, values: v})
+		delete(data, k)
+	}
+	for k, indexed := range merged {
+		sort.Slice(indexed, func(i, j int) bool {
+			return indexed[i].index < indexed[j].index
+		})
+		for _, iv := range indexed {
+			data[k] = append(data[k], iv.values...)
+		}
+	}
+}
+
+// bindMapKeys sets the values of keys like "meta.foo", which are parsed from "meta[foo]",
+// on the map fields of out and removes them from data, as the schema decoder doesn't support maps.
+// Maps with string keys and string or []string values are supported.
+func bindMapKeys(aliasTag string, out interface{}, data map[string][]string) {
+	outVal := reflect.ValueOf(out)
+	if outVal.Kind() != reflect.Ptr || outVal.Elem().Kind() != reflect.Struct {
+		return
+	}
+	for k, values := range data {
+		parts := strings.Split(k, ".")
+		if len(parts) < 2 {
+			continue
+		}
+		// walk the structs down to the map field
+		field := outVal.Elem()
+		for _, part := range parts[:len(parts)-1] {
+			if field.Kind() != reflect.Struct {
+				field = reflect.Value{}
+				break
+			}
+			if field = structFieldByAlias(field, aliasTag, part); !field.IsValid() {
+				break
+			}
+		}
+		if !field.IsValid() || field.Kind() != reflect.Map || field.Type().Key().Kind() != reflect.String {
+			continue
+		}
+		var val reflect.Value
+		switch elem := field.Type().Elem(); {
+		case elem.Kind() == reflect.String:
+			val = reflect.ValueOf(values[len(values)-1]).Convert(elem)
+		case elem.Kind() == reflect.Slice && elem.Elem().Kind() == reflect.String:
+			val = reflect.ValueOf(values).Convert(elem)
+		default:
+			continue
+		}
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		field.SetMapIndex(reflect.ValueOf(parts[len(parts)-1]).Convert(field.Type().Key()), val)
+		delete(data, k)
+	}
+}
+
+// structFieldByAlias returns the settable field of the struct whose alias tag
+// or name equals the given name case-insensitively
+func structFieldByAlias(structVal reflect.Value, aliasTag, name string) reflect.Value {
+	structTyp := structVal.Type()
+	for i := 0; i < structTyp.NumField(); i++ {
+		typeField := structTyp.Field(i)
+		// Look into embedded structs
+		if typeField.Anonymous && typeField.Type.Kind() == reflect.Struct {
+			if field := structFieldByAlias(structVal.Field(i), aliasTag, name); field.IsValid() {
+				return field
+			}
+		}
+		fieldName := strings.Split(typeField.Tag.Get(aliasTag), ",")[0]
+		if fieldName == "" {
+			fieldName = typeField.Name
+		}
+		if utils.EqualFold(fieldName, name) && structVal.Field(i).CanSet() {
+			return structVal.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// ReqHeaderParser binds the request header strings to a struct.
+// The fields are named by the `header:"X-Custom"` tag, or the `reqHeader` tag, and the headers are
+// matched case-insensitively. Repeated headers and comma separated values are collected into slices.
+//
+//	type Headers struct {
+//		RequestID string   `header:"X-Request-Id"`
+//		Tags      []string `header:"X-Tags"`
+//	}
+func (c *Ctx) ReqHeaderParser(out interface{}) error {
+	data := make(map[string][]string)
+	c.fasthttp.Request.Header.VisitAll(func(key, val []byte) {
+		k := utils.UnsafeString(key)
+		v := utils.UnsafeString(val)
+
+		if strings.Contains(v, ",") && equalFieldType(out, reflect.Slice, k, headerTag, reqHeaderTag) {
 			values := strings.Split(v, ",")
 			for i := 0; i < len(values); i++ {
-				data[k] = append(data[k], values[i])
+				data[k] = append(data[k], utils.Trim(values[i], ' '))
 			}
 		} else {
 			data[k] = append(data[k], v)
@@ -1103,21 +1761,46 @@ func (c *Ctx) ReqHeaderParser(out interface{}) error {
 
 	})
 
-	return c.parseToStruct(reqHeaderTag, out, data)
+	return c.parseToStruct(headerTag, out, data, reqHeaderTag)
 }
 
-func (c *Ctx) parseToStruct(aliasTag string, out interface{}, data map[string][]string) error {
+func (c *Ctx) parseToStruct(aliasTag string, out interface{}, data map[string][]string, fallbackTags ...string) error {
 	// Get decoder from pool
 	schemaDecoder := decoderPool.Get().(*schema.Decoder)
 	defer decoderPool.Put(schemaDecoder)
 
 	// Set alias tag
-	schemaDecoder.SetAliasTag(aliasTag)
+	schemaDecoder.SetAliasTag(aliasTag, fallbackTags...)
+
+	// Parse times with the layouts of the app, the decoders are shared by all apps
+	if len(c.app.config.TimeLayouts) > 0 {
+		prev := schemaDecoder.Converter(time.Time{})
+		schemaDecoder.RegisterConverter(time.Time{}, c.app.parseTime)
+		defer schemaDecoder.RegisterConverter(time.Time{}, prev)
+	}
 
 	return schemaDecoder.Decode(out, data)
 }
 
-func equalFieldType(out interface{}, kind reflect.Kind, key string) bool {
+// parseTime converts a value to time.Time with the TimeLayouts config,
+// digit-only values which match no layout are Unix timestamps in seconds
+func (app *App) parseTime(value string) reflect.Value {
+	for _, layout := range app.config.TimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return reflect.ValueOf(t)
+		}
+	}
+	if isDigits(value) {
+		if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return reflect.ValueOf(time.Unix(sec, 0).UTC())
+		}
+	}
+	return reflect.Value{}
+}
+
+// equalFieldType checks whether out has a field of the kind which is named key by the first present
+// of the given tags, the query tag by default, or by its field name
+func equalFieldType(out interface{}, kind reflect.Kind, key string, tags ...string) bool {
 	// Get type of interface
 	outTyp := reflect.TypeOf(out).Elem()
 	key = utils.ToLower(key)
@@ -1125,26 +1808,43 @@ func equalFieldType(out interface{}, kind reflect.Kind, key string) bool {
 	if outTyp.Kind() != reflect.Struct {
 		return false
 	}
-	// Copy interface to an value to be used
-	outVal := reflect.ValueOf(out).Elem()
+	if len(tags) == 0 {
+		tags = []string{queryTag}
+	}
+	return equalStructFieldType(outTyp, kind, key, tags)
+}
+
+// equalStructFieldType checks the fields of the struct type and its embedded structs
+func equalStructFieldType(outTyp reflect.Type, kind reflect.Kind, key string, tags []string) bool {
 	// Loop over each field
 	for i := 0; i < outTyp.NumField(); i++ {
-		// Get field value data
-		structField := outVal.Field(i)
+		// Get field key data
+		typeField := outTyp.Field(i)
+		// Look into embedded structs, their fields are bound like the fields of the outer struct
+		if typeField.Anonymous {
+			embeddedTyp := typeField.Type
+			if embeddedTyp.Kind() == reflect.Ptr {
+				embeddedTyp = embeddedTyp.Elem()
+			}
+			if embeddedTyp.Kind() == reflect.Struct && equalStructFieldType(embeddedTyp, kind, key, tags) {
+				return true
+			}
+		}
 		// Can this field be changed?
-		if !structField.CanSet() {
+		if typeField.PkgPath != "" {
 			continue
 		}
-		// Get field key data
-		typeField := outTyp.Field(i)
-		// Get type of field key
-		structFieldKind := structField.Kind()
 		// Does the field type equals input?
-		if structFieldKind != kind {
+		if typeField.Type.Kind() != kind {
 			continue
 		}
 		// Get tag from field if exist
-		inputFieldName := typeField.Tag.Get(queryTag)
+		var inputFieldName string
+		for _, tag := range tags {
+			if inputFieldName = typeField.Tag.Get(tag); inputFieldName != "" {
+				break
+			}
+		}
 		if inputFieldName == "" {
 			inputFieldName = typeField.Name
 		} else {
@@ -1241,6 +1941,11 @@ func (c *Ctx) Bind(vars Map) error {
 
 // getLocationFromRoute get URL location from route using parameters
 func (c *Ctx) getLocationFromRoute(route Route, params Map) (string, error) {
+	return c.app.getLocationFromRoute(route, params)
+}
+
+// getLocationFromRoute get URL location from route using parameters, missing parameters are left out
+func (app *App) getLocationFromRoute(route Route, params Map) (string, error) {
 	buf := bytebufferpool.Get()
 	for _, segment := range route.routeParser.segs {
 		if !segment.IsParam {
@@ -1252,10 +1957,13 @@ func (c *Ctx) getLocationFromRoute(route Route, params Map) (string, error) {
 		}
 
 		for key, val := range params {
-			isSame := key == segment.ParamName || (!c.app.config.CaseSensitive && utils.EqualFold(key, segment.ParamName))
-			isGreedy := (segment.IsGreedy && len(key) == 1 && isInCharset(key[0], greedyParameters))
-			if isSame || isGreedy {
-				_, err := buf.WriteString(utils.ToString(val))
+			if app.isRouteParam(segment, key) {
+				value := utils.ToString(val)
+				// decoded parameters are encoded again, so that the values of c.Params reproduce the request path
+				if app.config.UnescapePath {
+					value = escapePathSegments(value)
+				}
+				_, err := buf.WriteString(value)
 				if err != nil {
 					return "", err
 				}
@@ -1268,7 +1976,16 @@ func (c *Ctx) getLocationFromRoute(route Route, params Map) (string, error) {
 	return location, nil
 }
 
+// isRouteParam checks if the key of the given parameters belongs to the parameter segment of a route
+func (app *App) isRouteParam(segment *routeSegment, key string) bool {
+	isSame := key == segment.ParamName || (!app.config.CaseSensitive && utils.EqualFold(key, segment.ParamName))
+	isGreedy := segment.IsGreedy && len(key) == 1 && isInCharset(key[0], greedyParameters)
+	return isSame || isGreedy
+}
+
 // GetRouteURL generates URLs to named routes, with parameters. URLs are relative, for example: "/user/1831"
+// Values taken from c.Params reproduce the request path: they are written as they are by default
+// and percent-encoded again if the UnescapePath setting is enabled.
 func (c *Ctx) GetRouteURL(routeName string, params Map) (string, error) {
 	return c.getLocationFromRoute(c.App().GetRoute(routeName), params)
 }
@@ -1320,8 +2037,8 @@ func (c *Ctx) Render(name string, bind interface{}, layouts ...string) error {
 	buf := bytebufferpool.Get()
 	defer bytebufferpool.Put(buf)
 
-	// Pass-locals-to-views & bind
-	c.renderExtensions(bind)
+	// Pass-locals-to-views, view globals & bind
+	bind = c.renderExtensions(bind)
 
 	rendered := false
 	for prefix, app := range c.app.appList {
@@ -1369,7 +2086,13 @@ func (c *Ctx) Render(name string, bind interface{}, layouts ...string) error {
 	return err
 }
 
-func (c *Ctx) renderExtensions(bind interface{}) {
+func (c *Ctx) renderExtensions(bind interface{}) interface{} {
+	c.app.viewGlobalsMutex.RLock()
+	defer c.app.viewGlobalsMutex.RUnlock()
+	// the globals need a map to be passed
+	if bind == nil && len(c.app.viewGlobals) > 0 {
+		bind = make(Map, len(c.app.viewGlobals))
+	}
 	if bindMap, ok := bind.(Map); ok {
 		// Bind view map
 		if c.viewBindMap != nil {
@@ -1389,7 +2112,15 @@ func (c *Ctx) renderExtensions(bind interface{}) {
 				}
 			})
 		}
+
+		// Set the view globals which are not overridden
+		for key, val := range c.app.viewGlobals {
+			if _, ok := bindMap[key]; !ok {
+				bindMap[key] = val
+			}
+		}
 	}
+	return bind
 }
 
 // Route returns the matched Route struct.
@@ -1413,11 +2144,25 @@ func (c *Ctx) SaveFile(fileheader *multipart.FileHeader, path string) error {
 }
 
 // SaveFileToStorage saves any multipart file to an external storage system.
+// If the storage implements StorageWriter, the file is streamed instead of read into memory.
 func (c *Ctx) SaveFileToStorage(fileheader *multipart.FileHeader, path string, storage Storage) error {
 	file, err := fileheader.Open()
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+
+	if sw, ok := storage.(StorageWriter); ok {
+		w, err := sw.Writer(path, 0)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(w, file); err != nil {
+			_ = w.Close()
+			return err
+		}
+		return w.Close()
+	}
 
 	content, err := ioutil.ReadAll(file)
 	if err != nil {
@@ -1432,6 +2177,34 @@ func (c *Ctx) Secure() bool {
 	return c.fasthttp.IsTLS()
 }
 
+// IsWebSocketUpgrade returns true if the request is a WebSocket handshake,
+// i.e. a GET request with the "Connection: Upgrade" and "Upgrade: websocket" headers,
+// a Sec-WebSocket-Key and the supported Sec-WebSocket-Version 13 (RFC 6455).
+// Only the request headers are checked.
+func (c *Ctx) IsWebSocketUpgrade() bool {
+	if c.methodINT != methodInt(MethodGet) || !utils.EqualFold(c.Get(HeaderUpgrade), "websocket") ||
+		c.Get(HeaderSecWebSocketKey) == "" || c.Get(HeaderSecWebSocketVersion) != webSocketVersion {
+		return false
+	}
+	for _, token := range strings.Split(c.Get(HeaderConnection), ",") {
+		if utils.EqualFold(utils.Trim(token, ' '), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSecureWebSocket returns true if the request is a WebSocket handshake over a secure connection (wss).
+// The connection is secure if TLS is established, or if a trusted proxy forwards the
+// request with the https or wss protocol, see c.Protocol.
+func (c *Ctx) IsSecureWebSocket() bool {
+	if !c.IsWebSocketUpgrade() {
+		return false
+	}
+	protocol := c.Protocol()
+	return utils.EqualFold(protocol, "https") || utils.EqualFold(protocol, "wss")
+}
+
 // Send sets the HTTP response body without copying it.
 // From this point onward the body argument must not be changed.
 func (c *Ctx) Send(body []byte) error {
@@ -1447,8 +2220,10 @@ var (
 )
 
 // SendFile transfers the file from the given path.
-// The file is not compressed by default, enable this by passing a 'true' argument
+// The file is not compressed by default, enable this by passing a 'true' argument,
+// only the content types of Config.SendFile.CompressibleTypes are compressed with gzip or brotli.
 // Sets the Content-Type response HTTP header field based on the filenames extension.
+// If the path is a directory, the first existing file of Config.SendFile.IndexNames is served.
 func (c *Ctx) SendFile(file string, compress ...bool) error {
 	// Save the filename, we will need it in the error message if the file isn't found
 	filename := file
@@ -1461,6 +2236,7 @@ func (c *Ctx) SendFile(file string, compress ...bool) error {
 			GenerateIndexPages:   false,
 			AcceptByteRange:      true,
 			Compress:             true,
+			CompressBrotli:       true,
 			CompressedFileSuffix: c.app.config.CompressedFileSuffix,
 			CacheDuration:        10 * time.Second,
 			IndexNames:           []string{"index.html"},
@@ -1473,10 +2249,10 @@ func (c *Ctx) SendFile(file string, compress ...bool) error {
 
 	// Keep original path for mutable params
 	c.pathOriginal = utils.CopyString(c.pathOriginal)
-	// Disable compression
-	if len(compress) == 0 || !compress[0] {
-		// https://github.com/valyala/fasthttp/blob/7cc6f4c513f9e0d3686142e0a1a5aa2f76b3194a/fs.go#L55
-		c.fasthttp.Request.Header.Del(HeaderAcceptEncoding)
+	// relative paths are resolved against the root
+	root := c.app.config.SendFile.Root
+	if root != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(root, filepath.FromSlash(file))
 	}
 	// copy of https://github.com/valyala/fasthttp/blob/7cc6f4c513f9e0d3686142e0a1a5aa2f76b3194a/fs.go#L103-L121 with small adjustments
 	if len(file) == 0 || !filepath.IsAbs(file) {
@@ -1492,6 +2268,50 @@ func (c *Ctx) SendFile(file string, compress ...bool) error {
 			file += "/"
 		}
 	}
+	// serve the index file if the path is a directory
+	info, err := os.Stat(file)
+	if err == nil && info.IsDir() {
+		if file = findIndexFile(file, c.app.config.SendFile.IndexNames); file == "" {
+			return NewError(StatusNotFound, fmt.Sprintf("sendfile: no index file found in %s", filename))
+		}
+		info, err = os.Stat(file)
+	}
+	// reject files outside of the root, also if they are reached via symlinks
+	if root != "" && !isInRoot(root, file) {
+		return NewError(StatusForbidden, fmt.Sprintf("sendfile: file %s is outside of the root", filename))
+	}
+	// immutable files are never revalidated, so the conditional request headers are ignored
+	if c.app.config.SendFile.Immutable {
+		c.fasthttp.Request.Header.Del(HeaderIfModifiedSince)
+		c.skipETag = true
+	}
+	// Disable compression, already compressed types like images and small files are never compressed
+	compressible := len(compress) > 0 && compress[0] && err == nil && info.Size() >= c.app.config.SendFile.CompressMinSize &&
+		isCompressibleType(utils.GetMIME(filepath.Ext(file)), c.app.config.SendFile.CompressibleTypes)
+	// Answer conditional requests of unchanged files before the file is opened or compressed
+	if err == nil && c.fileNotModified(info, compressible) {
+		return nil
+	}
+	// Serve the requested byte ranges of the file
+	if rangeHeader := c.Get(HeaderRange); rangeHeader != "" && err == nil && (c.fasthttp.IsGet() || c.fasthttp.IsHead()) {
+		if ranges, ok := parseRange(rangeHeader, info.Size()); ok && c.ifRangeMatches(info) {
+			return c.sendFileRanges(file, info, ranges)
+		}
+		// a malformed Range header, or one for another version of the file, is ignored and the whole file is sent
+		c.fasthttp.Request.Header.Del(HeaderRange)
+	}
+	if !compressible {
+		// https://github.com/valyala/fasthttp/blob/7cc6f4c513f9e0d3686142e0a1a5aa2f76b3194a/fs.go#L55
+		c.fasthttp.Request.Header.Del(HeaderAcceptEncoding)
+	} else {
+		// Large files are compressed while streaming
+		if info.Size() > c.app.config.SendFile.CompressMaxSize && len(c.fasthttp.Request.Header.Peek(HeaderRange)) == 0 {
+			if c.fasthttp.Request.Header.HasAcceptEncoding("gzip") {
+				return c.sendFileGzipStream(file, info)
+			}
+			c.fasthttp.Request.Header.Del(HeaderAcceptEncoding)
+		}
+	}
 	// convert the path to forward slashes regardless the OS in order to set the URI properly
 	// the handler will convert back to OS path separator before opening the file
 	file = filepath.ToSlash(file)
@@ -1515,9 +2335,343 @@ func (c *Ctx) SendFile(file string, compress ...bool) error {
 	if status != StatusNotFound && fsStatus == StatusNotFound {
 		return NewError(StatusNotFound, fmt.Sprintf("sendfile: file %s not found", filename))
 	}
+	// only compressed responses vary by Accept-Encoding, identity responses stay cacheable for all clients
+	if compressible && len(c.fasthttp.Response.Header.Peek(HeaderContentEncoding)) > 0 {
+		c.Vary(HeaderAcceptEncoding)
+	}
+	if fsStatus == StatusOK && err == nil {
+		// range requests of the file are always supported, see sendFileRanges
+		c.setCanonical(HeaderAcceptRanges, "bytes")
+		c.setFileETag(info)
+	}
 	return nil
 }
 
+// fileNotModified answers a GET or HEAD request with 304 Not Modified if the If-None-Match header
+// matches the ETag of the file variant which would be sent, or otherwise if the file wasn't modified
+// since the If-Modified-Since header. Only the stat of the file is used, so nothing is read or compressed.
+func (c *Ctx) fileNotModified(info os.FileInfo, compressible bool) bool {
+	// files without modification time, like the files of embed.FS, are validated by the body ETag
+	if c.app.config.SendFile.Immutable || info.IsDir() || info.ModTime().IsZero() || !(c.fasthttp.IsGet() || c.fasthttp.IsHead()) {
+		return false
+	}
+	var etag string
+	if noneMatch := c.fasthttp.Request.Header.Peek(HeaderIfNoneMatch); len(noneMatch) > 0 {
+		if !c.app.config.ETag {
+			return false
+		}
+		// predict the encoding of the variant, ranges are sent uncompressed
+		encoding := ""
+		if compressible && len(c.fasthttp.Request.Header.Peek(HeaderRange)) == 0 {
+			if info.Size() > c.app.config.SendFile.CompressMaxSize {
+				if c.fasthttp.Request.Header.HasAcceptEncoding(StrGzip) {
+					encoding = StrGzip
+				}
+			} else if c.fasthttp.Request.Header.HasAcceptEncoding(StrBr) {
+				encoding = StrBr
+			} else if c.fasthttp.Request.Header.HasAcceptEncoding(StrGzip) {
+				encoding = StrGzip
+			}
+		}
+		etag = fileETag(info, encoding)
+		if c.app.isEtagStale(etag, noneMatch) {
+			// If-None-Match takes precedence, the file server must not answer with 304 by the date
+			c.fasthttp.Request.Header.Del(HeaderIfModifiedSince)
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(c.Get(HeaderIfModifiedSince))
+		if err != nil || info.ModTime().Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	c.fasthttp.Response.ResetBody()
+	c.Status(StatusNotModified)
+	c.setCanonical(HeaderLastModified, info.ModTime().UTC().Format(http.TimeFormat))
+	if etag != "" {
+		c.setCanonical(normalizedHeaderETag, etag)
+	}
+	return true
+}
+
+// setFileETag sets the ETag of the served file if ETags are enabled, the ETag is derived from
+// the file size and modification time. Compressed variants get a weak ETag with the encoding,
+// so caches never serve a compressed body to a client which expects another encoding.
+// Immutable files get a long-lived Cache-Control header instead of an ETag.
+func (c *Ctx) setFileETag(info os.FileInfo) {
+	if c.app.config.SendFile.Immutable {
+		c.setCanonical(HeaderCacheControl, immutableCacheControl)
+		return
+	}
+	if !c.app.config.ETag || info.ModTime().IsZero() || len(c.fasthttp.Response.Header.Peek(HeaderETag)) > 0 {
+		return
+	}
+	c.setCanonical(normalizedHeaderETag, fileETag(info, c.app.getString(c.fasthttp.Response.Header.Peek(HeaderContentEncoding))))
+}
+
+// setFileLastModified sets the Last-Modified header, unless the file has no modification time
+func (c *Ctx) setFileLastModified(info os.FileInfo) {
+	if !info.ModTime().IsZero() {
+		c.setCanonical(HeaderLastModified, info.ModTime().UTC().Format(http.TimeFormat))
+	}
+}
+
+// SendFileFS transfers the file with the given name from the file system, e.g. an embed.FS,
+// so that assets can be bundled into the binary. Like c.SendFile, the content type is set by the
+// extension, the first existing file of Config.SendFile.IndexNames is served for directories,
+// ETags are generated and Range requests are answered. The file isn't compressed.
+// Files without modification time, like the files of embed.FS, are buffered, so that the ETag
+// is generated from the content, other files are streamed.
+//
+//	//go:embed static
+//	var static embed.FS
+//
+//	app.Get("/logo.png", func(c *fiber.Ctx) error {
+//		return c.SendFileFS(static, "static/logo.png")
+//	})
+func (c *Ctx) SendFileFS(fsys fs.FS, name string) error {
+	filename := name
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return NewError(StatusForbidden, fmt.Sprintf("sendfile: file %s is outside of the root", filename))
+	}
+	info, err := fs.Stat(fsys, name)
+	if err == nil && info.IsDir() {
+		if name = findIndexFileFS(fsys, name, c.app.config.SendFile.IndexNames); name == "" {
+			return NewError(StatusNotFound, fmt.Sprintf("sendfile: no index file found in %s", filename))
+		}
+		info, err = fs.Stat(fsys, name)
+	}
+	if err != nil {
+		return NewError(StatusNotFound, fmt.Sprintf("sendfile: file %s not found", filename))
+	}
+	// immutable files are never revalidated, so the conditional request headers are ignored
+	if c.app.config.SendFile.Immutable {
+		c.fasthttp.Request.Header.Del(HeaderIfModifiedSince)
+		c.skipETag = true
+	}
+	if c.fileNotModified(info, false) {
+		return nil
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return NewError(StatusNotFound, fmt.Sprintf("sendfile: file %s not found", filename))
+	}
+	// Serve the requested byte ranges of the file, if it can be read at offsets
+	if rangeHeader := c.Get(HeaderRange); rangeHeader != "" && (c.fasthttp.IsGet() || c.fasthttp.IsHead()) {
+		if ra, ok := f.(readerAtCloser); ok && c.ifRangeMatches(info) {
+			if ranges, ok := parseRange(rangeHeader, info.Size()); ok {
+				if len(ranges) == 0 {
+					_ = f.Close()
+					c.setCanonical(HeaderAcceptRanges, "bytes")
+					c.setCanonical(HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size()))
+					return c.SendStatus(StatusRequestedRangeNotSatisfiable)
+				}
+				c.setCanonical(HeaderAcceptRanges, "bytes")
+				return c.sendRanges(name, info, ranges, ra)
+			}
+		}
+	}
+	if _, ok := f.(io.ReaderAt); ok {
+		c.setCanonical(HeaderAcceptRanges, "bytes")
+	}
+	c.setFileContentType(name)
+	c.setFileLastModified(info)
+	c.setFileETag(info)
+	if c.fasthttp.IsHead() {
+		c.fasthttp.Response.Header.SetContentLength(int(info.Size()))
+		return f.Close()
+	}
+	if info.ModTime().IsZero() {
+		defer f.Close()
+		body, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		c.fasthttp.Response.SetBodyRaw(body)
+		return nil
+	}
+	// the file is closed by fasthttp once the body is sent
+	return c.SendStream(f, int(info.Size()))
+}
+
+// sendFileGzipStream sends the file gzipped on the fly without buffering the compressed file
+func (c *Ctx) sendFileGzipStream(file string, info os.FileInfo) error {
+	f, err := os.Open(filepath.Clean(file))
+	if err != nil {
+		return NewError(StatusNotFound, fmt.Sprintf("sendfile: file %s not found", file))
+	}
+	c.setFileContentType(file)
+	c.setCanonical(HeaderContentEncoding, "gzip")
+	c.Vary(HeaderAcceptEncoding)
+	c.setFileLastModified(info)
+	// ranges are served from the uncompressed file
+	c.setCanonical(HeaderAcceptRanges, "bytes")
+	c.setFileETag(info)
+	if c.fasthttp.IsHead() {
+		return f.Close()
+	}
+	return c.SendStreamWriter(func(ctx context.Context, w *bufio.Writer) {
+		defer f.Close()
+		gz := gzip.NewWriter(w)
+		if _, err := io.Copy(gz, f); err != nil {
+			return
+		}
+		_ = gz.Close()
+	})
+}
+
+// setFileContentType sets the content type of the file, the same as served by fasthttp.FS
+func (c *Ctx) setFileContentType(file string) string {
+	contentType := mime.TypeByExtension(filepath.Ext(file))
+	if contentType == "" {
+		if contentType = utils.GetMIME(filepath.Ext(file)); contentType == "" {
+			contentType = MIMEOctetStream
+		}
+	}
+	c.setCanonical(HeaderContentType, contentType)
+	return contentType
+}
+
+// ifRangeMatches reports whether the ranges of the file may be sent, that is if the request
+// has no If-Range header or it matches the strong ETag or the Last-Modified date of the file.
+// Otherwise the file was changed since the client got its other parts and the whole file is sent.
+// https://www.rfc-editor.org/rfc/rfc7233#section-3.2
+func (c *Ctx) ifRangeMatches(info os.FileInfo) bool {
+	ifRange := c.Get(HeaderIfRange)
+	if ifRange == "" {
+		return true
+	}
+	if info.ModTime().IsZero() {
+		return false
+	}
+	if strings.HasPrefix(ifRange, "\"") || strings.HasPrefix(ifRange, "W/") {
+		// weak ETags never match
+		return c.app.config.ETag && ifRange == fileETag(info, "")
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && info.ModTime().Unix() == t.Unix()
+}
+
+// sendFileRanges answers a Range request with the given byte ranges of the file,
+// multiple ranges are sent as multipart/byteranges body. The ranges are streamed from the file.
+// https://www.rfc-editor.org/rfc/rfc7233
+func (c *Ctx) sendFileRanges(file string, info os.FileInfo, ranges []byteRange) error {
+	size := info.Size()
+	c.setCanonical(HeaderAcceptRanges, "bytes")
+	if len(ranges) == 0 {
+		c.setCanonical(HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+		return c.SendStatus(StatusRequestedRangeNotSatisfiable)
+	}
+	f, err := os.Open(filepath.Clean(file))
+	if err != nil {
+		return NewError(StatusNotFound, fmt.Sprintf("sendfile: file %s not found", file))
+	}
+	return c.sendRanges(file, info, ranges, f)
+}
+
+// sendRanges sends the satisfiable byte ranges of the opened file, which is closed once the response is sent
+func (c *Ctx) sendRanges(file string, info os.FileInfo, ranges []byteRange, f readerAtCloser) error {
+	size := info.Size()
+	contentType := c.setFileContentType(file)
+	c.setFileLastModified(info)
+	c.setFileETag(info)
+	c.Status(StatusPartialContent)
+
+	var body io.Reader
+	var length int64
+	if len(ranges) == 1 {
+		r := ranges[0]
+		c.setCanonical(HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, size))
+		body, length = io.NewSectionReader(f, r.start, r.length()), r.length()
+	} else {
+		boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+		parts := make([]io.Reader, 0, 2*len(ranges)+1)
+		for i, r := range ranges {
+			partHeader := fmt.Sprintf("\r\n--%s\r\n%s: %s\r\n%s: bytes %d-%d/%d\r\n\r\n",
+				boundary, HeaderContentType, contentType, HeaderContentRange, r.start, r.end, size)
+			if i == 0 {
+				partHeader = partHeader[2:]
+			}
+			parts = append(parts, strings.NewReader(partHeader), io.NewSectionReader(f, r.start, r.length()))
+			length += int64(len(partHeader)) + r.length()
+		}
+		closing := "\r\n--" + boundary + "--\r\n"
+		parts = append(parts, strings.NewReader(closing))
+		length += int64(len(closing))
+		c.setCanonical(HeaderContentType, "multipart/byteranges; boundary="+boundary)
+		body = io.MultiReader(parts...)
+	}
+	if c.fasthttp.IsHead() {
+		c.fasthttp.Response.Header.SetContentLength(int(length))
+		return f.Close()
+	}
+	// the file is closed by fasthttp once the body is sent
+	return c.SendStream(&fileSection{Reader: body, file: f}, int(length))
+}
+
+// readerAtCloser is a file whose ranges can be read, like *os.File and the files of embed.FS
+type readerAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// fileSection reads parts of a file and closes the file when the response is sent
+type fileSection struct {
+	io.Reader
+	file io.Closer
+}
+
+func (fs *fileSection) Close() error {
+	return fs.file.Close()
+}
+
+// ErrInformationalReusedConn is returned by c.SendInformational for requests on a reused connection.
+var ErrInformationalReusedConn = errors.New("informational: not sent on a reused connection")
+
+// SendInformational sends an informational 1xx response with the given headers ahead of the
+// final response and the handler continues, e.g. 102 Processing during long operations
+// or 103 Early Hints. 101 Switching Protocols isn't supported.
+// Like required by RFC 9110, nothing is sent to HTTP/1.0 clients.
+// fasthttp buffers the responses of pipelined requests until no further request is waiting,
+// so the previous response on a keep-alive connection may not have been sent yet, and the
+// handler has no access to that buffer to flush it. As the informational response is written
+// to the connection directly, it's only sent for the first request of a connection, otherwise
+// ErrInformationalReusedConn is returned and the handler can continue with the final response.
+// Browsers and proxies reuse their connections for most requests, so behind them the
+// informational response is usually not sent at all. Don't rely on it, it's only a hint.
+//
+//	c.SendInformational(fiber.StatusEarlyHints, map[string]string{"Link": "</style.css>; rel=preload; as=style"})
+func (c *Ctx) SendInformational(status int, headers map[string]string) error {
+	if status < StatusContinue || status > 199 || status == StatusSwitchingProtocols {
+		return fmt.Errorf("informational: invalid status code %d", status)
+	}
+	if !c.fasthttp.Request.Header.IsHTTP11() {
+		return nil
+	}
+	if c.fasthttp.ConnRequestNum() > 1 {
+		return ErrInformationalReusedConn
+	}
+
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+	_, _ = bb.WriteString("HTTP/1.1 " + strconv.Itoa(status) + " " + utils.StatusMessage(status) + "\r\n")
+	for key, val := range headers {
+		if strings.ContainsAny(key, "\r\n:") || strings.ContainsAny(val, "\r\n") {
+			return fmt.Errorf("informational: invalid header %q", key)
+		}
+		_, _ = bb.WriteString(key + ": " + val + "\r\n")
+	}
+	_, _ = bb.WriteString("\r\n")
+
+	// the final response is written to the connection after the handler
+	_, err := c.fasthttp.Conn().Write(bb.B)
+	return err
+}
+
 // SendStatus sets the HTTP status code and if the response body is empty,
 // it sets the correct status message in the body.
 func (c *Ctx) SendStatus(status int) error {
@@ -1531,6 +2685,30 @@ func (c *Ctx) SendStatus(status int) error {
 	return nil
 }
 
+// SendError discards the response body written so far, including body streams,
+// and sends the status code with the message, or the status message by default, as plain text.
+// The headers describing the discarded body, Content-Encoding, Content-Disposition, ETag and Vary,
+// are removed as well. Unlike c.Status, which keeps the body, it's meant to turn a partly written
+// success response into an error response.
+//
+//	return c.SendError(fiber.StatusInternalServerError, "export failed")
+func (c *Ctx) SendError(status int, msg ...string) error {
+	// a stream writer keeps running until its writes fail, so c.streaming stays set
+	// and the Ctx isn't put back into the pool while it's still used
+	c.fasthttp.Response.ResetBody()
+	c.written = nil
+	c.Status(status)
+	c.fasthttp.Response.Header.Del(HeaderContentEncoding)
+	c.fasthttp.Response.Header.Del(HeaderContentDisposition)
+	c.fasthttp.Response.Header.Del(HeaderETag)
+	c.fasthttp.Response.Header.Del(HeaderVary)
+	c.fasthttp.Response.Header.SetContentType(MIMETextPlainCharsetUTF8)
+	if len(msg) > 0 {
+		return c.SendString(msg[0])
+	}
+	return c.SendString(utils.StatusMessage(status))
+}
+
 // SendString sets the HTTP response body for string types.
 // This means no type assertion, recommended for faster performance
 func (c *Ctx) SendString(body string) error {
@@ -1540,17 +2718,279 @@ func (c *Ctx) SendString(body string) error {
 }
 
 // SendStream sets response body stream and optional body size.
+// Without a size, the size of a regular file like *os.File is taken from its stat,
+// e.g. to send the Content-Length of a download along with c.Attachment.
 func (c *Ctx) SendStream(stream io.Reader, size ...int) error {
+	// files are passed on as they are, so that fasthttp can send them with sendfile
+	body := stream
+	c.written = nil
+	if _, ok := stream.(*os.File); !ok {
+		c.written = &bodyCounter{}
+		body = &countingReader{r: stream, n: c.written}
+	}
 	if len(size) > 0 && size[0] >= 0 {
-		c.fasthttp.Response.SetBodyStream(stream, size[0])
+		c.fasthttp.Response.SetBodyStream(body, size[0])
+	} else if fileSize, ok := statSize(stream); ok {
+		c.fasthttp.Response.SetBodyStream(body, fileSize)
 	} else {
-		c.fasthttp.Response.SetBodyStream(stream, -1)
+		c.fasthttp.Response.SetBodyStream(body, -1)
 		c.setCanonical(HeaderContentLength, strconv.Itoa(len(c.fasthttp.Response.Body())))
 	}
 
 	return nil
 }
 
+// SendStreamWriter sets the response body to be written by streamWriter in its own goroutine,
+// the written data is sent to the client once the handler returned.
+// The given context is derived from the user context and is cancelled as soon as a write
+// to the client fails, e.g. because the client disconnected, so that long-running streams can stop.
+// Use w.Flush() or c.Flush() to send the buffered data to the client immediately.
+// Apart from c.Flush(), the Ctx must not be used in streamWriter.
+func (c *Ctx) SendStreamWriter(streamWriter func(ctx context.Context, w *bufio.Writer)) error {
+	ctx, cancel := context.WithCancel(c.UserContext())
+	c.streaming = true
+	written := &bodyCounter{}
+	c.written = written
+	c.fasthttp.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer written.end()
+		c.stream = bufio.NewWriter(&cancelWriter{w: w, cancel: cancel, n: written})
+		streamWriter(ctx, c.stream)
+		_ = c.stream.Flush()
+		c.stream = nil
+	})
+
+	return nil
+}
+
+// ErrNotStreaming is returned by c.Flush when it's used outside of a stream writer.
+var ErrNotStreaming = errors.New("flush: response body is not streamed")
+
+// Flush sends the buffered response body to the client immediately,
+// e.g. to deliver server-sent events one by one.
+// It can only be used in the stream writer of c.SendStreamWriter, otherwise ErrNotStreaming is returned.
+func (c *Ctx) Flush() error {
+	if c.stream == nil {
+		return ErrNotStreaming
+	}
+	return c.stream.Flush()
+}
+
+// cancelWriter passes writes through to the connection and
+// cancels the stream context when writing to the client fails
+type cancelWriter struct {
+	w      *bufio.Writer
+	cancel context.CancelFunc
+	n      *bodyCounter
+}
+
+func (cw *cancelWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n.add(n)
+	if err == nil {
+		err = cw.w.Flush()
+	}
+	if err != nil {
+		cw.cancel()
+	}
+	return n, err
+}
+
+// countingReader counts the bytes which are read from the response body stream,
+// the stream is closed after sending if it implements io.Closer
+type countingReader struct {
+	r io.Reader
+	n *bodyCounter
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.add(n)
+	return n, err
+}
+
+// Close is called by fasthttp once the body has been sent or the response is reset
+func (cr *countingReader) Close() error {
+	defer cr.n.end()
+	if closer, ok := cr.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// bodyCounter counts the bytes of a streamed response body and
+// calls the callbacks of c.OnBodySent once the stream ended
+type bodyCounter struct {
+	n     int64
+	mutex sync.Mutex
+	ended bool
+	fns   []func(bytesWritten int)
+}
+
+func (bc *bodyCounter) add(n int) {
+	atomic.AddInt64(&bc.n, int64(n))
+}
+
+func (bc *bodyCounter) count() int {
+	return int(atomic.LoadInt64(&bc.n))
+}
+
+// end marks the stream as ended and calls the callbacks with the final count
+func (bc *bodyCounter) end() {
+	bc.mutex.Lock()
+	fns := bc.fns
+	bc.fns, bc.ended = nil, true
+	bc.mutex.Unlock()
+	for _, fn := range fns {
+		fn(bc.count())
+	}
+}
+
+// onEnd calls the callbacks once the stream ended, right away if it already has
+func (bc *bodyCounter) onEnd(fns []func(bytesWritten int)) {
+	bc.mutex.Lock()
+	if !bc.ended {
+		bc.fns = append(bc.fns, fns...)
+		bc.mutex.Unlock()
+		return
+	}
+	bc.mutex.Unlock()
+	for _, fn := range fns {
+		fn(bc.count())
+	}
+}
+
+// BytesWritten returns the size of the response body, e.g. for logging middleware.
+// For bodies of c.SendStream and c.SendStreamWriter it's the running count of the bytes
+// passed to the connection so far, as these are sent after the handler returned,
+// so it's usually 0 in the handler chain, use c.OnBodySent to get the final count.
+// Files, e.g. of c.SendFile or an *os.File passed to c.SendStream, aren't counted
+// so that they can be sent with sendfile, their Content-Length is returned instead.
+// 0 is returned for other body streams of unknown length. Like the other methods of the Ctx,
+// it must not be used once the Ctx has been released, except in the stream writer.
+func (c *Ctx) BytesWritten() int {
+	if c.written != nil {
+		return c.written.count()
+	}
+	if c.fasthttp.Response.IsBodyStream() {
+		if length := c.fasthttp.Response.Header.ContentLength(); length > 0 {
+			return length
+		}
+		return 0
+	}
+	return len(c.fasthttp.Response.Body())
+}
+
+// OnBodySent registers fn to be called with the final size of the response body as reported by
+// c.BytesWritten, e.g. by logging middleware after c.Next(). For bodies of c.SendStream and
+// c.SendStreamWriter, fn is called from the goroutine sending the response once the stream ended,
+// at that time the Ctx has been released and must not be used in fn. Otherwise fn is called
+// when the Ctx is released after the handler chain.
+//
+//	c.OnBodySent(func(n int) { log.Printf("%s %s %d bytes", method, path, n) })
+func (c *Ctx) OnBodySent(fn func(bytesWritten int)) {
+	c.onBodySent = append(c.onBodySent, fn)
+}
+
+// bodySent hands the callbacks of c.OnBodySent to the body stream or calls them right away
+func (c *Ctx) bodySent() {
+	fns := c.onBodySent
+	c.onBodySent = nil
+	if c.written != nil {
+		c.written.onEnd(fns)
+		return
+	}
+	n := c.BytesWritten()
+	for _, fn := range fns {
+		fn(n)
+	}
+}
+
+// SSEvent is a server-sent event which is sent to the client by c.SendSSE.
+type SSEvent struct {
+	// Event is the name of the event, omitted if empty, line breaks are removed
+	Event string
+	// Data is the payload of the event, multiple lines are sent as multiple data fields,
+	// lines can end with "\r\n", "\r" or "\n"
+	Data string
+	// ID sets the last event ID of the client, omitted if empty, line breaks are removed
+	ID string
+	// Retry sets the reconnection time of the client, omitted if zero
+	Retry time.Duration
+}
+
+// DefaultSSEHeartbeat is the interval of the heartbeat comments of c.SendSSE.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// SendSSE streams the events received from ch to the client as server-sent events
+// and sets the Content-Type and Cache-Control headers accordingly. Every event is flushed immediately.
+// The stream ends when ch is closed. The user context is replaced by a context which is cancelled
+// once the stream ended, e.g. because the client disconnected, so event producers should
+// stop when c.UserContext() obtained after calling SendSSE is done.
+// A disconnect is only noticed when writing to the client fails, so while no event is sent,
+// a comment is sent every heartbeat interval, DefaultSSEHeartbeat by default,
+// a negative interval disables the heartbeat.
+func (c *Ctx) SendSSE(ch <-chan SSEvent, heartbeat ...time.Duration) error {
+	c.setCanonical(HeaderContentType, "text/event-stream")
+	c.setCanonical(HeaderCacheControl, "no-cache")
+
+	interval := DefaultSSEHeartbeat
+	if len(heartbeat) > 0 && heartbeat[0] != 0 {
+		interval = heartbeat[0]
+	}
+	ctx, cancel := context.WithCancel(c.UserContext())
+	c.SetUserContext(ctx)
+	return c.SendStreamWriter(func(streamCtx context.Context, w *bufio.Writer) {
+		defer cancel()
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-streamCtx.Done():
+				return
+			case <-tick:
+				_, _ = w.WriteString(":\n\n")
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				writeSSEvent(w, event)
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+var (
+	// sseFieldReplacer removes line breaks, which would end the field
+	sseFieldReplacer = strings.NewReplacer("\r", "", "\n", "")
+	// sseLineReplacer normalizes the line breaks of the data
+	sseLineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+)
+
+// writeSSEvent writes the event in the text/event-stream format
+func writeSSEvent(w *bufio.Writer, event SSEvent) {
+	if event.ID != "" {
+		_, _ = w.WriteString("id: " + sseFieldReplacer.Replace(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		_, _ = w.WriteString("event: " + sseFieldReplacer.Replace(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		_, _ = w.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(sseLineReplacer.Replace(event.Data), "\n") {
+		_, _ = w.WriteString("data: " + line + "\n")
+	}
+	_ = w.WriteByte('\n')
+}
+
 // Set sets the response's HTTP header field to the specified key, value.
 func (c *Ctx) Set(key string, val string) {
 	c.fasthttp.Response.Header.Set(key, val)
@@ -1589,6 +3029,12 @@ func (c *Ctx) Status(status int) *Ctx {
 	return c
 }
 
+// StatusCode returns the current status code of the response,
+// e.g. to read the final status in a middleware after c.Next().
+func (c *Ctx) StatusCode() int {
+	return c.fasthttp.Response.StatusCode()
+}
+
 // String returns unique string representation of the ctx.
 //
 // The returned value may be useful for logging.
@@ -1614,9 +3060,27 @@ func (c *Ctx) Type(extension string, charset ...string) *Ctx {
 }
 
 // Vary adds the given header field to the Vary response header.
-// This will append the header, if not already listed, otherwise leaves it listed in the current location.
+// This will append the header, if not already listed (case-insensitive), otherwise leaves it listed in the current location.
+// The field "*" replaces all other fields, as the response varies on everything.
 func (c *Ctx) Vary(fields ...string) {
-	c.Append(HeaderVary, fields...)
+	h := c.app.getString(c.fasthttp.Response.Header.Peek(HeaderVary))
+	vary := h
+	for _, field := range fields {
+		field = utils.Trim(field, ' ')
+		if field == "" || vary == "*" {
+			continue
+		}
+		if field == "*" {
+			vary = "*"
+		} else if vary == "" {
+			vary = field
+		} else if !hasHeaderToken(vary, field) {
+			vary += ", " + field
+		}
+	}
+	if vary != h {
+		c.setCanonical(HeaderVary, vary)
+	}
 }
 
 // Write appends p into response body.
@@ -1678,13 +3142,18 @@ func (c *Ctx) IsProxyTrusted() bool {
 		return true
 	}
 
-	_, trusted := c.app.config.trustedProxiesMap[c.fasthttp.RemoteIP().String()]
+	return c.app.isTrustedProxy(c.fasthttp.RemoteIP())
+}
+
+// isTrustedProxy checks if the ip is in the TrustedProxies list
+func (app *App) isTrustedProxy(ip net.IP) bool {
+	_, trusted := app.config.trustedProxiesMap[ip.String()]
 	if trusted {
 		return trusted
 	}
 
-	for _, ipNet := range c.app.config.trustedProxyRanges {
-		if ipNet.Contains(c.fasthttp.RemoteIP()) {
+	for _, ipNet := range app.config.trustedProxyRanges {
+		if ipNet.Contains(ip) {
 			return true
 		}
 	}
@@ -13,28 +13,38 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"mime/multipart"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"text/template"
 	"time"
 
 	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
 	"github.com/gofiber/fiber/v2/internal/storage/memory"
 	"github.com/gofiber/fiber/v2/internal/template/html"
+	"github.com/gofiber/fiber/v2/internal/tlstest"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
 )
 
 // go test -run Test_Ctx_Accepts
@@ -62,6 +72,61 @@ func Test_Ctx_Accepts(t *testing.T) {
 
 	c.Request().Header.Set(HeaderAccept, "*/*")
 	utils.AssertEqual(t, "html", c.Accepts("html"))
+
+	c.Request().Header.Set(HeaderAccept, "text/html;q=0.2, application/json;q=0.9")
+	utils.AssertEqual(t, "json", c.Accepts("html", "json"))
+	utils.AssertEqual(t, "application/json", c.Accepts("text/html", "application/json"))
+
+	c.Request().Header.Set(HeaderAccept, "text/html;q=0, */*;q=0.1")
+	utils.AssertEqual(t, "", c.Accepts("html"))
+	utils.AssertEqual(t, "xml", c.Accepts("html", "xml"))
+}
+
+// go test -run Test_Ctx_AcceptsDefault
+func Test_Ctx_AcceptsDefault(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	// no Accept header
+	utils.AssertEqual(t, "html", c.Accepts("html", "json"))
+	utils.AssertEqual(t, "json", c.AcceptsDefault("json", "html", "json"))
+	utils.AssertEqual(t, "application/json", c.AcceptsDefault("application/json", "text/html", "application/json"))
+
+	// the Accept header is negotiated as usual
+	c.Request().Header.Set(HeaderAccept, "text/html,application/xhtml+xml,application/xml;q=0.9")
+	utils.AssertEqual(t, "html", c.AcceptsDefault("json", "html", "json"))
+	utils.AssertEqual(t, "", c.AcceptsDefault("json", "json"))
+}
+
+// go test -run Test_Ctx_Accepts_StrictNegotiation
+func Test_Ctx_Accepts_StrictNegotiation(t *testing.T) {
+	t.Parallel()
+
+	// lenient: the first offer is returned without header
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	utils.AssertEqual(t, "html", c.Accepts("html", "json"))
+	utils.AssertEqual(t, "utf-8", c.AcceptsCharsets("utf-8", "iso-8859-1"))
+	utils.AssertEqual(t, "gzip", c.AcceptsEncodings("gzip", "br"))
+	utils.AssertEqual(t, "en", c.AcceptsLanguages("en", "de"))
+	app.ReleaseCtx(c)
+
+	// strict: the client has to specify what it accepts
+	app = New(Config{StrictNegotiation: true})
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, "", c.Accepts("html", "json"))
+	utils.AssertEqual(t, "", c.AcceptsCharsets("utf-8", "iso-8859-1"))
+	utils.AssertEqual(t, "", c.AcceptsEncodings("gzip", "br"))
+	utils.AssertEqual(t, "", c.AcceptsLanguages("en", "de"))
+	utils.AssertEqual(t, "json", c.AcceptsDefault("json", "html", "json"))
+
+	c.Request().Header.Set(HeaderAccept, "application/json")
+	c.Request().Header.Set(HeaderAcceptLanguage, "de")
+	utils.AssertEqual(t, "json", c.Accepts("html", "json"))
+	utils.AssertEqual(t, "de", c.AcceptsLanguages("en", "de"))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_Accepts -benchmem -count=4
@@ -138,6 +203,38 @@ func Test_Ctx_AcceptsEncodings(t *testing.T) {
 	utils.AssertEqual(t, "abc", c.AcceptsEncodings("abc"))
 }
 
+// go test -run Test_Ctx_AcceptsEncodings_Cache
+func Test_Ctx_AcceptsEncodings_Cache(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	testCases := []struct {
+		header   string
+		expected string
+	}{
+		{"gzip, deflate, br", "gzip"},
+		{"br, gzip, deflate", "br"},
+		{"deflate, gzip", "deflate"},
+		{"deflate;q=0.5, gzip;q=0.8", "gzip"},
+		{"br;q=0, gzip;q=0.1", "gzip"},
+		{"*", "br"},
+		{"*;q=0.2, deflate", "deflate"},
+		{"identity", ""},
+		{"br;q=0, gzip;q=0, deflate;q=0", ""},
+	}
+	// the second round is answered from the cache
+	for i := 0; i < 2; i++ {
+		for _, tc := range testCases {
+			c.Request().Header.Set(HeaderAcceptEncoding, tc.header)
+			utils.AssertEqual(t, tc.expected, c.AcceptsEncodings(StrBr, StrGzip, StrDeflate), tc.header)
+		}
+	}
+	c.Request().Header.Del(HeaderAcceptEncoding)
+	utils.AssertEqual(t, StrBr, c.AcceptsEncodings(StrBr, StrGzip, StrDeflate))
+}
+
 // go test -v -run=^$ -bench=Benchmark_Ctx_AcceptsEncodings -benchmem -count=4
 func Benchmark_Ctx_AcceptsEncodings(b *testing.B) {
 	app := New()
@@ -163,6 +260,39 @@ func Test_Ctx_AcceptsLanguages(t *testing.T) {
 	utils.AssertEqual(t, "fr", c.AcceptsLanguages("fr"))
 }
 
+// go test -run Test_Ctx_AcceptsLanguages_Quality
+func Test_Ctx_AcceptsLanguages_Quality(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	testCases := []struct {
+		header, expected string
+		offers           []string
+	}{
+		{"fr-CH, fr;q=0.9, en;q=0.8", "fr", []string{"en", "fr"}},
+		{"de;q=0.5, en;q=0.8", "en", []string{"de", "en"}},
+		// prefix matching in both directions, case-insensitive
+		{"en", "en-US", []string{"de", "en-US"}},
+		{"EN-us", "en", []string{"de", "en"}},
+		{"en-US", "en-us", []string{"en-us"}},
+		{"eng", "", []string{"en"}},
+		// the exact range is preferred over a prefix range
+		{"en;q=0.1, en-GB", "en-GB", []string{"en-US", "en-GB"}},
+		{"en-GB;q=0.1, en", "en-US", []string{"en-GB", "en-US"}},
+		// wildcard
+		{"*", "de", []string{"de", "en"}},
+		{"fr, *;q=0.1", "fr", []string{"de", "fr"}},
+		// no match
+		{"fr, de;q=0", "", []string{"de", "en"}},
+	}
+	for _, tc := range testCases {
+		c.Request().Header.Set(HeaderAcceptLanguage, tc.header)
+		utils.AssertEqual(t, tc.expected, c.AcceptsLanguages(tc.offers...), tc.header)
+	}
+}
+
 // go test -v -run=^$ -bench=Benchmark_Ctx_AcceptsLanguages -benchmem -count=4
 func Benchmark_Ctx_AcceptsLanguages(b *testing.B) {
 	app := New()
@@ -224,6 +354,21 @@ func Test_Ctx_Append(t *testing.T) {
 	utils.AssertEqual(t, "", string(c.Response().Header.Peek("x-custom-header")))
 }
 
+// go test -run Test_Ctx_Append_SetCookie
+func Test_Ctx_Append_SetCookie(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		c.Append(HeaderSetCookie, "a=1; path=/")
+		c.Append(HeaderSetCookie, "b=2; path=/", "c=3; path=/")
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, []string{"a=1; path=/", "b=2; path=/", "c=3; path=/"}, resp.Header.Values(HeaderSetCookie))
+}
+
 // go test -v -run=^$ -bench=Benchmark_Ctx_Append -benchmem -count=4
 func Benchmark_Ctx_Append(b *testing.B) {
 	app := New()
@@ -255,6 +400,14 @@ func Test_Ctx_Attachment(t *testing.T) {
 	// check quoting
 	c.Attachment("another document.pdf\"\r\nBla: \"fasel")
 	utils.AssertEqual(t, `attachment; filename="another+document.pdf%22%0D%0ABla%3A+%22fasel"`, string(c.Response().Header.Peek(HeaderContentDisposition)))
+	// non-ASCII filename
+	c.Attachment("./files/résumé.pdf")
+	utils.AssertEqual(t, `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`, string(c.Response().Header.Peek(HeaderContentDisposition)))
+	c.Attachment("报告 2022.txt")
+	utils.AssertEqual(t, `attachment; filename="__+2022.txt"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%202022.txt`, string(c.Response().Header.Peek(HeaderContentDisposition)))
+	// empty filename
+	c.Attachment("")
+	utils.AssertEqual(t, `attachment`, string(c.Response().Header.Peek(HeaderContentDisposition)))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_Attachment -benchmem -count=4
@@ -428,6 +581,420 @@ func Test_Ctx_BodyParser(t *testing.T) {
 	utils.AssertEqual(t, "doe", cq.Data[1].Name)
 }
 
+// go test -run Test_Ctx_BodyParser_TimeLayouts
+func Test_Ctx_BodyParser_TimeLayouts(t *testing.T) {
+	t.Parallel()
+	type Event struct {
+		Start   time.Time  `form:"start" query:"start"`
+		Created time.Time  `form:"created" query:"created"`
+		Day     *time.Time `form:"day" query:"day"`
+	}
+
+	app := New(Config{TimeLayouts: []string{time.RFC3339, "2006-01-02"}})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.SetContentType(MIMEApplicationForm)
+	c.Request().SetBody([]byte("start=2022-10-05T14:30:00Z&created=1665000000&day=2022-10-06"))
+
+	e := new(Event)
+	utils.AssertEqual(t, nil, c.BodyParser(e))
+	utils.AssertEqual(t, time.Date(2022, 10, 5, 14, 30, 0, 0, time.UTC), e.Start)
+	utils.AssertEqual(t, time.Unix(1665000000, 0).UTC(), e.Created)
+	utils.AssertEqual(t, time.Date(2022, 10, 6, 0, 0, 0, 0, time.UTC), *e.Day)
+
+	c.Request().URI().SetQueryString("day=06.10.2022")
+	utils.AssertEqual(t, "schema: error converting value for \"day\"", c.QueryParser(new(Event)).Error())
+
+	// the layouts aren't used by other apps
+	app2 := New()
+	c2 := app2.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app2.ReleaseCtx(c2)
+	c2.Request().URI().SetQueryString("start=2022-10-05T14:30:00Z")
+	e = new(Event)
+	utils.AssertEqual(t, nil, c2.QueryParser(e))
+	utils.AssertEqual(t, time.Date(2022, 10, 5, 14, 30, 0, 0, time.UTC), e.Start)
+	c2.Request().URI().SetQueryString("day=2022-10-06")
+	utils.AssertEqual(t, true, c2.QueryParser(new(Event)) != nil)
+}
+
+// go test -run Test_Ctx_BodyParserWithInfo
+func Test_Ctx_BodyParserWithInfo(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type Demo struct {
+		Name string `json:"name" xml:"name" form:"name"`
+	}
+
+	testBodyType := func(contentType, body string, expected BodyType) {
+		c.Request().Header.SetContentType(contentType)
+		c.Request().SetBody([]byte(body))
+		c.Request().Header.SetContentLength(len(body))
+		d := new(Demo)
+		bodyType, err := c.BodyParserWithInfo(d)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected, bodyType)
+		utils.AssertEqual(t, "john", d.Name)
+	}
+
+	testBodyType(MIMEApplicationJSON, `{"name":"john"}`, BodyTypeJSON)
+	testBodyType("application/vnd.api+json", `{"name":"john"}`, BodyTypeJSON)
+	testBodyType(MIMEApplicationXML, `<Demo><name>john</name></Demo>`, BodyTypeXML)
+	testBodyType(MIMETextXML, `<Demo><name>john</name></Demo>`, BodyTypeXML)
+	testBodyType(MIMEApplicationForm, "name=john", BodyTypeForm)
+	testBodyType(MIMEMultipartForm+`;boundary="b"`, "--b\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\njohn\r\n--b--", BodyTypeMultipart)
+
+	// the detected type is returned with the parser error
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().SetBody([]byte(`{"name":`))
+	bodyType, err := c.BodyParserWithInfo(new(Demo))
+	utils.AssertEqual(t, BodyTypeJSON, bodyType)
+	utils.AssertEqual(t, true, err != nil)
+
+	c.Request().Header.SetContentType("invalid-content-type")
+	bodyType, err = c.BodyParserWithInfo(new(Demo))
+	utils.AssertEqual(t, BodyTypeUnknown, bodyType)
+	utils.AssertEqual(t, ErrUnprocessableEntity, err)
+}
+
+// go test -run Test_Ctx_BodyParser_NestedBrackets
+func Test_Ctx_BodyParser_NestedBrackets(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type Address struct {
+		City string `form:"city"`
+	}
+	type User struct {
+		Name    string            `form:"name"`
+		Address Address           `form:"address"`
+		Meta    map[string]string `form:"meta"`
+	}
+	type Item struct {
+		Name string `form:"name"`
+	}
+	type Request struct {
+		User    User                `form:"user"`
+		Items   []Item              `form:"items"`
+		IDs     []int               `form:"ids"`
+		Tags    []string            `form:"tags"`
+		Filters map[string][]string `form:"filters"`
+	}
+
+	body := "user[name]=john&user[address][city]=berlin&user[meta][role]=admin&user[meta][1]=one" +
+		"&items[0][name]=a&items[1][name]=b" +
+		"&ids[1]=20&ids[0]=10&ids[2]=30" +
+		"&tags[]=x&tags[]=y" +
+		"&filters[color]=red&filters[color]=blue"
+	c.Request().Header.SetContentType(MIMEApplicationForm)
+	c.Request().SetBody([]byte(body))
+	c.Request().Header.SetContentLength(len(body))
+
+	req := new(Request)
+	utils.AssertEqual(t, nil, c.BodyParser(req))
+	utils.AssertEqual(t, "john", req.User.Name)
+	utils.AssertEqual(t, "berlin", req.User.Address.City)
+	utils.AssertEqual(t, map[string]string{"role": "admin", "1": "one"}, req.User.Meta)
+	utils.AssertEqual(t, []Item{{Name: "a"}, {Name: "b"}}, req.Items)
+	utils.AssertEqual(t, []int{10, 20, 30}, req.IDs)
+	utils.AssertEqual(t, []string{"x", "y"}, req.Tags)
+	utils.AssertEqual(t, map[string][]string{"color": {"red", "blue"}}, req.Filters)
+}
+
+// go test -run Test_Ctx_BodyParser_FormSliceMap
+func Test_Ctx_BodyParser_FormSliceMap(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type Request struct {
+		Tags []string          `form:"tags"`
+		Meta map[string]string `form:"meta"`
+	}
+
+	body := "tags=a&tags=b&meta[k]=v&meta[lang]=go"
+	c.Request().Header.SetContentType(MIMEApplicationForm)
+	c.Request().SetBody([]byte(body))
+	c.Request().Header.SetContentLength(len(body))
+
+	req := new(Request)
+	utils.AssertEqual(t, nil, c.BodyParser(req))
+	utils.AssertEqual(t, []string{"a", "b"}, req.Tags)
+	utils.AssertEqual(t, map[string]string{"k": "v", "lang": "go"}, req.Meta)
+}
+
+// go test -run Test_Ctx_BodyParser_ContentEncoding
+func Test_Ctx_BodyParser_ContentEncoding(t *testing.T) {
+	t.Parallel()
+	type Demo struct {
+		Name string `json:"name" form:"name"`
+	}
+
+	parse := func(app *App, contentType, body string) (*Demo, error) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		c.Request().Header.SetContentType(contentType)
+		c.Request().Header.Set(HeaderContentEncoding, StrGzip)
+		c.Request().SetBody(fasthttp.AppendGzipBytes(nil, []byte(body)))
+		d := new(Demo)
+		return d, c.BodyParser(d)
+	}
+
+	// decompressed before parsing
+	app := New()
+	d, err := parse(app, MIMEApplicationJSON, `{"name":"john"}`)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john", d.Name)
+	d, err = parse(app, MIMEApplicationForm, "name=john")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john", d.Name)
+
+	// invalid compressed data
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().Header.Set(HeaderContentEncoding, StrGzip)
+	c.Request().SetBody([]byte(`{"name":"john"}`))
+	err = c.BodyParser(new(Demo))
+	utils.AssertEqual(t, true, strings.HasPrefix(err.Error(), "bodyparser: failed to decompress gzip body: "))
+	app.ReleaseCtx(c)
+
+	// decompression disabled
+	app = New(Config{DisableBodyDecompression: true})
+	_, err = parse(app, MIMEApplicationJSON, `{"name":"john"}`)
+	var e *Error
+	utils.AssertEqual(t, true, errors.As(err, &e))
+	utils.AssertEqual(t, StatusUnsupportedMediaType, e.Code)
+	utils.AssertEqual(t, "bodyparser: cannot parse gzip encoded body, body decompression is disabled", e.Message)
+}
+
+// go test -run Test_Ctx_BodyParser_MaxDecompressedBodySize
+func Test_Ctx_BodyParser_MaxDecompressedBodySize(t *testing.T) {
+	t.Parallel()
+	type Demo struct {
+		Name string `json:"name"`
+	}
+	small := []byte(`{"name":"john"}`)
+	large := []byte(`{"name":"` + strings.Repeat("a", 1000) + `"}`)
+	compress := map[string]func(dst, src []byte) []byte{
+		StrGzip:    fasthttp.AppendGzipBytes,
+		StrDeflate: fasthttp.AppendDeflateBytes,
+		StrBr:      fasthttp.AppendBrotliBytes,
+	}
+
+	app := New(Config{MaxDecompressedBodySize: 64})
+	app.Post("/", func(c *Ctx) error {
+		d := new(Demo)
+		if err := c.BodyParser(d); err != nil {
+			return err
+		}
+		return c.SendString(d.Name)
+	})
+	post := func(encoding string, body []byte) (int, string) {
+		req := httptest.NewRequest(MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		req.Header.Set(HeaderContentEncoding, encoding)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		b, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return resp.StatusCode, string(b)
+	}
+
+	for encoding, fn := range compress {
+		code, body := post(encoding, fn(nil, small))
+		utils.AssertEqual(t, StatusOK, code, encoding)
+		utils.AssertEqual(t, "john", body, encoding)
+		// the compressed body is small, but exceeds the limit when decompressed
+		compressed := fn(nil, large)
+		utils.AssertEqual(t, true, len(compressed) < 64, encoding)
+		code, _ = post(encoding, compressed)
+		utils.AssertEqual(t, StatusRequestEntityTooLarge, code, encoding)
+	}
+
+	// unknown encodings are passed through untouched
+	code, body := post("identity", small)
+	utils.AssertEqual(t, StatusOK, code)
+	utils.AssertEqual(t, "john", body)
+
+	// the limit defaults to the body limit, -1 disables it
+	c := New(Config{BodyLimit: 64}).AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().Header.Set(HeaderContentEncoding, StrGzip)
+	c.Request().SetBody(fasthttp.AppendGzipBytes(nil, large))
+	utils.AssertEqual(t, true, errors.Is(c.BodyParser(new(Demo)), ErrRequestEntityTooLarge))
+	c = New(Config{BodyLimit: 64, MaxDecompressedBodySize: -1}).AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().Header.Set(HeaderContentEncoding, StrGzip)
+	c.Request().SetBody(fasthttp.AppendGzipBytes(nil, large))
+	utils.AssertEqual(t, nil, c.BodyParser(new(Demo)))
+	utils.AssertEqual(t, large, c.Body())
+}
+
+// go test -run Test_Ctx_DecompressedBody
+func Test_Ctx_DecompressedBody(t *testing.T) {
+	t.Parallel()
+	app := New(Config{MaxDecompressedBodySize: 64})
+	app.Post("/", func(c *Ctx) error {
+		body, err := c.DecompressedBody()
+		if err != nil {
+			return err
+		}
+		return c.Send(body)
+	})
+	post := func(body []byte) (int, string) {
+		req := httptest.NewRequest(MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(HeaderContentEncoding, StrGzip)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		b, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return resp.StatusCode, string(b)
+	}
+
+	code, body := post(fasthttp.AppendGzipBytes(nil, []byte("john")))
+	utils.AssertEqual(t, StatusOK, code)
+	utils.AssertEqual(t, "john", body)
+	code, _ = post(fasthttp.AppendGzipBytes(nil, bytes.Repeat([]byte("a"), 1000)))
+	utils.AssertEqual(t, StatusRequestEntityTooLarge, code)
+	code, _ = post([]byte("not gzip"))
+	utils.AssertEqual(t, StatusInternalServerError, code)
+
+	// the raw body is returned if decompression is disabled
+	c := New(Config{DisableBodyDecompression: true}).AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.Set(HeaderContentEncoding, StrGzip)
+	c.Request().SetBody([]byte("not gzip"))
+	raw, err := c.DecompressedBody()
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "not gzip", string(raw))
+}
+
+// go test -run Test_Ctx_BodyParser_ShortBody
+func Test_Ctx_BodyParser_ShortBody(t *testing.T) {
+	t.Parallel()
+	app := New()
+	type Demo struct {
+		Name string `json:"name" form:"name"`
+	}
+
+	parse := func(contentType, body string, length int) error {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		c.Request().Header.SetContentType(contentType)
+		c.Request().SetBody([]byte(body))
+		c.Request().Header.SetContentLength(length)
+		return c.BodyParser(new(Demo))
+	}
+
+	// the declared length exceeds the body, e.g. of a truncated upload
+	err := parse(MIMEApplicationForm, "name=jo", 9)
+	utils.AssertEqual(t, true, errors.Is(err, io.ErrUnexpectedEOF))
+	utils.AssertEqual(t, "bodyparser: body of 7 bytes is shorter than the Content-Length of 9 bytes: unexpected EOF", err.Error())
+	err = parse(MIMEApplicationJSON, `{"name":`, 15)
+	utils.AssertEqual(t, true, errors.Is(err, io.ErrUnexpectedEOF))
+
+	utils.AssertEqual(t, nil, parse(MIMEApplicationForm, "name=john", 9))
+	utils.AssertEqual(t, nil, parse(MIMEApplicationJSON, `{"name":"john"}`, 15))
+}
+
+// go test -run Test_Ctx_BodyParser_XMLNamespace
+func Test_Ctx_BodyParser_XMLNamespace(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type Feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Title   string   `xml:"http://www.w3.org/2005/Atom title"`
+		Creator string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	}
+
+	body := `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+		`<title>Fiber</title><dc:creator>john</dc:creator></feed>`
+	c.Request().Header.SetContentType(MIMEApplicationXML)
+	c.Request().SetBody([]byte(body))
+	feed := new(Feed)
+	utils.AssertEqual(t, nil, c.BodyParser(feed))
+	utils.AssertEqual(t, "Fiber", feed.Title)
+	utils.AssertEqual(t, "john", feed.Creator)
+
+	// elements from another namespace don't match
+	c.Request().SetBody([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Fiber</title><creator>john</creator></feed>`))
+	feed = new(Feed)
+	utils.AssertEqual(t, nil, c.BodyParser(feed))
+	utils.AssertEqual(t, "Fiber", feed.Title)
+	utils.AssertEqual(t, "", feed.Creator)
+
+	// root element in the wrong namespace
+	c.Request().SetBody([]byte(`<feed xmlns="urn:other"><title>Fiber</title></feed>`))
+	err := c.BodyParser(new(Feed))
+	utils.AssertEqual(t, true, err != nil)
+	utils.AssertEqual(t, true, strings.HasPrefix(err.Error(), "bodyparser: failed to decode xml: "))
+}
+
+// go test -run Test_Ctx_BodyParser_JSONDecoderUseNumber
+func Test_Ctx_BodyParser_JSONDecoderUseNumber(t *testing.T) {
+	t.Parallel()
+	app := New(Config{JSONDecoderUseNumber: true})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type Demo struct {
+		ID     json.Number `json:"id"`
+		Amount json.Number `json:"amount"`
+	}
+
+	body := []byte(`{"id":12345678901234567890,"amount":1234567.8901234567890123}`)
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().SetBody(body)
+	c.Request().Header.SetContentLength(len(body))
+
+	d := new(Demo)
+	utils.AssertEqual(t, nil, c.BodyParser(d))
+	utils.AssertEqual(t, json.Number("12345678901234567890"), d.ID)
+	utils.AssertEqual(t, json.Number("1234567.8901234567890123"), d.Amount)
+
+	m := Map{}
+	utils.AssertEqual(t, nil, c.BodyParser(&m))
+	utils.AssertEqual(t, json.Number("12345678901234567890"), m["id"])
+}
+
+// go test -run Test_Ctx_BodyParser_UnsupportedMediaTypeHandler
+func Test_Ctx_BodyParser_UnsupportedMediaTypeHandler(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		UnsupportedMediaTypeHandler: func(c *Ctx, contentType string) error {
+			return NewError(StatusUnsupportedMediaType, contentType+" is not supported, use "+MIMEApplicationJSON)
+		},
+	})
+
+	type Demo struct {
+		Name string `json:"name"`
+	}
+	app.Post("/", func(c *Ctx) error {
+		d := new(Demo)
+		if err := c.BodyParser(d); err != nil {
+			return err
+		}
+		return c.SendString(d.Name)
+	})
+
+	req := httptest.NewRequest(MethodPost, "/", strings.NewReader("name\njohn"))
+	req.Header.Set(HeaderContentType, "text/csv")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusUnsupportedMediaType, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "text/csv is not supported, use application/json", string(body))
+}
+
 func Test_Ctx_ParamParser(t *testing.T) {
 	t.Parallel()
 	app := New()
@@ -721,6 +1288,48 @@ func Test_Ctx_Cookie(t *testing.T) {
 	utils.AssertEqual(t, expect, string(c.Response().Header.Peek(HeaderSetCookie)))
 }
 
+// go test -run Test_Ctx_Cookie_Partitioned
+func Test_Ctx_Cookie_Partitioned(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	cookie := &Cookie{
+		Name:     "username",
+		Value:    "john",
+		Secure:   true,
+		SameSite: CookieSameSiteNoneMode,
+	}
+	c.Cookie(cookie)
+	cookie.Partitioned = true
+	c.Cookie(cookie)
+	// the cookie is replaced, not added twice
+	utils.AssertEqual(t, "username=john; path=/; secure; SameSite=None; Partitioned", string(c.Response().Header.Peek(HeaderSetCookie)))
+}
+
+// go test -run Test_BuildSetCookie
+func Test_BuildSetCookie(t *testing.T) {
+	t.Parallel()
+	expire := time.Date(2022, time.August, 1, 10, 0, 0, 0, time.UTC)
+	httpdate := "Mon, 01 Aug 2022 10:00:00 GMT"
+
+	testCookie := func(cookie Cookie, expect string) {
+		utils.AssertEqual(t, expect, BuildSetCookie(cookie))
+	}
+
+	testCookie(Cookie{Name: "john", Value: "doe"}, "john=doe; path=/; SameSite=Lax")
+	testCookie(Cookie{Name: "john", Value: "doe", Path: "/api", Domain: "gofiber.io"}, "john=doe; domain=gofiber.io; path=/api; SameSite=Lax")
+	testCookie(Cookie{Name: "john", Value: "doe", Expires: expire}, "john=doe; expires="+httpdate+"; path=/; SameSite=Lax")
+	testCookie(Cookie{Name: "john", Value: "doe", MaxAge: 60}, "john=doe; max-age=60; path=/; SameSite=Lax")
+	testCookie(Cookie{Name: "john", Value: "doe", MaxAge: 60, Expires: expire, SessionOnly: true}, "john=doe; path=/; SameSite=Lax")
+	testCookie(Cookie{Name: "john", Value: "doe", HTTPOnly: true}, "john=doe; path=/; HttpOnly; SameSite=Lax")
+	testCookie(Cookie{Name: "john", Value: "doe", SameSite: CookieSameSiteStrictMode}, "john=doe; path=/; SameSite=Strict")
+	testCookie(Cookie{Name: "john", Value: "doe", SameSite: CookieSameSiteDisabled}, "john=doe; path=/")
+	testCookie(Cookie{Name: "john", Value: "doe", Secure: true, SameSite: CookieSameSiteNoneMode}, "john=doe; path=/; secure; SameSite=None")
+	testCookie(Cookie{Name: "john", Value: "doe", Secure: true, SameSite: CookieSameSiteNoneMode, Partitioned: true}, "john=doe; path=/; secure; SameSite=None; Partitioned")
+}
+
 // go test -v -run=^$ -bench=Benchmark_Ctx_Cookie -benchmem -count=4
 func Benchmark_Ctx_Cookie(b *testing.B) {
 	app := New()
@@ -748,6 +1357,48 @@ func Test_Ctx_Cookies(t *testing.T) {
 	utils.AssertEqual(t, "default", c.Cookies("unknown", "default"))
 }
 
+// go test -run Test_Ctx_CookieTyped
+func Test_Ctx_CookieTyped(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.Set(HeaderCookie, "page=3; dark=true; ratio=1.5; name=john; id=b2e0e0a4-6d3c-4b1e-9b5a-3f0c2d1e4a5b; bad=x1")
+
+	utils.AssertEqual(t, 3, c.CookieInt("page"))
+	utils.AssertEqual(t, 0, c.CookieInt("bad"))
+	utils.AssertEqual(t, 1, c.CookieInt("missing", 1))
+	utils.AssertEqual(t, true, c.CookieBool("dark"))
+	utils.AssertEqual(t, true, c.CookieBool("bad", true))
+	utils.AssertEqual(t, 1.5, c.CookieFloat("ratio"))
+	utils.AssertEqual(t, 0.0, c.CookieFloat("name"))
+
+	compile := func(constraint string) *CompiledConstraint {
+		cc, err := app.CompileConstraint(constraint)
+		utils.AssertEqual(t, nil, err)
+		return cc
+	}
+	value, err := c.CookieConstraint("id", compile(ConstraintGuid))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "b2e0e0a4-6d3c-4b1e-9b5a-3f0c2d1e4a5b", value)
+	value, err = c.CookieConstraint("name", compile("alpha;minLen(3)"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john", value)
+	value, err = c.CookieConstraint("page", compile("int;range(1,2)"))
+	utils.AssertEqual(t, `cookie: "page" does not satisfy the constraint "int;range(1,2)"`, err.Error())
+	utils.AssertEqual(t, "", value)
+	_, err = c.CookieConstraint("bad", compile(ConstraintAlpha))
+	utils.AssertEqual(t, true, err != nil)
+	_, err = c.CookieConstraint("missing", compile(ConstraintInt))
+	utils.AssertEqual(t, true, err != nil)
+
+	// the float getters accept the values of the float constraint
+	c.Request().Header.Set(HeaderCookie, "big=1e300")
+	utils.AssertEqual(t, 1e300, c.CookieFloat("big"))
+	_, err = c.CookieConstraint("big", compile(ConstraintFloat))
+	utils.AssertEqual(t, nil, err)
+}
+
 // go test -run Test_Ctx_Format
 func Test_Ctx_Format(t *testing.T) {
 	t.Parallel()
@@ -970,12 +1621,115 @@ func Test_Ctx_Fresh(t *testing.T) {
 	c.Response().Header.Set(HeaderLastModified, "Wed, 21 Oct 2015 07:28:00 GMT")
 	utils.AssertEqual(t, false, c.Fresh())
 
+	// the echoed Last-Modified date is fresh
 	c.Request().Header.Set(HeaderIfModifiedSince, "Wed, 21 Oct 2015 07:28:00 GMT")
-	utils.AssertEqual(t, false, c.Fresh())
+	utils.AssertEqual(t, true, c.Fresh())
 }
 
-// go test -v -run=^$ -bench=Benchmark_Ctx_Fresh_WithNoCache -benchmem -count=4
-func Benchmark_Ctx_Fresh_WithNoCache(b *testing.B) {
+// go test -run Test_Ctx_Fresh_Conditional
+func Test_Ctx_Fresh_Conditional(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	testCases := []struct {
+		noneMatch, modifiedSince string
+		etag, lastModified       string
+		cacheControl             string
+		fresh                    bool
+	}{
+		// If-Modified-Since only
+		{modifiedSince: "Wed, 21 Oct 2015 08:00:00 GMT", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", fresh: true},
+		{modifiedSince: "Wed, 21 Oct 2015 07:28:00 GMT", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", fresh: true},
+		{modifiedSince: "Wed, 21 Oct 2015 07:00:00 GMT", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", fresh: false},
+		{modifiedSince: "Wed, 21 Oct 2015 08:00:00 GMT", fresh: false},
+		// If-None-Match only
+		{noneMatch: `"a"`, etag: `"a"`, fresh: true},
+		{noneMatch: `W/"a"`, etag: `"a"`, fresh: true},
+		{noneMatch: `"a"`, etag: `"b"`, fresh: false},
+		// both headers must be fresh
+		{noneMatch: `"a"`, etag: `"a"`, modifiedSince: "Wed, 21 Oct 2015 08:00:00 GMT", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", fresh: true},
+		{noneMatch: `"a"`, etag: `"a"`, modifiedSince: "Wed, 21 Oct 2015 07:00:00 GMT", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", fresh: false},
+		{noneMatch: `"a"`, etag: `"b"`, modifiedSince: "Wed, 21 Oct 2015 08:00:00 GMT", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", fresh: false},
+		{noneMatch: "*", modifiedSince: "Wed, 21 Oct 2015 07:00:00 GMT", lastModified: "Wed, 21 Oct 2015 07:28:00 GMT", fresh: false},
+		// end-to-end reload
+		{noneMatch: `"a"`, etag: `"a"`, cacheControl: "no-cache", fresh: false},
+	}
+
+	for _, tc := range testCases {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		if tc.noneMatch != "" {
+			c.Request().Header.Set(HeaderIfNoneMatch, tc.noneMatch)
+		}
+		if tc.modifiedSince != "" {
+			c.Request().Header.Set(HeaderIfModifiedSince, tc.modifiedSince)
+		}
+		if tc.cacheControl != "" {
+			c.Request().Header.Set(HeaderCacheControl, tc.cacheControl)
+		}
+		if tc.etag != "" {
+			c.Set(HeaderETag, tc.etag)
+		}
+		if tc.lastModified != "" {
+			c.Set(HeaderLastModified, tc.lastModified)
+		}
+		utils.AssertEqual(t, tc.fresh, c.Fresh(), fmt.Sprintf("%+v", tc))
+		utils.AssertEqual(t, !tc.fresh, c.Stale(), fmt.Sprintf("%+v", tc))
+		app.ReleaseCtx(c)
+	}
+}
+
+// go test -run Test_Ctx_CheckPreconditions
+func Test_Ctx_CheckPreconditions(t *testing.T) {
+	t.Parallel()
+	app := New()
+	modified := time.Date(2022, time.March, 1, 10, 0, 0, 500, time.UTC)
+
+	testCases := []struct {
+		ifMatch, ifUnmodifiedSince string
+		etag                       string
+		lastModified               time.Time
+		ok                         bool
+	}{
+		{ok: true, etag: `"abc"`},
+		{ifMatch: `"abc"`, etag: `"abc"`, ok: true},
+		{ifMatch: `"xyz", "abc"`, etag: `"abc"`, ok: true},
+		{ifMatch: `"xyz"`, etag: `"abc"`, ok: false},
+		{ifMatch: `W/"abc"`, etag: `"abc"`, ok: false},
+		{ifMatch: `"abc"`, etag: `W/"abc"`, ok: false},
+		{ifMatch: `"abc"`, ok: false},
+		{ifMatch: "*", etag: `"abc"`, ok: true},
+		{ifMatch: "*", lastModified: modified, ok: true},
+		{ifMatch: "*", ok: false},
+		{ifUnmodifiedSince: "Tue, 01 Mar 2022 10:00:00 GMT", lastModified: modified, ok: true},
+		{ifUnmodifiedSince: "Tue, 01 Mar 2022 11:00:00 GMT", lastModified: modified, ok: true},
+		{ifUnmodifiedSince: "Tue, 01 Mar 2022 09:59:59 GMT", lastModified: modified, ok: false},
+		{ifUnmodifiedSince: "invalid", lastModified: modified, ok: true},
+		{ifUnmodifiedSince: "Tue, 01 Mar 2022 09:59:59 GMT", ok: true},
+		// If-Unmodified-Since is ignored along with If-Match
+		{ifMatch: `"abc"`, ifUnmodifiedSince: "Tue, 01 Mar 2022 09:59:59 GMT", etag: `"abc"`, lastModified: modified, ok: true},
+	}
+
+	for _, tc := range testCases {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		c.Request().Header.SetMethod(MethodPut)
+		if tc.ifMatch != "" {
+			c.Request().Header.Set(HeaderIfMatch, tc.ifMatch)
+		}
+		if tc.ifUnmodifiedSince != "" {
+			c.Request().Header.Set(HeaderIfUnmodifiedSince, tc.ifUnmodifiedSince)
+		}
+		utils.AssertEqual(t, tc.ok, c.CheckPreconditions(tc.etag, tc.lastModified), fmt.Sprintf("%+v", tc))
+		if tc.ok {
+			utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+		} else {
+			utils.AssertEqual(t, StatusPreconditionFailed, c.Response().StatusCode())
+		}
+		app.ReleaseCtx(c)
+	}
+}
+
+// go test -v -run=^$ -bench=Benchmark_Ctx_Fresh_WithNoCache -benchmem -count=4
+func Benchmark_Ctx_Fresh_WithNoCache(b *testing.B) {
 	app := New()
 	c := app.AcquireCtx(&fasthttp.RequestCtx{})
 	defer app.ReleaseCtx(c)
@@ -1079,6 +1833,76 @@ func Test_Ctx_Port(t *testing.T) {
 	utils.AssertEqual(t, "0", c.Port())
 }
 
+// go test -run Test_Ctx_Port_ForwardedPort
+func Test_Ctx_Port_ForwardedPort(t *testing.T) {
+	t.Parallel()
+	// the header is ignored without trusted proxy check
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.Set(HeaderXForwardedPort, "8443")
+	utils.AssertEqual(t, "0", c.Port())
+	app.ReleaseCtx(c)
+
+	// trusted proxy
+	app = New(Config{EnableTrustedProxyCheck: true, TrustedProxies: []string{"0.0.0.0"}})
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.Set(HeaderXForwardedPort, "8443")
+	utils.AssertEqual(t, "8443", c.Port())
+	c.Request().Header.Set(HeaderXForwardedPort, "invalid")
+	utils.AssertEqual(t, "0", c.Port())
+	c.Request().Header.Set(HeaderXForwardedPort, "70000")
+	utils.AssertEqual(t, "0", c.Port())
+	app.ReleaseCtx(c)
+
+	// untrusted proxy
+	app = New(Config{EnableTrustedProxyCheck: true, TrustedProxies: []string{"0.8.0.1"}})
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.Set(HeaderXForwardedPort, "8443")
+	utils.AssertEqual(t, "0", c.Port())
+}
+
+// go test -run Test_Ctx_Port_Addr
+func Test_Ctx_Port_Addr(t *testing.T) {
+	t.Parallel()
+	// connections without ports, e.g. on unix sockets
+	app := New()
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Init(&fasthttp.Request{}, &net.UnixAddr{Name: "/tmp/fiber.sock", Net: "unix"}, nil)
+	c := app.AcquireCtx(fctx)
+	utils.AssertEqual(t, "", c.Port())
+	app.ReleaseCtx(c)
+
+	// the local port is used for trusted proxies without valid X-Forwarded-Port header
+	app = New(Config{DisableStartupMessage: true, EnableTrustedProxyCheck: true, TrustedProxies: []string{"127.0.0.1"}})
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString(c.Port())
+	})
+	ln, err := net.Listen(NetworkTCP4, "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	go func() {
+		_ = app.Listener(ln)
+	}()
+	defer func() {
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
+
+	localPort := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	for header, expected := range map[string]string{"": localPort, "invalid": localPort, "8443": "8443"} {
+		req, err := http.NewRequest(MethodGet, "http://"+ln.Addr().String()+"/", nil)
+		utils.AssertEqual(t, nil, err)
+		if header != "" {
+			req.Header.Set(HeaderXForwardedPort, header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, nil, resp.Body.Close())
+		utils.AssertEqual(t, expected, string(body), header)
+	}
+}
+
 // go test -run Test_Ctx_PortInHandler
 func Test_Ctx_PortInHandler(t *testing.T) {
 	t.Parallel()
@@ -1122,28 +1946,27 @@ func Test_Ctx_IP_ProxyHeader(t *testing.T) {
 
 	for _, proxyHeaderName := range proxyHeaderNames {
 		app := New(Config{ProxyHeader: proxyHeaderName})
-		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		// the resolved IP is cached per request, so every case needs a fresh ctx
+		ipFor := func(value string) string {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			c.Request().Header.Set(proxyHeaderName, value)
+			return c.IP()
+		}
 
-		c.Request().Header.Set(proxyHeaderName, "0.0.0.1")
-		utils.AssertEqual(t, "0.0.0.1", c.IP())
+		utils.AssertEqual(t, "0.0.0.1", ipFor("0.0.0.1"))
 
 		// without IP validation we return the full string
-		c.Request().Header.Set(proxyHeaderName, "0.0.0.1, 0.0.0.2")
-		utils.AssertEqual(t, "0.0.0.1, 0.0.0.2", c.IP())
+		utils.AssertEqual(t, "0.0.0.1, 0.0.0.2", ipFor("0.0.0.1, 0.0.0.2"))
 
 		// without IP validation we return invalid IPs
-		c.Request().Header.Set(proxyHeaderName, "invalid, 0.0.0.2, 0.0.0.3")
-		utils.AssertEqual(t, "invalid, 0.0.0.2, 0.0.0.3", c.IP())
+		utils.AssertEqual(t, "invalid, 0.0.0.2, 0.0.0.3", ipFor("invalid, 0.0.0.2, 0.0.0.3"))
 
 		// when proxy header is enabled but the value is empty, without IP validation we return an empty string
-		c.Request().Header.Set(proxyHeaderName, "")
-		utils.AssertEqual(t, "", c.IP())
+		utils.AssertEqual(t, "", ipFor(""))
 
 		// without IP validation we return an invalid IP
-		c.Request().Header.Set(proxyHeaderName, "not-valid-ip")
-		utils.AssertEqual(t, "not-valid-ip", c.IP())
-
-		app.ReleaseCtx(c)
+		utils.AssertEqual(t, "not-valid-ip", ipFor("not-valid-ip"))
 	}
 }
 
@@ -1156,32 +1979,52 @@ func Test_Ctx_IP_ProxyHeader_With_IP_Validation(t *testing.T) {
 
 	for _, proxyHeaderName := range proxyHeaderNames {
 		app := New(Config{EnableIPValidation: true, ProxyHeader: proxyHeaderName})
-		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		// the resolved IP is cached per request, so every case needs a fresh ctx
+		ipFor := func(value string) string {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			c.Request().Header.Set(proxyHeaderName, value)
+			return c.IP()
+		}
 
 		// when proxy header & validation is enabled and the value is a valid IP, we return it
-		c.Request().Header.Set(proxyHeaderName, "0.0.0.1")
-		utils.AssertEqual(t, "0.0.0.1", c.IP())
+		utils.AssertEqual(t, "0.0.0.1", ipFor("0.0.0.1"))
 
 		// when proxy header & validation is enabled and the value is a list of IPs, we return the first valid IP
-		c.Request().Header.Set(proxyHeaderName, "0.0.0.1, 0.0.0.2")
-		utils.AssertEqual(t, "0.0.0.1", c.IP())
+		utils.AssertEqual(t, "0.0.0.1", ipFor("0.0.0.1, 0.0.0.2"))
 
-		c.Request().Header.Set(proxyHeaderName, "invalid, 0.0.0.2, 0.0.0.3")
-		utils.AssertEqual(t, "0.0.0.2", c.IP())
+		utils.AssertEqual(t, "0.0.0.2", ipFor("invalid, 0.0.0.2, 0.0.0.3"))
 
 		// when proxy header & validation is enabled but the value is empty, we will ignore the header
-		c.Request().Header.Set(proxyHeaderName, "")
-		utils.AssertEqual(t, "0.0.0.0", c.IP())
+		utils.AssertEqual(t, "0.0.0.0", ipFor(""))
 
 		// when proxy header & validation is enabled but the value is not an IP, we will ignore the header
 		// and return the IP of the caller
-		c.Request().Header.Set(proxyHeaderName, "not-valid-ip")
-		utils.AssertEqual(t, "0.0.0.0", c.IP())
-
-		app.ReleaseCtx(c)
+		utils.AssertEqual(t, "0.0.0.0", ipFor("not-valid-ip"))
 	}
 }
 
+// go test -run Test_Ctx_IP_Cached
+func Test_Ctx_IP_Cached(t *testing.T) {
+	t.Parallel()
+	app := New(Config{EnableTrustedProxyCheck: true, TrustedProxies: []string{"0.0.0.0/8"}, ProxyHeader: HeaderXForwardedFor})
+	fctx := &fasthttp.RequestCtx{}
+	c := app.AcquireCtx(fctx)
+
+	c.Request().Header.Set(HeaderXForwardedFor, "0.0.0.1")
+	utils.AssertEqual(t, "0.0.0.1", c.IP())
+
+	// the header is not parsed again for the same request
+	c.Request().Header.Set(HeaderXForwardedFor, "0.0.0.2")
+	utils.AssertEqual(t, "0.0.0.1", c.IP())
+
+	// the cache is reset when the ctx is recycled
+	app.ReleaseCtx(c)
+	c = app.AcquireCtx(fctx)
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, "0.0.0.2", c.IP())
+}
+
 // go test -run Test_Ctx_IP_UntrustedProxy
 func Test_Ctx_IP_UntrustedProxy(t *testing.T) {
 	t.Parallel()
@@ -1202,6 +2045,51 @@ func Test_Ctx_IP_TrustedProxy(t *testing.T) {
 	utils.AssertEqual(t, "0.0.0.1", c.IP())
 }
 
+// go test -run Test_Ctx_IP_ForwardedForChain
+func Test_Ctx_IP_ForwardedForChain(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          []string{"0.0.0.0", "10.0.0.0/8", "2001:db8::1"},
+		ProxyHeader:             HeaderXForwardedFor,
+	})
+
+	testCases := []struct {
+		header string
+		ip     string
+		ips    []string
+	}{
+		// the spoofed address on the left is skipped
+		{header: "6.6.6.6, 1.1.1.1, 10.0.0.2, 10.0.0.1", ip: "1.1.1.1", ips: []string{"6.6.6.6", "1.1.1.1"}},
+		{header: "1.1.1.1", ip: "1.1.1.1", ips: []string{"1.1.1.1"}},
+		{header: "1.1.1.1:1234, 10.0.0.1:80", ip: "1.1.1.1", ips: []string{"1.1.1.1:1234"}},
+		{header: "2001:db8::2, [2001:db8::1]:443", ip: "2001:db8::2", ips: []string{"2001:db8::2"}},
+		// all addresses are trusted proxies, the first one is the client
+		{header: "10.0.0.3, 10.0.0.2", ip: "10.0.0.3", ips: []string{"10.0.0.3"}},
+		{header: "", ip: "0.0.0.0", ips: []string{}},
+	}
+
+	for _, tc := range testCases {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		c.Request().Header.Set(HeaderXForwardedFor, tc.header)
+		utils.AssertEqual(t, tc.ip, c.IP(), tc.header)
+		utils.AssertEqual(t, tc.ips, c.IPs(), tc.header)
+		app.ReleaseCtx(c)
+	}
+
+	// the header is ignored if the remote address is not a trusted proxy
+	app = New(Config{
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          []string{"10.0.0.0/8"},
+		ProxyHeader:             HeaderXForwardedFor,
+	})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.Set(HeaderXForwardedFor, "1.1.1.1, 10.0.0.1")
+	utils.AssertEqual(t, "0.0.0.0", c.IP())
+	utils.AssertEqual(t, 0, len(c.IPs()))
+}
+
 // go test -run Test_Ctx_IPs  -parallel
 func Test_Ctx_IPs(t *testing.T) {
 	t.Parallel()
@@ -1407,6 +2295,45 @@ func Test_Ctx_Locals(t *testing.T) {
 	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
 }
 
+type (
+	testLocalsKeyA struct{}
+	testLocalsKeyB struct{}
+	testLocalsKeyC string
+)
+
+// go test -run Test_Ctx_Locals_TypedKeys
+func Test_Ctx_Locals_TypedKeys(t *testing.T) {
+	t.Parallel()
+	app := New()
+	// two packages using their own private key types
+	app.Use(func(c *Ctx) error {
+		c.Locals(testLocalsKeyA{}, "a")
+		c.Locals(testLocalsKeyB{}, "b")
+		c.Locals(testLocalsKeyC("user"), "c")
+		c.Locals("user", "string")
+		return c.Next()
+	})
+	app.Get("/test", func(c *Ctx) error {
+		utils.AssertEqual(t, "a", c.Locals(testLocalsKeyA{}))
+		utils.AssertEqual(t, "b", c.Locals(testLocalsKeyB{}))
+		utils.AssertEqual(t, "c", c.Locals(testLocalsKeyC("user")))
+		utils.AssertEqual(t, "string", c.Locals("user"))
+		utils.AssertEqual(t, "string", c.Context().UserValue("user"))
+		return nil
+	})
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/test", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	// the values don't leak into other requests
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	c.Locals(testLocalsKeyA{}, "a")
+	app.ReleaseCtx(c)
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, nil, c.Locals(testLocalsKeyA{}))
+}
+
 // go test -run Test_Ctx_Method
 func Test_Ctx_Method(t *testing.T) {
 	t.Parallel()
@@ -1484,6 +2411,45 @@ func Test_Ctx_ClientHelloInfo(t *testing.T) {
 	utils.AssertEqual(t, "["+strconv.Itoa(VersionTLS13)+"]", string(body))
 }
 
+// go test -run Test_Ctx_ClientCertificates
+func Test_Ctx_ClientCertificates(t *testing.T) {
+	t.Parallel()
+	serverTLSConf, clientTLSConf, err := tlstest.GetTLSConfigs()
+	utils.AssertEqual(t, nil, err)
+	// the certificate of the server is signed by the CA for client authentication too
+	serverTLSConf.ClientAuth = tls.RequireAndVerifyClientCert
+	serverTLSConf.ClientCAs = clientTLSConf.RootCAs
+	clientTLSConf.Certificates = serverTLSConf.Certificates
+
+	ln, err := net.Listen(NetworkTCP4, "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	ln = tls.NewListener(ln, serverTLSConf)
+
+	app := New(Config{DisableStartupMessage: true})
+	app.Get("/", func(c *Ctx) error {
+		certs := c.ClientCertificates()
+		utils.AssertEqual(t, 2, len(certs))
+		utils.AssertEqual(t, uint16(tls.VersionTLS13), c.TLSVersion())
+		return c.SendString(certs[0].SerialNumber.String())
+	})
+
+	go func() { utils.AssertEqual(t, nil, app.Listener(ln)) }()
+
+	code, body, errs := Get("https://" + ln.Addr().String()).
+		TLSConfig(clientTLSConf).
+		String()
+
+	utils.AssertEqual(t, 0, len(errs))
+	utils.AssertEqual(t, StatusOK, code)
+	utils.AssertEqual(t, "2021", body)
+
+	// plain connection
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, true, c.ClientCertificates() == nil)
+	utils.AssertEqual(t, uint16(0), c.TLSVersion())
+}
+
 // go test -run Test_Ctx_InvalidMethod
 func Test_Ctx_InvalidMethod(t *testing.T) {
 	t.Parallel()
@@ -1806,6 +2772,87 @@ func Test_Ctx_Protocol(t *testing.T) {
 	utils.AssertEqual(t, "http", c.Protocol())
 }
 
+// go test -run Test_Ctx_IsWebSocketUpgrade
+func Test_Ctx_IsWebSocketUpgrade(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	utils.AssertEqual(t, false, c.IsWebSocketUpgrade())
+
+	c.Request().Header.Set(HeaderConnection, "keep-alive, Upgrade")
+	c.Request().Header.Set(HeaderUpgrade, "WebSocket")
+	c.Request().Header.Set(HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+	c.Request().Header.Set(HeaderSecWebSocketVersion, "13")
+	utils.AssertEqual(t, true, c.IsWebSocketUpgrade())
+	// the response is not touched
+	utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderUpgrade)))
+	utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderSecWebSocketAccept)))
+	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+
+	c.Request().Header.Set(HeaderSecWebSocketVersion, "8")
+	utils.AssertEqual(t, false, c.IsWebSocketUpgrade())
+	c.Request().Header.Del(HeaderSecWebSocketVersion)
+	utils.AssertEqual(t, false, c.IsWebSocketUpgrade())
+	c.Request().Header.Set(HeaderSecWebSocketVersion, "13")
+	c.Request().Header.Del(HeaderSecWebSocketKey)
+	utils.AssertEqual(t, false, c.IsWebSocketUpgrade())
+	c.Request().Header.Set(HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+
+	c.Request().Header.SetMethod(MethodPost)
+	c.Method(MethodPost)
+	utils.AssertEqual(t, false, c.IsWebSocketUpgrade())
+	c.Method(MethodGet)
+
+	c.Request().Header.Set(HeaderUpgrade, "h2c")
+	utils.AssertEqual(t, false, c.IsWebSocketUpgrade())
+
+	c.Request().Header.Set(HeaderUpgrade, "websocket")
+	c.Request().Header.Set(HeaderConnection, "keep-alive")
+	utils.AssertEqual(t, false, c.IsWebSocketUpgrade())
+}
+
+// go test -run Test_Ctx_IsSecureWebSocket
+func Test_Ctx_IsSecureWebSocket(t *testing.T) {
+	t.Parallel()
+	upgrade := func(c *Ctx) {
+		c.Request().Header.Set(HeaderConnection, "Upgrade")
+		c.Request().Header.Set(HeaderUpgrade, "websocket")
+		c.Request().Header.Set(HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+		c.Request().Header.Set(HeaderSecWebSocketVersion, "13")
+	}
+
+	// TLS connection
+	app := New()
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Init2(tls.Server(&testConn{}, &tls.Config{MinVersion: tls.VersionTLS12}), nil, false)
+	c := app.AcquireCtx(fctx)
+	utils.AssertEqual(t, false, c.IsSecureWebSocket())
+	upgrade(c)
+	utils.AssertEqual(t, true, c.IsSecureWebSocket())
+	app.ReleaseCtx(c)
+
+	// plain connection
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	upgrade(c)
+	utils.AssertEqual(t, false, c.IsSecureWebSocket())
+	// TLS terminated by a proxy
+	c.Request().Header.Set(HeaderXForwardedProto, "wss")
+	utils.AssertEqual(t, true, c.IsSecureWebSocket())
+	c.Request().Header.Set(HeaderXForwardedProto, "https")
+	utils.AssertEqual(t, true, c.IsSecureWebSocket())
+	app.ReleaseCtx(c)
+
+	// forwarded protocol of an untrusted proxy
+	app = New(Config{EnableTrustedProxyCheck: true})
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	upgrade(c)
+	c.Request().Header.Set(HeaderXForwardedProto, "https")
+	utils.AssertEqual(t, false, c.IsSecureWebSocket())
+}
+
 // go test -v -run=^$ -bench=Benchmark_Ctx_Protocol -benchmem -count=4
 func Benchmark_Ctx_Protocol(b *testing.B) {
 	app := New()
@@ -1936,6 +2983,52 @@ func Test_Ctx_Query(t *testing.T) {
 	utils.AssertEqual(t, "default", c.Query("unknown", "default"))
 }
 
+// go test -run Test_Ctx_QueryInt
+func Test_Ctx_QueryInt(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("age=20&negative=-5&name=john&float=1.5")
+	utils.AssertEqual(t, 20, c.QueryInt("age"))
+	utils.AssertEqual(t, -5, c.QueryInt("negative", 1))
+	utils.AssertEqual(t, 0, c.QueryInt("name"))
+	utils.AssertEqual(t, 18, c.QueryInt("name", 18))
+	utils.AssertEqual(t, 18, c.QueryInt("float", 18))
+	utils.AssertEqual(t, 18, c.QueryInt("unknown", 18))
+}
+
+// go test -run Test_Ctx_QueryBool
+func Test_Ctx_QueryBool(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("a=1&b=t&c=true&d=0&e=false&f=yes")
+	utils.AssertEqual(t, true, c.QueryBool("a"))
+	utils.AssertEqual(t, true, c.QueryBool("b"))
+	utils.AssertEqual(t, true, c.QueryBool("c"))
+	utils.AssertEqual(t, false, c.QueryBool("d", true))
+	utils.AssertEqual(t, false, c.QueryBool("e", true))
+	utils.AssertEqual(t, false, c.QueryBool("f"))
+	utils.AssertEqual(t, true, c.QueryBool("f", true))
+	utils.AssertEqual(t, true, c.QueryBool("unknown", true))
+}
+
+// go test -run Test_Ctx_QueryFloat
+func Test_Ctx_QueryFloat(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("price=9.99&count=3&name=john")
+	utils.AssertEqual(t, 9.99, c.QueryFloat("price"))
+	utils.AssertEqual(t, 3.0, c.QueryFloat("count"))
+	utils.AssertEqual(t, 0.0, c.QueryFloat("name"))
+	utils.AssertEqual(t, 1.5, c.QueryFloat("name", 1.5))
+	utils.AssertEqual(t, 1.5, c.QueryFloat("unknown", 1.5))
+}
+
 // go test -run Test_Ctx_Range
 func Test_Ctx_Range(t *testing.T) {
 	t.Parallel()
@@ -2095,6 +3188,62 @@ func Test_Ctx_SaveFileToStorage(t *testing.T) {
 	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
 }
 
+type testStreamStorage struct {
+	Storage
+	streamed bool
+}
+
+type testStorageWriter struct {
+	bytes.Buffer
+	key     string
+	storage *testStreamStorage
+}
+
+func (s *testStreamStorage) Writer(key string, _ time.Duration) (io.WriteCloser, error) {
+	return &testStorageWriter{key: key, storage: s}, nil
+}
+
+func (w *testStorageWriter) Close() error {
+	w.storage.streamed = true
+	return w.storage.Set(w.key, w.Bytes(), 0)
+}
+
+// go test -run Test_Ctx_SaveFileToStorage_Writer
+func Test_Ctx_SaveFileToStorage_Writer(t *testing.T) {
+	t.Parallel()
+	app := New()
+	storage := &testStreamStorage{Storage: memory.New()}
+
+	app.Post("/test", func(c *Ctx) error {
+		fh, err := c.FormFile("file")
+		utils.AssertEqual(t, nil, err)
+
+		utils.AssertEqual(t, nil, c.SaveFileToStorage(fh, "test", storage))
+		utils.AssertEqual(t, true, storage.streamed)
+
+		file, err := storage.Get("test")
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, []byte("hello world"), file)
+		return nil
+	})
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	ioWriter, err := writer.CreateFormFile("file", "test")
+	utils.AssertEqual(t, nil, err)
+	_, err = ioWriter.Write([]byte("hello world"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, writer.Close())
+
+	req := httptest.NewRequest(MethodPost, "/test", body)
+	req.Header.Set(HeaderContentType, writer.FormDataContentType())
+	req.Header.Set(HeaderContentLength, strconv.Itoa(len(body.Bytes())))
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+}
+
 // go test -run Test_Ctx_Secure
 func Test_Ctx_Secure(t *testing.T) {
 	t.Parallel()
@@ -2159,6 +3308,45 @@ func Test_Ctx_ClearCookie(t *testing.T) {
 	utils.AssertEqual(t, true, strings.Contains(string(c.Response().Header.Peek(HeaderSetCookie)), "test2=; expires="))
 }
 
+// go test -run Test_Ctx_ClearScopedCookie
+func Test_Ctx_ClearScopedCookie(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Cookie(&Cookie{Name: "session", Value: "1", Path: "/admin", Domain: "example.com"})
+	c.ClearScopedCookie("session", "/admin", "example.com")
+	utils.AssertEqual(t, "session=; expires=Tue, 10 Nov 2009 23:00:00 GMT; domain=example.com; path=/admin",
+		string(c.Response().Header.Peek(HeaderSetCookie)))
+}
+
+// go test -run Test_Ctx_Cookie_SameSite
+func Test_Ctx_Cookie_SameSite(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	// invalid values fall back to lax
+	c.Cookie(&Cookie{Name: "a", Value: "1", SameSite: "invalid"})
+	utils.AssertEqual(t, "a=1; path=/; SameSite=Lax", string(c.Response().Header.Peek(HeaderSetCookie)))
+	c.Cookie(&Cookie{Name: "a", Value: "1", SameSite: "Strict"})
+	utils.AssertEqual(t, "a=1; path=/; SameSite=Strict", string(c.Response().Header.Peek(HeaderSetCookie)))
+
+	// none implies secure
+	c.Cookie(&Cookie{Name: "a", Value: "1", SameSite: CookieSameSiteNoneMode})
+	utils.AssertEqual(t, "a=1; path=/; secure; SameSite=None", string(c.Response().Header.Peek(HeaderSetCookie)))
+
+	app = New(Config{RejectInsecureSameSiteNone: true})
+	c2 := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c2)
+	utils.AssertEqual(t, ErrCookieSameSiteNoneInsecure, c2.SetCookie(&Cookie{Name: "a", Value: "1", SameSite: "None"}))
+	c2.Cookie(&Cookie{Name: "a", Value: "1", SameSite: "None"})
+	utils.AssertEqual(t, "", string(c2.Response().Header.Peek(HeaderSetCookie)))
+	utils.AssertEqual(t, nil, c2.SetCookie(&Cookie{Name: "a", Value: "1", SameSite: "None", Secure: true}))
+	utils.AssertEqual(t, "a=1; path=/; secure; SameSite=None", string(c2.Response().Header.Peek(HeaderSetCookie)))
+}
+
 // go test -race -run Test_Ctx_Download
 func Test_Ctx_Download(t *testing.T) {
 	t.Parallel()
@@ -2179,21 +3367,116 @@ func Test_Ctx_Download(t *testing.T) {
 
 	c.Download("ctx.go")
 	utils.AssertEqual(t, `attachment; filename="ctx.go"`, string(c.Response().Header.Peek(HeaderContentDisposition)))
+
+	c.Download("ctx.go", "résumé.go")
+	utils.AssertEqual(t, `attachment; filename="r_sum_.go"; filename*=UTF-8''r%C3%A9sum%C3%A9.go`, string(c.Response().Header.Peek(HeaderContentDisposition)))
 }
 
-// go test -race -run Test_Ctx_SendFile
-func Test_Ctx_SendFile(t *testing.T) {
+// go test -run Test_Ctx_Download_ContentLength
+func Test_Ctx_Download_ContentLength(t *testing.T) {
 	t.Parallel()
+	info, err := os.Stat("./ctx.go")
+	utils.AssertEqual(t, nil, err)
+	size := strconv.FormatInt(info.Size(), 10)
+
 	app := New()
+	app.Get("/download", func(c *Ctx) error {
+		return c.Download("./ctx.go")
+	})
+	app.Get("/stream", func(c *Ctx) error {
+		f, err := os.Open("./ctx.go")
+		if err != nil {
+			return err
+		}
+		c.Attachment("ctx.go")
+		return c.SendStream(f)
+	})
+	app.Get("/partial", func(c *Ctx) error {
+		f, err := os.Open("./ctx.go")
+		if err != nil {
+			return err
+		}
+		if _, err = f.Seek(10, io.SeekStart); err != nil {
+			return err
+		}
+		return c.SendStream(f)
+	})
 
-	// fetch file content
-	f, err := os.Open("./ctx.go")
-	utils.AssertEqual(t, nil, err)
-	defer f.Close()
-	expectFileContent, err := ioutil.ReadAll(f)
+	for _, method := range []string{MethodGet, MethodHead} {
+		for _, path := range []string{"/download", "/stream"} {
+			resp, err := app.Test(httptest.NewRequest(method, path, nil))
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, StatusOK, resp.StatusCode)
+			utils.AssertEqual(t, size, resp.Header.Get(HeaderContentLength), method+" "+path)
+			utils.AssertEqual(t, 0, len(resp.TransferEncoding))
+			utils.AssertEqual(t, `attachment; filename="ctx.go"`, resp.Header.Get(HeaderContentDisposition))
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/partial", nil))
 	utils.AssertEqual(t, nil, err)
-	// fetch file info for the not modified test case
-	fI, err := os.Stat("./ctx.go")
+	utils.AssertEqual(t, strconv.FormatInt(info.Size()-10, 10), resp.Header.Get(HeaderContentLength))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, int(info.Size()-10), len(body))
+}
+
+// go test -run Test_Ctx_Download_NotFound
+func Test_Ctx_Download_NotFound(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		err := c.Download("./.github/does-not-exist.txt", "file.txt")
+		utils.AssertEqual(t, "sendfile: file ./.github/does-not-exist.txt not found", err.Error())
+		utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderContentDisposition)))
+		return err
+	})
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderContentDisposition))
+}
+
+// go test -run Test_Ctx_Download_Root
+func Test_Ctx_Download_Root(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fiber-download")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	utils.AssertEqual(t, nil, ioutil.WriteFile(filepath.Join(dir, "report.txt"), []byte("report"), 0o600))
+
+	app := New(Config{SendFile: SendFile{Root: dir}})
+	app.Get("/", func(c *Ctx) error {
+		return c.Download("report.txt")
+	})
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, `attachment; filename="report.txt"`, resp.Header.Get(HeaderContentDisposition))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "report", string(body))
+
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	err = c.Download("../outside.txt")
+	utils.AssertEqual(t, StatusForbidden, err.(*Error).Code)
+	utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderContentDisposition)))
+}
+
+// go test -race -run Test_Ctx_SendFile
+func Test_Ctx_SendFile(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	// fetch file content
+	f, err := os.Open("./ctx.go")
+	utils.AssertEqual(t, nil, err)
+	defer f.Close()
+	expectFileContent, err := ioutil.ReadAll(f)
+	utils.AssertEqual(t, nil, err)
+	// fetch file info for the not modified test case
+	fI, err := os.Stat("./ctx.go")
 	utils.AssertEqual(t, nil, err)
 
 	// simple test case
@@ -2203,6 +3486,7 @@ func Test_Ctx_SendFile(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, expectFileContent, c.Response().Body())
 	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+	utils.AssertEqual(t, "bytes", string(c.Response().Header.Peek(HeaderAcceptRanges)))
 	app.ReleaseCtx(c)
 
 	// test with custom error code
@@ -2240,6 +3524,596 @@ func Test_Ctx_SendFile_404(t *testing.T) {
 	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
 }
 
+// go test -race -run Test_Ctx_SendFile_Directory
+func Test_Ctx_SendFile_Directory(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	expectFileContent, err := ioutil.ReadFile(filepath.FromSlash("./.github/testdata/fs/index.html"))
+	utils.AssertEqual(t, nil, err)
+
+	// directory with an index file
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	err = c.SendFile(filepath.FromSlash("./.github/testdata/fs"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+	utils.AssertEqual(t, expectFileContent, c.Response().Body())
+	app.ReleaseCtx(c)
+
+	// directory without an index file
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	err = c.SendFile(filepath.FromSlash("./.github/testdata/fs/css"))
+	var fiberErr *Error
+	utils.AssertEqual(t, true, errors.As(err, &fiberErr))
+	utils.AssertEqual(t, StatusNotFound, fiberErr.Code)
+	app.ReleaseCtx(c)
+
+	// custom index names
+	app = New(Config{SendFile: SendFile{IndexNames: []string{"index.htm", "style.css"}}})
+	expectFileContent, err = ioutil.ReadFile(filepath.FromSlash("./.github/testdata/fs/css/style.css"))
+	utils.AssertEqual(t, nil, err)
+
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	err = c.SendFile(filepath.FromSlash("./.github/testdata/fs/css/"))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+	utils.AssertEqual(t, expectFileContent, c.Response().Body())
+	app.ReleaseCtx(c)
+}
+
+// go test -run Test_Ctx_SendFile_Compress
+func Test_Ctx_SendFile_Compress(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	dir, err := ioutil.TempDir("", "fiber-sendfile")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	content := bytes.Repeat([]byte("body { color: #fff; }\n"), 256)
+	css := filepath.Join(dir, "style.css")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(css, content, 0o600))
+	jpg := filepath.Join(dir, "photo.jpg")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(jpg, content, 0o600))
+
+	sendFile := func(file, acceptEncoding string) *fasthttp.Response {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		c.Request().Header.Set(HeaderAcceptEncoding, acceptEncoding)
+		utils.AssertEqual(t, nil, c.SendFile(file, true))
+		resp := &fasthttp.Response{}
+		c.Response().CopyTo(resp)
+		// read the file stream
+		resp.SetBody(c.Response().Body())
+		return resp
+	}
+
+	// compressible type is gzipped on the fly
+	resp := sendFile(css, "gzip")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode())
+	utils.AssertEqual(t, "gzip", string(resp.Header.Peek(HeaderContentEncoding)))
+	utils.AssertEqual(t, HeaderAcceptEncoding, string(resp.Header.Peek(HeaderVary)))
+	body, err := resp.BodyGunzip()
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, content, body)
+
+	// brotli is preferred when accepted
+	resp = sendFile(css, "gzip, br")
+	utils.AssertEqual(t, "br", string(resp.Header.Peek(HeaderContentEncoding)))
+	body, err = resp.BodyUnbrotli()
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, content, body)
+
+	// images are sent as they are
+	resp = sendFile(jpg, "gzip, br")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode())
+	utils.AssertEqual(t, "", string(resp.Header.Peek(HeaderContentEncoding)))
+	utils.AssertEqual(t, "", string(resp.Header.Peek(HeaderVary)))
+	utils.AssertEqual(t, content, resp.Body())
+
+	// identity responses of compressible types don't vary by Accept-Encoding
+	for _, acceptEncoding := range []string{"", "identity"} {
+		resp = sendFile(css, acceptEncoding)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode())
+		utils.AssertEqual(t, "", string(resp.Header.Peek(HeaderContentEncoding)))
+		utils.AssertEqual(t, "", string(resp.Header.Peek(HeaderVary)))
+		utils.AssertEqual(t, content, resp.Body())
+	}
+}
+
+// go test -run Test_Ctx_SendFile_CompressSize
+func Test_Ctx_SendFile_CompressSize(t *testing.T) {
+	t.Parallel()
+	app := New(Config{SendFile: SendFile{CompressMaxSize: 64 * 1024}})
+
+	dir, err := ioutil.TempDir("", "fiber-sendfile")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	small := []byte("body { color: #fff; }\n")
+	tiny := filepath.Join(dir, "tiny.css")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(tiny, small, 0o600))
+	large := bytes.Repeat([]byte("body { color: #fff; }\n"), 8*1024)
+	big := filepath.Join(dir, "large.css")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(big, large, 0o600))
+
+	sendFile := func(file string, header ...string) *fasthttp.Response {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		c.Request().Header.Set(HeaderAcceptEncoding, "gzip")
+		if len(header) > 0 {
+			c.Request().Header.Set(header[0], header[1])
+		}
+		utils.AssertEqual(t, nil, c.SendFile(file, true))
+		resp := &fasthttp.Response{}
+		c.Response().CopyTo(resp)
+		// read the file stream
+		resp.SetBody(c.Response().Body())
+		return resp
+	}
+
+	// tiny files are sent as they are
+	resp := sendFile(tiny)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode())
+	utils.AssertEqual(t, "bytes", string(resp.Header.Peek(HeaderAcceptRanges)))
+	utils.AssertEqual(t, "", string(resp.Header.Peek(HeaderContentEncoding)))
+	utils.AssertEqual(t, small, resp.Body())
+
+	// large files are gzipped while streaming, without a compressed file next to them
+	resp = sendFile(big)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode())
+	utils.AssertEqual(t, "gzip", string(resp.Header.Peek(HeaderContentEncoding)))
+	utils.AssertEqual(t, HeaderAcceptEncoding, string(resp.Header.Peek(HeaderVary)))
+	utils.AssertEqual(t, "text/css; charset=utf-8", string(resp.Header.Peek(HeaderContentType)))
+	utils.AssertEqual(t, "bytes", string(resp.Header.Peek(HeaderAcceptRanges)))
+	body, err := resp.BodyGunzip()
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, large, body)
+	_, err = os.Stat(big + app.config.CompressedFileSuffix)
+	utils.AssertEqual(t, true, os.IsNotExist(err))
+
+	// range requests are answered uncompressed
+	resp = sendFile(big, HeaderRange, "bytes=0-3")
+	utils.AssertEqual(t, StatusPartialContent, resp.StatusCode())
+	utils.AssertEqual(t, "", string(resp.Header.Peek(HeaderContentEncoding)))
+	utils.AssertEqual(t, "body", string(resp.Body()))
+}
+
+// go test -run Test_Ctx_SendFile_ETag
+func Test_Ctx_SendFile_ETag(t *testing.T) {
+	t.Parallel()
+	app := New(Config{ETag: true})
+
+	dir, err := ioutil.TempDir("", "fiber-sendfile")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "style.css")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(file, bytes.Repeat([]byte("body { color: #fff; }\n"), 512), 0o600))
+
+	app.Get("/", func(c *Ctx) error {
+		return c.SendFile(file, true)
+	})
+
+	request := func(encoding, ifNoneMatch string) *http.Response {
+		req := httptest.NewRequest(MethodGet, "/", nil)
+		if encoding != "" {
+			req.Header.Set(HeaderAcceptEncoding, encoding)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set(HeaderIfNoneMatch, ifNoneMatch)
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		return resp
+	}
+
+	resp := request("", "")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderContentEncoding))
+	identityETag := resp.Header.Get(HeaderETag)
+	utils.AssertEqual(t, true, strings.HasPrefix(identityETag, `"`))
+
+	resp = request("gzip", "")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "gzip", resp.Header.Get(HeaderContentEncoding))
+	gzipETag := resp.Header.Get(HeaderETag)
+	utils.AssertEqual(t, true, strings.HasPrefix(gzipETag, `W/"`))
+	utils.AssertEqual(t, true, strings.HasSuffix(gzipETag, `-gzip"`))
+	utils.AssertEqual(t, false, identityETag == gzipETag)
+	utils.AssertEqual(t, false, identityETag == gzipETag[2:])
+
+	// the ETag of each variant only matches its own encoding
+	utils.AssertEqual(t, StatusNotModified, request("", identityETag).StatusCode)
+	utils.AssertEqual(t, StatusNotModified, request("gzip", gzipETag).StatusCode)
+	utils.AssertEqual(t, StatusOK, request("gzip", identityETag).StatusCode)
+	utils.AssertEqual(t, StatusOK, request("", gzipETag).StatusCode)
+}
+
+// go test -run Test_Ctx_SendFile_NotModified
+func Test_Ctx_SendFile_NotModified(t *testing.T) {
+	t.Parallel()
+	app := New(Config{ETag: true})
+
+	dir, err := ioutil.TempDir("", "fiber-sendfile")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "style.css")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(file, bytes.Repeat([]byte("body { color: #fff; }\n"), 512), 0o600))
+	info, err := os.Stat(file)
+	utils.AssertEqual(t, nil, err)
+
+	sendFile := func(file string, header ...string) (*fasthttp.Response, bool) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		for i := 0; i < len(header); i += 2 {
+			c.Request().Header.Set(header[i], header[i+1])
+		}
+		utils.AssertEqual(t, nil, c.SendFile(file, true))
+		resp := &fasthttp.Response{}
+		c.Response().CopyTo(resp)
+		return resp, c.Response().IsBodyStream()
+	}
+
+	// matching ETags of the variant which would be sent
+	resp, stream := sendFile(file, HeaderIfNoneMatch, fileETag(info, ""))
+	utils.AssertEqual(t, StatusNotModified, resp.StatusCode())
+	utils.AssertEqual(t, false, stream)
+	utils.AssertEqual(t, 0, len(resp.Body()))
+	utils.AssertEqual(t, fileETag(info, ""), string(resp.Header.Peek(HeaderETag)))
+	resp, _ = sendFile(file, HeaderIfNoneMatch, fileETag(info, StrGzip), HeaderAcceptEncoding, "gzip, deflate")
+	utils.AssertEqual(t, StatusNotModified, resp.StatusCode())
+	resp, _ = sendFile(file, HeaderIfNoneMatch, fileETag(info, StrBr), HeaderAcceptEncoding, "gzip, br")
+	utils.AssertEqual(t, StatusNotModified, resp.StatusCode())
+	resp, _ = sendFile(file, HeaderIfNoneMatch, fileETag(info, StrGzip), HeaderAcceptEncoding, "gzip", HeaderRange, "bytes=0-3")
+	utils.AssertEqual(t, StatusPartialContent, resp.StatusCode())
+
+	// the variant of another encoding is sent
+	resp, _ = sendFile(file, HeaderIfNoneMatch, fileETag(info, ""), HeaderAcceptEncoding, "gzip")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode())
+
+	// If-Modified-Since is only used without If-None-Match
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+	resp, _ = sendFile(file, HeaderIfModifiedSince, lastModified)
+	utils.AssertEqual(t, StatusNotModified, resp.StatusCode())
+	utils.AssertEqual(t, lastModified, string(resp.Header.Peek(HeaderLastModified)))
+	resp, _ = sendFile(file, HeaderIfModifiedSince, info.ModTime().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	utils.AssertEqual(t, StatusOK, resp.StatusCode())
+	resp, _ = sendFile(file, HeaderIfModifiedSince, lastModified, HeaderIfNoneMatch, `"other"`)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode())
+
+	// the file isn't opened on a 304, reading a named pipe would block without a writer
+	pipe := filepath.Join(dir, "pipe.css")
+	if err := exec.Command("mkfifo", pipe).Run(); err != nil {
+		t.Skip("named pipes are not supported")
+	}
+	info, err = os.Stat(pipe)
+	utils.AssertEqual(t, nil, err)
+	done := make(chan int, 1)
+	go func() {
+		resp, _ := sendFile(pipe, HeaderIfNoneMatch, fileETag(info, ""))
+		done <- resp.StatusCode()
+	}()
+	select {
+	case status := <-done:
+		utils.AssertEqual(t, StatusNotModified, status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the file was opened for a 304 response")
+	}
+}
+
+// go test -run Test_Ctx_SendFile_Range
+func Test_Ctx_SendFile_Range(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	dir, err := ioutil.TempDir("", "fiber-sendfile")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "data.txt")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(file, []byte("0123456789abcdefghij"), 0o600))
+
+	app.Get("/", func(c *Ctx) error {
+		return c.SendFile(file)
+	})
+	request := func(method, rangeHeader string) (*http.Response, string) {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set(HeaderRange, rangeHeader)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return resp, string(body)
+	}
+
+	testCases := []struct {
+		rangeHeader  string
+		contentRange string
+		body         string
+	}{
+		{rangeHeader: "bytes=0-3", contentRange: "bytes 0-3/20", body: "0123"},
+		{rangeHeader: "bytes=10-", contentRange: "bytes 10-19/20", body: "abcdefghij"},
+		{rangeHeader: "bytes=-5", contentRange: "bytes 15-19/20", body: "fghij"},
+		{rangeHeader: "bytes=15-100", contentRange: "bytes 15-19/20", body: "fghij"},
+		// unsatisfiable ranges are skipped
+		{rangeHeader: "bytes=100-200, 2-4", contentRange: "bytes 2-4/20", body: "234"},
+	}
+	for _, tc := range testCases {
+		resp, body := request(MethodGet, tc.rangeHeader)
+		utils.AssertEqual(t, StatusPartialContent, resp.StatusCode, tc.rangeHeader)
+		utils.AssertEqual(t, tc.contentRange, resp.Header.Get(HeaderContentRange), tc.rangeHeader)
+		utils.AssertEqual(t, "bytes", resp.Header.Get(HeaderAcceptRanges), tc.rangeHeader)
+		utils.AssertEqual(t, "text/plain; charset=utf-8", resp.Header.Get(HeaderContentType), tc.rangeHeader)
+		utils.AssertEqual(t, strconv.Itoa(len(tc.body)), resp.Header.Get(HeaderContentLength), tc.rangeHeader)
+		utils.AssertEqual(t, tc.body, body, tc.rangeHeader)
+	}
+
+	// multiple ranges are sent as multipart/byteranges
+	resp, body := request(MethodGet, "bytes=0-1, -2")
+	utils.AssertEqual(t, StatusPartialContent, resp.StatusCode)
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "multipart/byteranges", mediaType)
+	utils.AssertEqual(t, strconv.Itoa(len(body)), resp.Header.Get(HeaderContentLength))
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for _, expected := range []struct{ contentRange, body string }{
+		{contentRange: "bytes 0-1/20", body: "01"},
+		{contentRange: "bytes 18-19/20", body: "ij"},
+	} {
+		part, err := reader.NextPart()
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected.contentRange, part.Header.Get(HeaderContentRange))
+		utils.AssertEqual(t, "text/plain; charset=utf-8", part.Header.Get(HeaderContentType))
+		partBody, err := ioutil.ReadAll(part)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected.body, string(partBody))
+	}
+	_, err = reader.NextPart()
+	utils.AssertEqual(t, io.EOF, err)
+
+	// out of bounds
+	resp, _ = request(MethodGet, "bytes=20-30")
+	utils.AssertEqual(t, StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+	utils.AssertEqual(t, "bytes */20", resp.Header.Get(HeaderContentRange))
+
+	// malformed ranges are ignored
+	for _, rangeHeader := range []string{"bytes=x-1", "bytes=5-2", "items=0-1", "bytes="} {
+		resp, body = request(MethodGet, rangeHeader)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, rangeHeader)
+		utils.AssertEqual(t, "0123456789abcdefghij", body, rangeHeader)
+	}
+
+	// ranges which would amplify the response are ignored
+	for _, rangeHeader := range []string{"bytes=0-,0-", "bytes=0-9,5-15", "bytes=" + strings.Repeat("0-0,", maxRanges+1)} {
+		resp, body = request(MethodGet, rangeHeader)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, rangeHeader)
+		utils.AssertEqual(t, "0123456789abcdefghij", body, rangeHeader)
+	}
+
+	// HEAD requests get the headers only
+	resp, body = request(MethodHead, "bytes=0-3")
+	utils.AssertEqual(t, StatusPartialContent, resp.StatusCode)
+	utils.AssertEqual(t, "bytes 0-3/20", resp.Header.Get(HeaderContentRange))
+	utils.AssertEqual(t, "", body)
+}
+
+// go test -run Test_Ctx_SendFile_IfRange
+func Test_Ctx_SendFile_IfRange(t *testing.T) {
+	t.Parallel()
+	app := New(Config{ETag: true})
+
+	dir, err := ioutil.TempDir("", "fiber-sendfile")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "data.txt")
+	utils.AssertEqual(t, nil, ioutil.WriteFile(file, []byte("0123456789abcdefghij"), 0o600))
+	modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	utils.AssertEqual(t, nil, os.Chtimes(file, modTime, modTime))
+	info, err := os.Stat(file)
+	utils.AssertEqual(t, nil, err)
+
+	app.Get("/", func(c *Ctx) error {
+		return c.SendFile(file)
+	})
+
+	testCases := []struct {
+		ifRange string
+		status  int
+		body    string
+	}{
+		{ifRange: fileETag(info, ""), status: StatusPartialContent, body: "0123"},
+		{ifRange: modTime.Format(http.TimeFormat), status: StatusPartialContent, body: "0123"},
+		// the file was changed since
+		{ifRange: `"stale"`, status: StatusOK, body: "0123456789abcdefghij"},
+		{ifRange: "W/" + fileETag(info, ""), status: StatusOK, body: "0123456789abcdefghij"},
+		{ifRange: modTime.Add(-time.Hour).Format(http.TimeFormat), status: StatusOK, body: "0123456789abcdefghij"},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(MethodGet, "/", nil)
+		req.Header.Set(HeaderRange, "bytes=0-3")
+		req.Header.Set(HeaderIfRange, tc.ifRange)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.status, resp.StatusCode, tc.ifRange)
+		utils.AssertEqual(t, tc.body, string(body), tc.ifRange)
+	}
+}
+
+// go test -run Test_Ctx_SendFileFS
+func Test_Ctx_SendFileFS(t *testing.T) {
+	t.Parallel()
+	app := New(Config{ETag: true})
+
+	fsys := fstest.MapFS{
+		"static/style.css":   {Data: []byte("body{}")},
+		"static/data.txt":    {Data: []byte("0123456789")},
+		"static/index.html":  {Data: []byte("<h1>index</h1>")},
+		"static/empty/.keep": {Data: nil},
+		"stamped.txt":        {Data: []byte("stamped"), ModTime: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	app.Get("/*", func(c *Ctx) error {
+		return c.SendFileFS(fsys, c.Params("*"))
+	})
+	request := func(path string, headers ...string) (*http.Response, string) {
+		req := httptest.NewRequest(MethodGet, path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return resp, string(body)
+	}
+
+	resp, body := request("/static/style.css")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "text/css; charset=utf-8", resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "body{}", body)
+	etag := resp.Header.Get(HeaderETag)
+	utils.AssertEqual(t, true, etag != "")
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderLastModified))
+
+	// the body ETag validates files without modification time
+	resp, _ = request("/static/style.css", HeaderIfNoneMatch, etag)
+	utils.AssertEqual(t, StatusNotModified, resp.StatusCode)
+
+	// directories serve their index file
+	resp, body = request("/static")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, MIMETextHTMLCharsetUTF8, resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "<h1>index</h1>", body)
+
+	resp, _ = request("/static/empty")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+	resp, _ = request("/static/missing.txt")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+
+	resp, body = request("/static/data.txt", HeaderRange, "bytes=2-4")
+	utils.AssertEqual(t, StatusPartialContent, resp.StatusCode)
+	utils.AssertEqual(t, "bytes 2-4/10", resp.Header.Get(HeaderContentRange))
+	utils.AssertEqual(t, "234", body)
+	resp, _ = request("/static/data.txt", HeaderRange, "bytes=20-30")
+	utils.AssertEqual(t, StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+	utils.AssertEqual(t, "bytes */10", resp.Header.Get(HeaderContentRange))
+
+	// files with modification time are streamed and validated by their stat
+	resp, body = request("/stamped.txt")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "stamped", body)
+	utils.AssertEqual(t, "Sun, 02 Jan 2022 03:04:05 GMT", resp.Header.Get(HeaderLastModified))
+	resp, _ = request("/stamped.txt", HeaderIfModifiedSince, "Sun, 02 Jan 2022 03:04:05 GMT")
+	utils.AssertEqual(t, StatusNotModified, resp.StatusCode)
+
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	err := c.SendFileFS(fsys, "../secret")
+	utils.AssertEqual(t, StatusForbidden, err.(*Error).Code)
+}
+
+// go test -run Test_Ctx_SendFile_Root
+func Test_Ctx_SendFile_Root(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fiber-sendfile")
+	utils.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "public")
+	utils.AssertEqual(t, nil, os.MkdirAll(filepath.Join(root, "docs"), 0o700))
+	utils.AssertEqual(t, nil, ioutil.WriteFile(filepath.Join(root, "docs", "index.html"), []byte("docs"), 0o600))
+	utils.AssertEqual(t, nil, ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o600))
+
+	app := New(Config{SendFile: SendFile{Root: root}})
+	app.Get("/abs/secret", func(c *Ctx) error {
+		return c.SendFile(filepath.Join(dir, "secret.txt"))
+	})
+	app.Get("/*", func(c *Ctx) error {
+		return c.SendFile(c.Params("*"))
+	})
+
+	request := func(target string) (int, string) {
+		req := httptest.NewRequest(MethodGet, "/", nil)
+		// keep the dot segments of the path
+		req.URL.Path = target
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// relative paths are resolved against the root
+	status, body := request("/docs/index.html")
+	utils.AssertEqual(t, StatusOK, status)
+	utils.AssertEqual(t, "docs", body)
+	status, body = request("/docs")
+	utils.AssertEqual(t, StatusOK, status)
+	utils.AssertEqual(t, "docs", body)
+
+	// traversal attempts are rejected
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	err = c.SendFile("../secret.txt")
+	utils.AssertEqual(t, StatusForbidden, err.(*Error).Code)
+	err = c.SendFile("docs/../../secret.txt")
+	utils.AssertEqual(t, StatusForbidden, err.(*Error).Code)
+	status, _ = request("/abs/secret")
+	utils.AssertEqual(t, StatusForbidden, status)
+
+	// missing files inside the root are not found
+	status, _ = request("/missing.txt")
+	utils.AssertEqual(t, StatusNotFound, status)
+
+	// symlinks escaping the root are rejected
+	if err = os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	status, body = request("/link.txt")
+	utils.AssertEqual(t, StatusForbidden, status)
+	utils.AssertEqual(t, false, body == "secret")
+	utils.AssertEqual(t, nil, os.Symlink(dir, filepath.Join(root, "parent")))
+	status, _ = request("/parent/secret.txt")
+	utils.AssertEqual(t, StatusForbidden, status)
+}
+
+// go test -run Test_Ctx_SendFile_Immutable_Caching
+func Test_Ctx_SendFile_Immutable_Caching(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		ETag:     true,
+		SendFile: SendFile{Immutable: true},
+	})
+	app.Get("/app.abc123.js", func(c *Ctx) error {
+		return c.SendFile("./.github/testdata/index.tmpl")
+	})
+
+	request := func(header, value string) *http.Response {
+		req := httptest.NewRequest(MethodGet, "/app.abc123.js", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		return resp
+	}
+
+	resp := request("", "")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "public, max-age=31536000, immutable", resp.Header.Get(HeaderCacheControl))
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderETag))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "<h1>{{.Title}}</h1>", string(body))
+
+	// conditional requests are answered with the file
+	resp = request(HeaderIfModifiedSince, time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderETag))
+	resp = request(HeaderIfNoneMatch, "*")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "public, max-age=31536000, immutable", resp.Header.Get(HeaderCacheControl))
+}
+
 // go test -race -run Test_Ctx_SendFile_Immutable
 func Test_Ctx_SendFile_Immutable(t *testing.T) {
 	t.Parallel()
@@ -2331,6 +4205,30 @@ func Test_Ctx_JSON(t *testing.T) {
 	testEmpty([]int{}, "[]")
 }
 
+// go test -run Test_Ctx_JSON_EscapeHTML
+func Test_Ctx_JSON_EscapeHTML(t *testing.T) {
+	t.Parallel()
+	data := Map{"html": "<b>Tom & Jerry</b>"}
+
+	// HTML characters are escaped by default
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, nil, c.JSON(data))
+	utils.AssertEqual(t, `{"html":"\u003cb\u003eTom \u0026 Jerry\u003c/b\u003e"}`, string(c.Response().Body()))
+	utils.AssertEqual(t, nil, c.JSONStream(data))
+	utils.AssertEqual(t, `{"html":"\u003cb\u003eTom \u0026 Jerry\u003c/b\u003e"}`+"\n", string(c.Response().Body()))
+
+	app = New(Config{DisableJSONEscapeHTML: true})
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, nil, c.JSON(data))
+	utils.AssertEqual(t, `{"html":"<b>Tom & Jerry</b>"}`, string(c.Response().Body()))
+	utils.AssertEqual(t, nil, c.JSONStream(data))
+	utils.AssertEqual(t, `{"html":"<b>Tom & Jerry</b>"}`+"\n", string(c.Response().Body()))
+	utils.AssertEqual(t, "json: unsupported type: chan int", c.JSON(make(chan int)).Error())
+}
+
 // go test -run=^$ -bench=Benchmark_Ctx_JSON -benchmem -count=4
 func Benchmark_Ctx_JSON(b *testing.B) {
 	app := New()
@@ -2354,35 +4252,163 @@ func Benchmark_Ctx_JSON(b *testing.B) {
 	utils.AssertEqual(b, `{"Name":"Grame","Age":20}`, string(c.Response().Body()))
 }
 
-// go test -run Test_Ctx_JSONP
-func Test_Ctx_JSONP(t *testing.T) {
+// go test -run Test_Ctx_JSON_MarshalError
+func Test_Ctx_JSON_MarshalError(t *testing.T) {
+	t.Parallel()
+	var handlerErr error
+	errorHandler := func(c *Ctx, err error) error {
+		handlerErr = err
+		utils.AssertEqual(t, 0, len(c.Response().Body()))
+		utils.AssertEqual(t, false, strings.HasPrefix(string(c.Response().Header.ContentType()), MIMEApplicationJSON))
+		return c.Status(StatusInternalServerError).SendString("encoding failed")
+	}
+
+	testMarshalError := func(app *App, data interface{}) {
+		app.Get("/", func(c *Ctx) error {
+			return c.JSON(data)
+		})
+		resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "encoding failed", string(body))
+	}
+
+	// unsupported type
+	testMarshalError(New(Config{ErrorHandler: errorHandler}), make(chan int))
+	var unsupported *json.UnsupportedTypeError
+	utils.AssertEqual(t, true, errors.As(handlerErr, &unsupported))
+
+	// encoder fails after producing partial output
+	encodeErr := errors.New("encode error")
+	testMarshalError(New(Config{
+		ErrorHandler: errorHandler,
+		JSONEncoder: func(v interface{}) ([]byte, error) {
+			return []byte(`{"name":`), encodeErr
+		},
+	}), Map{"name": "john"})
+	utils.AssertEqual(t, encodeErr, handlerErr)
+}
+
+// go test -run Test_Ctx_JSONStream
+func Test_Ctx_JSONStream(t *testing.T) {
 	t.Parallel()
 	app := New()
 	c := app.AcquireCtx(&fasthttp.RequestCtx{})
 	defer app.ReleaseCtx(c)
 
-	utils.AssertEqual(t, true, c.JSONP(complex(1, 1)) != nil)
+	utils.AssertEqual(t, nil, c.JSONStream(Map{"name": "john", "age": 20}))
+	utils.AssertEqual(t, `{"age":20,"name":"john"}`+"\n", string(c.Response().Body()))
+	utils.AssertEqual(t, MIMEApplicationJSON, string(c.Response().Header.ContentType()))
 
-	c.JSONP(Map{
-		"Name": "Grame",
-		"Age":  20,
+	// custom content type
+	utils.AssertEqual(t, nil, c.JSONStream([]string{"a", "b"}, "application/problem+json"))
+	utils.AssertEqual(t, `["a","b"]`+"\n", string(c.Response().Body()))
+	utils.AssertEqual(t, "application/problem+json", string(c.Response().Header.ContentType()))
+
+	// custom encoder
+	app = New(Config{JSONStreamEncoder: func(w io.Writer, v interface{}) error {
+		_, err := fmt.Fprintf(w, `{"custom":%q}`, v)
+		return err
+	}})
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, nil, c.JSONStream("john"))
+	utils.AssertEqual(t, `{"custom":"john"}`, string(c.Response().Body()))
+}
+
+// go test -run Test_Ctx_JSONStream_EncodeError
+func Test_Ctx_JSONStream_EncodeError(t *testing.T) {
+	t.Parallel()
+	encodeErr := errors.New("encode error")
+	errorHandlerCalled := false
+	app := New(Config{
+		// the encoder fails after writing partial output
+		JSONStreamEncoder: func(w io.Writer, v interface{}) error {
+			_, _ = w.Write([]byte(`{"name":`))
+			return encodeErr
+		},
+		ErrorHandler: func(c *Ctx, err error) error {
+			errorHandlerCalled = true
+			return DefaultErrorHandler(c, err)
+		},
 	})
-	utils.AssertEqual(t, `callback({"Age":20,"Name":"Grame"});`, string(c.Response().Body()))
-	utils.AssertEqual(t, "application/javascript; charset=utf-8", string(c.Response().Header.Peek("content-type")))
+	app.Get("/", func(c *Ctx) error {
+		return c.JSONStream(Map{"name": "john"})
+	})
+	ln, err := app.NewTestListener()
+	utils.AssertEqual(t, nil, err)
+	defer ln.Close()
 
-	c.JSONP(Map{
-		"Name": "Grame",
-		"Age":  20,
-	}, "john")
-	utils.AssertEqual(t, `john({"Age":20,"Name":"Grame"});`, string(c.Response().Body()))
-	utils.AssertEqual(t, "application/javascript; charset=utf-8", string(c.Response().Header.Peek("content-type")))
+	// the status has been sent, the client notices the aborted body
+	resp, err := ln.Client().Get("http://example.com/")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, MIMEApplicationJSON, resp.Header.Get(HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, io.ErrUnexpectedEOF, err)
+	utils.AssertEqual(t, `{"name":`, string(body))
+	utils.AssertEqual(t, false, errorHandlerCalled)
 }
 
-// go test -v  -run=^$ -bench=Benchmark_Ctx_JSONP -benchmem -count=4
-func Benchmark_Ctx_JSONP(b *testing.B) {
+// go test -run Test_Ctx_JSONStream_Streamed
+func Test_Ctx_JSONStream_Streamed(t *testing.T) {
+	t.Parallel()
 	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
+	encoded := make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		if err := c.JSONStream(Map{"name": "john"}); err != nil {
+			return err
+		}
+		// the value is encoded while the body is sent, not into the body buffer
+		utils.AssertEqual(t, true, c.Response().IsBodyStream())
+		c.OnBodySent(func(n int) {
+			utils.AssertEqual(t, len(`{"name":"john"}`+"\n"), n)
+			close(encoded)
+		})
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderContentLength))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"name":"john"}`+"\n", string(body))
+	<-encoded
+}
+
+// go test -run Test_Ctx_JSONP
+func Test_Ctx_JSONP(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	utils.AssertEqual(t, true, c.JSONP(complex(1, 1)) != nil)
+
+	c.JSONP(Map{
+		"Name": "Grame",
+		"Age":  20,
+	})
+	utils.AssertEqual(t, `callback({"Age":20,"Name":"Grame"});`, string(c.Response().Body()))
+	utils.AssertEqual(t, "application/javascript; charset=utf-8", string(c.Response().Header.Peek("content-type")))
+
+	c.JSONP(Map{
+		"Name": "Grame",
+		"Age":  20,
+	}, "john")
+	utils.AssertEqual(t, `john({"Age":20,"Name":"Grame"});`, string(c.Response().Body()))
+	utils.AssertEqual(t, "application/javascript; charset=utf-8", string(c.Response().Header.Peek("content-type")))
+}
+
+// go test -v  -run=^$ -bench=Benchmark_Ctx_JSONP -benchmem -count=4
+func Benchmark_Ctx_JSONP(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
 	type SomeStruct struct {
 		Name string
 		Age  uint8
@@ -2478,6 +4504,39 @@ func Test_Ctx_Links(t *testing.T) {
 	utils.AssertEqual(t, `<http://api.example.com/users?page=2>; rel="next",<http://api.example.com/users?page=5>; rel="last"`, string(c.Response().Header.Peek(HeaderLink)))
 }
 
+// go test -run Test_Ctx_Push
+func Test_Ctx_Push(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	utils.AssertEqual(t, nil, c.Push("/app.css", PushOption{As: "style"}))
+	utils.AssertEqual(t, "</app.css>; rel=preload; as=style", string(c.Response().Header.Peek(HeaderLink)))
+	utils.AssertEqual(t, nil, c.Push("/app.js"))
+	utils.AssertEqual(t, nil, c.Push("/font.woff2", PushOption{As: "font", CrossOrigin: "anonymous", NoPush: true}))
+	utils.AssertEqual(t, "</app.css>; rel=preload; as=style, </app.js>; rel=preload, </font.woff2>; rel=preload; as=font; crossorigin=anonymous; nopush",
+		string(c.Response().Header.Peek(HeaderLink)))
+
+	for _, path := range []string{"", "app.css", "http://example.com/app.css", "/app.css>; rel=next", "/app.css\r\nX-Injected: 1"} {
+		utils.AssertEqual(t, ErrPushInvalidPath, c.Push(path))
+	}
+	for _, opt := range []PushOption{
+		{As: "style, </evil.js>; rel=preload"},
+		{As: "style; rel=next"},
+		{As: "style\r\nX-Injected: 1"},
+		{As: `"style"`},
+		{CrossOrigin: "anonymous; as=script"},
+		{CrossOrigin: "use credentials"},
+		{CrossOrigin: "anonymous\nX-Injected: 1"},
+	} {
+		utils.AssertEqual(t, ErrPushInvalidOption, c.Push("/app.css", opt))
+	}
+	utils.AssertEqual(t, "", string(c.Response().Header.Peek("X-Injected")))
+	utils.AssertEqual(t, "</app.css>; rel=preload; as=style, </app.js>; rel=preload, </font.woff2>; rel=preload; as=font; crossorigin=anonymous; nopush",
+		string(c.Response().Header.Peek(HeaderLink)))
+}
+
 // go test -v  -run=^$ -bench=Benchmark_Ctx_Links -benchmem -count=4
 func Benchmark_Ctx_Links(b *testing.B) {
 	app := New()
@@ -2849,6 +4908,36 @@ func Test_Ctx_RenderWithLocalsAndBinding(t *testing.T) {
 	utils.AssertEqual(t, "<h1>Hello, World!</h1>", string(c.Response().Body()))
 }
 
+// go test -run Test_Ctx_RenderWithViewGlobals
+func Test_Ctx_RenderWithViewGlobals(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.SetViewGlobal("Title", "Fiber")
+	app.SetViewGlobal("Summary", "v1.2.3")
+
+	// the globals are available in every template
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	err := c.Render("./.github/testdata/template.tmpl", Map{})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "<h1>Fiber v1.2.3</h1>", string(c.Response().Body()))
+	app.ReleaseCtx(c)
+
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	err = c.Render("./.github/testdata/index.tmpl", nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "<h1>Fiber</h1>", string(c.Response().Body()))
+	app.ReleaseCtx(c)
+
+	// the data of the call takes precedence
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	err = c.Render("./.github/testdata/template.tmpl", Map{
+		"Title": "Hello, World!",
+	})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "<h1>Hello, World! v1.2.3</h1>", string(c.Response().Body()))
+}
+
 func Benchmark_Ctx_RenderWithLocalsAndBinding(b *testing.B) {
 	engine := &testTemplateEngine{}
 	err := engine.Load()
@@ -3148,158 +5237,718 @@ func Test_Ctx_Get_Location_From_Route_name(t *testing.T) {
 
 		location, err := c.GetRouteURL("User", Map{"name": "fiber"})
 		utils.AssertEqual(t, nil, err)
-		utils.AssertEqual(t, "/user/fiber", location)
+		utils.AssertEqual(t, "/user/fiber", location)
+
+		location, err = c.GetRouteURL("User", Map{"Name": "fiber"})
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "/user/", location)
+	})
+}
+
+// go test -run Test_Ctx_Get_Location_From_Route_name_Optional_greedy
+func Test_Ctx_Get_Location_From_Route_name_Optional_greedy(t *testing.T) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	app.Get("/:phone/*/send/*", func(c *Ctx) error {
+		return c.SendString("Phone: " + c.Params("phone") + "\nFirst Param: " + c.Params("*1") + "\nSecond Param: " + c.Params("*2"))
+	}).Name("SendSms")
+
+	location, err := c.GetRouteURL("SendSms", Map{
+		"phone": "23456789",
+		"*1":    "sms",
+		"*2":    "test-msg",
+	})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/23456789/sms/send/test-msg", location)
+}
+
+// go test -run Test_Ctx_Get_Location_From_Route_name_Optional_greedy_one_param
+func Test_Ctx_Get_Location_From_Route_name_Optional_greedy_one_param(t *testing.T) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	app.Get("/:phone/*/send", func(c *Ctx) error {
+		return c.SendString("Phone: " + c.Params("phone") + "\nFirst Param: " + c.Params("*1"))
+	}).Name("SendSms")
+
+	location, err := c.GetRouteURL("SendSms", Map{
+		"phone": "23456789",
+		"*":     "sms",
+	})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/23456789/sms/send", location)
+}
+
+// go test -run Test_Ctx_GetRouteURL_Params_RoundTrip
+func Test_Ctx_GetRouteURL_Params_RoundTrip(t *testing.T) {
+	t.Parallel()
+	paths := []string{
+		"/user/john%20doe/files/docs/a%20b.txt",
+		"/user/a%2Fb/files/x/y%2Fz",
+		"/user/%C3%A9t%C3%A9/files/%E6%8A%A5%E5%91%8A",
+		"/user/a%3Fb%23c/files/a%25b",
+	}
+	for _, unescape := range []bool{false, true} {
+		app := New(Config{UnescapePath: unescape})
+		app.Get("/user/:name/files/*", func(c *Ctx) error {
+			location, err := c.GetRouteURL("files", Map{"name": c.Params("name"), "*": c.Params("*")})
+			if err != nil {
+				return err
+			}
+			return c.SendString(location)
+		}).Name("files")
+
+		for _, path := range paths {
+			if unescape && strings.Contains(path, "%2F") {
+				// encoded slashes are decoded before routing and can't be part of a single segment
+				continue
+			}
+			resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, StatusOK, resp.StatusCode, path)
+			body, err := ioutil.ReadAll(resp.Body)
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, path, string(body), path)
+		}
+	}
+}
+
+type errorTemplateEngine struct{}
+
+func (t errorTemplateEngine) Render(w io.Writer, name string, bind interface{}, layout ...string) error {
+	return errors.New("errorTemplateEngine")
+}
+
+func (t errorTemplateEngine) Load() error { return nil }
+
+// go test -run Test_Ctx_Render_Engine_Error
+func Test_Ctx_Render_Engine_Error(t *testing.T) {
+	app := New()
+	app.config.Views = errorTemplateEngine{}
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	err := c.Render("index.tmpl", nil)
+	utils.AssertEqual(t, false, err == nil)
+}
+
+// go test -run Test_Ctx_Render_Go_Template
+func Test_Ctx_Render_Go_Template(t *testing.T) {
+	t.Parallel()
+
+	file, err := ioutil.TempFile(os.TempDir(), "fiber")
+	utils.AssertEqual(t, nil, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.Write([]byte("template"))
+	utils.AssertEqual(t, nil, err)
+
+	err = file.Close()
+	utils.AssertEqual(t, nil, err)
+
+	app := New()
+
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	err = c.Render(file.Name(), nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "template", string(c.Response().Body()))
+}
+
+// go test -run Test_Ctx_Send
+func Test_Ctx_Send(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Send([]byte("Hello, World"))
+	c.Send([]byte("Don't crash please"))
+	c.Send([]byte("1337"))
+	utils.AssertEqual(t, "1337", string(c.Response().Body()))
+}
+
+// go test -v  -run=^$ -bench=Benchmark_Ctx_Send -benchmem -count=4
+func Benchmark_Ctx_Send(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	byt := []byte("Hello, World!")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.Send(byt)
+	}
+	utils.AssertEqual(b, "Hello, World!", string(c.Response().Body()))
+}
+
+// go test -run Test_Ctx_SendStatus
+func Test_Ctx_SendStatus(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.SendStatus(415)
+	utils.AssertEqual(t, 415, c.Response().StatusCode())
+	utils.AssertEqual(t, "Unsupported Media Type", string(c.Response().Body()))
+}
+
+// go test -run Test_Ctx_SendError
+func Test_Ctx_SendError(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	// the status alone keeps the body
+	utils.AssertEqual(t, nil, c.JSON(Map{"ok": true}))
+	c.Status(StatusInternalServerError)
+	utils.AssertEqual(t, `{"ok":true}`, string(c.Response().Body()))
+
+	utils.AssertEqual(t, nil, c.SendError(StatusInternalServerError))
+	utils.AssertEqual(t, StatusInternalServerError, c.Response().StatusCode())
+	utils.AssertEqual(t, "Internal Server Error", string(c.Response().Body()))
+	utils.AssertEqual(t, MIMETextPlainCharsetUTF8, string(c.Response().Header.ContentType()))
+
+	// the headers of the discarded body are removed
+	c.Attachment("export.csv.gz")
+	c.Set(HeaderContentEncoding, "gzip")
+	c.Set(HeaderETag, `"abc"`)
+	c.Vary(HeaderAcceptEncoding)
+	utils.AssertEqual(t, nil, c.SendStream(strings.NewReader("partial"), 7))
+	utils.AssertEqual(t, true, c.Response().IsBodyStream())
+	utils.AssertEqual(t, nil, c.SendError(StatusBadGateway, "upstream failed"))
+	utils.AssertEqual(t, "", c.GetRespHeader(HeaderContentDisposition))
+	utils.AssertEqual(t, "", c.GetRespHeader(HeaderContentEncoding))
+	utils.AssertEqual(t, "", c.GetRespHeader(HeaderETag))
+	utils.AssertEqual(t, "", c.GetRespHeader(HeaderVary))
+	utils.AssertEqual(t, false, c.Response().IsBodyStream())
+	utils.AssertEqual(t, StatusBadGateway, c.Response().StatusCode())
+	utils.AssertEqual(t, "upstream failed", string(c.Response().Body()))
+	utils.AssertEqual(t, 15, c.BytesWritten())
+}
+
+// go test -race -run Test_Ctx_SendError_StreamWriter
+func Test_Ctx_SendError_StreamWriter(t *testing.T) {
+	t.Parallel()
+	app := New()
+	done := make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		_ = c.SendStreamWriter(func(ctx context.Context, w *bufio.Writer) {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				_, _ = w.WriteString("partial")
+				_ = w.Flush()
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		})
+		return c.SendError(StatusBadGateway, "upstream failed")
+	})
+	app.Get("/other", func(c *Ctx) error {
+		return c.SendString(c.Path())
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusBadGateway, resp.StatusCode)
+	utils.AssertEqual(t, "upstream failed", string(body))
+
+	// the Ctx of the stream writer isn't reused while the writer is running
+	for i := 0; i < 10; i++ {
+		resp, err = app.Test(httptest.NewRequest(MethodGet, "/other", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream writer wasn't stopped")
+	}
+}
+
+// go test -run Test_Ctx_SendInformational
+func Test_Ctx_SendInformational(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		if err := c.SendInformational(StatusProcessing, map[string]string{"X-Progress": "50"}); err != nil {
+			return err
+		}
+		return c.Status(StatusMultiStatus).SendString("done")
+	})
+	app.Get("/invalid", func(c *Ctx) error {
+		utils.AssertEqual(t, "informational: invalid status code 101", c.SendInformational(StatusSwitchingProtocols, nil).Error())
+		utils.AssertEqual(t, "informational: invalid status code 200", c.SendInformational(StatusOK, nil).Error())
+		utils.AssertEqual(t, `informational: invalid header "X-Bad"`, c.SendInformational(StatusProcessing, map[string]string{"X-Bad": "a\r\nb"}).Error())
+		return c.SendStatus(StatusOK)
+	})
+
+	ln, err := app.NewTestListener()
+	utils.AssertEqual(t, nil, err)
+	defer ln.Close()
+
+	var informational []int
+	var progress string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			informational = append(informational, code)
+			progress = header.Get("X-Progress")
+			return nil
+		},
+	}
+	req, err := http.NewRequest(MethodGet, "http://localhost/", nil)
+	utils.AssertEqual(t, nil, err)
+	resp, err := ln.Client().Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, []int{StatusProcessing}, informational)
+	utils.AssertEqual(t, "50", progress)
+	utils.AssertEqual(t, StatusMultiStatus, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "done", string(body))
+	utils.AssertEqual(t, nil, resp.Body.Close())
+
+	// app.Test returns the final response
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMultiStatus, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/invalid", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	// not sent to HTTP/1.0 clients
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.SetProtocol("HTTP/1.0")
+	utils.AssertEqual(t, nil, c.SendInformational(StatusProcessing, nil))
+}
+
+// go test -run Test_Ctx_SendInformational_Pipelined
+func Test_Ctx_SendInformational_Pipelined(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		if err := c.SendInformational(StatusProcessing, nil); err != nil {
+			return c.Status(StatusMultiStatus).SendString(err.Error())
+		}
+		return c.SendString("sent")
+	})
+
+	ln, err := app.NewTestListener()
+	utils.AssertEqual(t, nil, err)
+	defer ln.Close()
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+
+	// the response of the first request is still buffered when the second one is handled
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\nGET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+	br := bufio.NewReader(conn)
+	var responses []string
+	for len(responses) < 3 {
+		resp, err := http.ReadResponse(br, nil)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		responses = append(responses, strconv.Itoa(resp.StatusCode)+" "+string(body))
+	}
+	utils.AssertEqual(t, []string{"102 ", "200 sent", "207 " + ErrInformationalReusedConn.Error()}, responses)
+}
+
+// go test -run Test_Ctx_SendString
+func Test_Ctx_SendString(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.SendString("Don't crash please")
+	utils.AssertEqual(t, "Don't crash please", string(c.Response().Body()))
+}
+
+// go test -run Test_Ctx_SendStream
+func Test_Ctx_SendStream(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	c.SendStream(bytes.NewReader([]byte("Don't crash please")))
+	utils.AssertEqual(t, "Don't crash please", string(c.Response().Body()))
+
+	c.SendStream(bytes.NewReader([]byte("Don't crash please")), len([]byte("Don't crash please")))
+	utils.AssertEqual(t, "Don't crash please", string(c.Response().Body()))
+
+	c.SendStream(bufio.NewReader(bytes.NewReader([]byte("Hello bufio"))))
+	utils.AssertEqual(t, "Hello bufio", string(c.Response().Body()))
+
+	file, err := os.Open("./.github/index.html")
+	utils.AssertEqual(t, nil, err)
+	c.SendStream(bufio.NewReader(file))
+	utils.AssertEqual(t, true, c.Response().Header.ContentLength() > 200)
+}
+
+// go test -run Test_Ctx_SendStreamWriter
+func Test_Ctx_SendStreamWriter(t *testing.T) {
+	t.Parallel()
+	app := New(Config{DisableStartupMessage: true})
+
+	cancelled := make(chan error, 1)
+	app.Get("/", func(c *Ctx) error {
+		return c.SendStreamWriter(func(ctx context.Context, w *bufio.Writer) {
+			for {
+				select {
+				case <-ctx.Done():
+					cancelled <- ctx.Err()
+					return
+				case <-time.After(10 * time.Millisecond):
+					_, _ = w.WriteString("data: ping\n\n")
+					_ = w.Flush()
+				}
+			}
+		})
+	})
+
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		utils.AssertEqual(t, nil, app.Listener(ln))
+	}()
+	defer func() {
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
+
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+
+	// wait for the stream to start, then disconnect mid-stream
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.HasPrefix(string(buf[:n]), "HTTP/1.1 200 OK"))
+	utils.AssertEqual(t, nil, conn.Close())
+
+	select {
+	case err = <-cancelled:
+		utils.AssertEqual(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("stream context was not cancelled after the client disconnected")
+	}
+}
+
+// go test -run Test_Ctx_BytesWritten
+func Test_Ctx_BytesWritten(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	t.Run("buffered", func(t *testing.T) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		utils.AssertEqual(t, 0, c.BytesWritten())
+		utils.AssertEqual(t, nil, c.SendString("Hello, World!"))
+		utils.AssertEqual(t, 13, c.BytesWritten())
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		utils.AssertEqual(t, nil, c.SendStream(bytes.NewReader(bytes.Repeat([]byte("a"), 10000)), 10000))
+		// nothing is sent before the handler returned
+		utils.AssertEqual(t, 0, c.BytesWritten())
+		utils.AssertEqual(t, nil, c.Response().Write(bufio.NewWriter(ioutil.Discard)))
+		utils.AssertEqual(t, 10000, c.BytesWritten())
+	})
+
+	t.Run("file", func(t *testing.T) {
+		info, err := os.Stat("./ctx.go")
+		utils.AssertEqual(t, nil, err)
+		app := New()
+		app.Get("/stream", func(c *Ctx) error {
+			f, err := os.Open("./ctx.go")
+			if err != nil {
+				return err
+			}
+			if err = c.SendStream(f); err != nil {
+				return err
+			}
+			// the file isn't counted, so that it can be sent with sendfile
+			c.Set("X-Bytes-Written", strconv.Itoa(c.BytesWritten()))
+			return nil
+		})
+		app.Get("/sendfile", func(c *Ctx) error {
+			if err := c.SendFile("./ctx.go"); err != nil {
+				return err
+			}
+			c.Set("X-Bytes-Written", strconv.Itoa(c.BytesWritten()))
+			return nil
+		})
+		resp, err := app.Test(httptest.NewRequest(MethodGet, "/stream", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+		utils.AssertEqual(t, strconv.FormatInt(info.Size(), 10), resp.Header.Get("X-Bytes-Written"))
+
+		resp, err = app.Test(httptest.NewRequest(MethodGet, "/sendfile", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+		utils.AssertEqual(t, strconv.FormatInt(info.Size(), 10), resp.Header.Get("X-Bytes-Written"))
+	})
 
-		location, err = c.GetRouteURL("User", Map{"Name": "fiber"})
+	t.Run("stream writer", func(t *testing.T) {
+		written := make(chan int, 2)
+		app := New()
+		app.Get("/", func(c *Ctx) error {
+			return c.SendStreamWriter(func(_ context.Context, w *bufio.Writer) {
+				_, _ = w.WriteString("Hello, ")
+				_ = w.Flush()
+				written <- c.BytesWritten()
+				_, _ = w.WriteString("World!")
+				_ = w.Flush()
+				written <- c.BytesWritten()
+			})
+		})
+		resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
 		utils.AssertEqual(t, nil, err)
-		utils.AssertEqual(t, "/user/", location)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "Hello, World!", string(body))
+		utils.AssertEqual(t, 7, <-written)
+		utils.AssertEqual(t, 13, <-written)
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		sent := make(chan int, 1)
+		app := New()
+		app.Use(func(c *Ctx) error {
+			err := c.Next()
+			c.OnBodySent(func(n int) {
+				sent <- n
+			})
+			return err
+		})
+		app.Get("/buffered", func(c *Ctx) error {
+			return c.SendString("Hello, World!")
+		})
+		app.Get("/stream", func(c *Ctx) error {
+			return c.SendStream(strings.NewReader(strings.Repeat("a", 10000)))
+		})
+		app.Get("/writer", func(c *Ctx) error {
+			return c.SendStreamWriter(func(_ context.Context, w *bufio.Writer) {
+				for i := 0; i < 100; i++ {
+					_, _ = w.WriteString(strings.Repeat("b", 100))
+					_ = w.Flush()
+				}
+			})
+		})
+		// the final count is passed once the body has been sent
+		for path, size := range map[string]int{"/buffered": 13, "/stream": 10000, "/writer": 10000} {
+			resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+			utils.AssertEqual(t, nil, err)
+			body, err := ioutil.ReadAll(resp.Body)
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, size, len(body), path)
+			select {
+			case n := <-sent:
+				utils.AssertEqual(t, size, n, path)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: callback was not called", path)
+			}
+		}
 	})
 }
 
-// go test -run Test_Ctx_Get_Location_From_Route_name_Optional_greedy
-func Test_Ctx_Get_Location_From_Route_name_Optional_greedy(t *testing.T) {
+// go test -run Test_Ctx_SendSSE
+func Test_Ctx_SendSSE(t *testing.T) {
+	t.Parallel()
 	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	app.Get("/:phone/*/send/*", func(c *Ctx) error {
-		return c.SendString("Phone: " + c.Params("phone") + "\nFirst Param: " + c.Params("*1") + "\nSecond Param: " + c.Params("*2"))
-	}).Name("SendSms")
 
-	location, err := c.GetRouteURL("SendSms", Map{
-		"phone": "23456789",
-		"*1":    "sms",
-		"*2":    "test-msg",
+	app.Get("/", func(c *Ctx) error {
+		ch := make(chan SSEvent)
+		go func() {
+			defer close(ch)
+			ch <- SSEvent{Data: "hello"}
+			ch <- SSEvent{ID: "2", Event: "update", Data: "line 1\nline 2", Retry: 3 * time.Second}
+			// line breaks can't inject other fields or events
+			ch <- SSEvent{ID: "3\r\nretry: 1", Event: "a\rdata: b", Data: "c\r\nd\re\n\nid: 4"}
+		}()
+		return c.SendSSE(ch)
 	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "text/event-stream", resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "no-cache", resp.Header.Get(HeaderCacheControl))
+	body, err := ioutil.ReadAll(resp.Body)
 	utils.AssertEqual(t, nil, err)
-	utils.AssertEqual(t, "/23456789/sms/send/test-msg", location)
+	utils.AssertEqual(t, "data: hello\n\nid: 2\nevent: update\nretry: 3000\ndata: line 1\ndata: line 2\n\n"+
+		"id: 3retry: 1\nevent: adata: b\ndata: c\ndata: d\ndata: e\ndata: \ndata: id: 4\n\n", string(body))
 }
 
-// go test -run Test_Ctx_Get_Location_From_Route_name_Optional_greedy_one_param
-func Test_Ctx_Get_Location_From_Route_name_Optional_greedy_one_param(t *testing.T) {
+// go test -run Test_Ctx_SendSSE_Heartbeat
+func Test_Ctx_SendSSE_Heartbeat(t *testing.T) {
+	t.Parallel()
 	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	app.Get("/:phone/*/send", func(c *Ctx) error {
-		return c.SendString("Phone: " + c.Params("phone") + "\nFirst Param: " + c.Params("*1"))
-	}).Name("SendSms")
 
-	location, err := c.GetRouteURL("SendSms", Map{
-		"phone": "23456789",
-		"*":     "sms",
+	stopped := make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		// the producer never sends an event
+		ch := make(chan SSEvent)
+		if err := c.SendSSE(ch, 10*time.Millisecond); err != nil {
+			return err
+		}
+		ctx := c.UserContext()
+		go func() {
+			defer close(stopped)
+			<-ctx.Done()
+		}()
+		return nil
 	})
+	ln, err := app.NewTestListener()
 	utils.AssertEqual(t, nil, err)
-	utils.AssertEqual(t, "/23456789/sms/send", location)
-}
-
-type errorTemplateEngine struct{}
+	defer ln.Close()
 
-func (t errorTemplateEngine) Render(w io.Writer, name string, bind interface{}, layout ...string) error {
-	return errors.New("errorTemplateEngine")
-}
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
 
-func (t errorTemplateEngine) Load() error { return nil }
+	// wait for the first heartbeat comment, then disconnect
+	r := bufio.NewReader(conn)
+	var received string
+	for !strings.Contains(received, "\n:\n") {
+		line, err := r.ReadString('\n')
+		utils.AssertEqual(t, nil, err)
+		received += line
+	}
+	utils.AssertEqual(t, nil, conn.Close())
 
-// go test -run Test_Ctx_Render_Engine_Error
-func Test_Ctx_Render_Engine_Error(t *testing.T) {
-	app := New()
-	app.config.Views = errorTemplateEngine{}
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	err := c.Render("index.tmpl", nil)
-	utils.AssertEqual(t, false, err == nil)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the user context wasn't cancelled after the client disconnected")
+	}
 }
 
-// go test -run Test_Ctx_Render_Go_Template
-func Test_Ctx_Render_Go_Template(t *testing.T) {
+// go test -run Test_Ctx_SendSSE_Disconnect
+func Test_Ctx_SendSSE_Disconnect(t *testing.T) {
 	t.Parallel()
+	app := New(Config{DisableStartupMessage: true})
 
-	file, err := ioutil.TempFile(os.TempDir(), "fiber")
-	utils.AssertEqual(t, nil, err)
-	defer os.Remove(file.Name())
+	stopped := make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		ch := make(chan SSEvent)
+		if err := c.SendSSE(ch); err != nil {
+			return err
+		}
+		ctx := c.UserContext()
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- SSEvent{Data: "ping"}:
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+		}()
+		return nil
+	})
 
-	_, err = file.Write([]byte("template"))
-	utils.AssertEqual(t, nil, err)
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		utils.AssertEqual(t, nil, app.Listener(ln))
+	}()
+	defer func() {
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
 
-	err = file.Close()
+	conn, err := ln.Dial()
 	utils.AssertEqual(t, nil, err)
-
-	app := New()
-
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-
-	err = c.Render(file.Name(), nil)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
 	utils.AssertEqual(t, nil, err)
-	utils.AssertEqual(t, "template", string(c.Response().Body()))
-}
 
-// go test -run Test_Ctx_Send
-func Test_Ctx_Send(t *testing.T) {
-	t.Parallel()
-	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	c.Send([]byte("Hello, World"))
-	c.Send([]byte("Don't crash please"))
-	c.Send([]byte("1337"))
-	utils.AssertEqual(t, "1337", string(c.Response().Body()))
-}
+	// wait for the first event, then disconnect mid-stream
+	r := bufio.NewReader(conn)
+	var received string
+	for !strings.Contains(received, "data: ping") {
+		line, err := r.ReadString('\n')
+		utils.AssertEqual(t, nil, err)
+		received += line
+	}
+	utils.AssertEqual(t, nil, conn.Close())
 
-// go test -v  -run=^$ -bench=Benchmark_Ctx_Send -benchmem -count=4
-func Benchmark_Ctx_Send(b *testing.B) {
-	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	byt := []byte("Hello, World!")
-	b.ReportAllocs()
-	b.ResetTimer()
-	for n := 0; n < b.N; n++ {
-		c.Send(byt)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("event producer was not stopped after the client disconnected")
 	}
-	utils.AssertEqual(b, "Hello, World!", string(c.Response().Body()))
 }
 
-// go test -run Test_Ctx_SendStatus
-func Test_Ctx_SendStatus(t *testing.T) {
+// go test -run Test_Ctx_Flush
+func Test_Ctx_Flush(t *testing.T) {
 	t.Parallel()
-	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	c.SendStatus(415)
-	utils.AssertEqual(t, 415, c.Response().StatusCode())
-	utils.AssertEqual(t, "Unsupported Media Type", string(c.Response().Body()))
-}
+	app := New(Config{DisableStartupMessage: true})
 
-// go test -run Test_Ctx_SendString
-func Test_Ctx_SendString(t *testing.T) {
-	t.Parallel()
-	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	c.SendString("Don't crash please")
-	utils.AssertEqual(t, "Don't crash please", string(c.Response().Body()))
-}
+	proceed := make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		c.Set(HeaderContentType, "text/event-stream")
+		return c.SendStreamWriter(func(ctx context.Context, w *bufio.Writer) {
+			_, _ = w.WriteString("data: 1\n\n")
+			utils.AssertEqual(t, nil, c.Flush())
+			<-proceed
+			_, _ = w.WriteString("data: 2\n\n")
+		})
+	})
+	app.Get("/not-streamed", func(c *Ctx) error {
+		return c.Flush()
+	})
 
-// go test -run Test_Ctx_SendStream
-func Test_Ctx_SendStream(t *testing.T) {
-	t.Parallel()
-	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		utils.AssertEqual(t, nil, app.Listener(ln))
+	}()
+	defer func() {
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
 
-	c.SendStream(bytes.NewReader([]byte("Don't crash please")))
-	utils.AssertEqual(t, "Don't crash please", string(c.Response().Body()))
+	conn, err := ln.Dial()
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+	r := bufio.NewReader(conn)
 
-	c.SendStream(bytes.NewReader([]byte("Don't crash please")), len([]byte("Don't crash please")))
-	utils.AssertEqual(t, "Don't crash please", string(c.Response().Body()))
+	// the flushed event arrives while the stream writer is still blocked
+	var received string
+	for !strings.Contains(received, "data: 1") {
+		line, err := r.ReadString('\n')
+		utils.AssertEqual(t, nil, err)
+		received += line
+	}
+	utils.AssertEqual(t, false, strings.Contains(received, "data: 2"))
 
-	c.SendStream(bufio.NewReader(bytes.NewReader([]byte("Hello bufio"))))
-	utils.AssertEqual(t, "Hello bufio", string(c.Response().Body()))
+	close(proceed)
+	for !strings.Contains(received, "data: 2") {
+		line, err := r.ReadString('\n')
+		utils.AssertEqual(t, nil, err)
+		received += line
+	}
 
-	file, err := os.Open("./.github/index.html")
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/not-streamed", nil))
 	utils.AssertEqual(t, nil, err)
-	c.SendStream(bufio.NewReader(file))
-	utils.AssertEqual(t, true, c.Response().Header.ContentLength() > 200)
+	utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, ErrNotStreaming.Error(), string(body))
 }
 
 // go test -run Test_Ctx_Set
@@ -3359,6 +6008,27 @@ func Test_Ctx_Status(t *testing.T) {
 	utils.AssertEqual(t, "Hello, World", string(c.Response().Body()))
 }
 
+// go test -run Test_Ctx_StatusCode
+func Test_Ctx_StatusCode(t *testing.T) {
+	t.Parallel()
+	app := New()
+	var status int
+	app.Use(func(c *Ctx) error {
+		utils.AssertEqual(t, StatusOK, c.StatusCode())
+		err := c.Next()
+		status = c.StatusCode()
+		return err
+	})
+	app.Post("/", func(c *Ctx) error {
+		return c.Status(StatusCreated).SendString("created")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodPost, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusCreated, resp.StatusCode)
+	utils.AssertEqual(t, StatusCreated, status)
+}
+
 // go test -run Test_Ctx_Type
 func Test_Ctx_Type(t *testing.T) {
 	t.Parallel()
@@ -3414,6 +6084,19 @@ func Test_Ctx_Vary(t *testing.T) {
 	c.Vary("User-Agent")
 	c.Vary("Accept-Encoding", "Accept")
 	utils.AssertEqual(t, "Origin, User-Agent, Accept-Encoding, Accept", string(c.Response().Header.Peek("Vary")))
+
+	// no duplicates
+	c.Vary("origin", "ACCEPT-ENCODING", " Accept ", "")
+	utils.AssertEqual(t, "Origin, User-Agent, Accept-Encoding, Accept", string(c.Response().Header.Peek("Vary")))
+	c.Response().Header.Set(HeaderVary, "Origin,Accept-Language")
+	c.Vary("accept-language", "Cookie")
+	utils.AssertEqual(t, "Origin,Accept-Language, Cookie", string(c.Response().Header.Peek("Vary")))
+
+	// "*" collapses all fields
+	c.Vary("Accept", "*", "Origin")
+	utils.AssertEqual(t, "*", string(c.Response().Header.Peek("Vary")))
+	c.Vary("User-Agent")
+	utils.AssertEqual(t, "*", string(c.Response().Header.Peek("Vary")))
 }
 
 // go test -v  -run=^$ -bench=Benchmark_Ctx_Vary -benchmem -count=4
@@ -3600,6 +6283,92 @@ func Test_Ctx_QueryParser(t *testing.T) {
 	utils.AssertEqual(t, 2, len(aq.Data))
 }
 
+// go test -run Test_Ctx_Parser_EmbeddedStruct
+func Test_Ctx_Parser_EmbeddedStruct(t *testing.T) {
+	t.Parallel()
+	type Pagination struct {
+		Page    int      `query:"page" form:"page" params:"page" json:"page"`
+		PerPage int      `query:"per_page" form:"per_page" params:"per_page" json:"per_page"`
+		Sort    []string `query:"sort" form:"sort"`
+	}
+	type Filter struct {
+		Labels map[string]string `form:"labels"`
+	}
+	type Request struct {
+		Pagination
+		Filter
+		Name string `query:"name" form:"name" params:"name" json:"name"`
+	}
+
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	// query
+	c.Request().URI().SetQueryString("name=tom&page=2&per_page=50&sort=name,-age")
+	q := new(Request)
+	utils.AssertEqual(t, nil, c.QueryParser(q))
+	utils.AssertEqual(t, "tom", q.Name)
+	utils.AssertEqual(t, 2, q.Page)
+	utils.AssertEqual(t, 50, q.PerPage)
+	utils.AssertEqual(t, []string{"name", "-age"}, q.Sort)
+
+	// form body
+	c.Request().Header.SetContentType(MIMEApplicationForm)
+	c.Request().SetBody([]byte("name=tom&page=3&per_page=10&sort=age&labels[env]=prod"))
+	b := new(Request)
+	utils.AssertEqual(t, nil, c.BodyParser(b))
+	utils.AssertEqual(t, "tom", b.Name)
+	utils.AssertEqual(t, 3, b.Page)
+	utils.AssertEqual(t, 10, b.PerPage)
+	utils.AssertEqual(t, []string{"age"}, b.Sort)
+	utils.AssertEqual(t, map[string]string{"env": "prod"}, b.Labels)
+
+	// json body
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().SetBody([]byte(`{"name":"tom","page":4,"per_page":20}`))
+	j := new(Request)
+	utils.AssertEqual(t, nil, c.BodyParser(j))
+	utils.AssertEqual(t, "tom", j.Name)
+	utils.AssertEqual(t, 4, j.Page)
+	utils.AssertEqual(t, 20, j.PerPage)
+
+	// params
+	app.Get("/users/:name/:page/:per_page", func(c *Ctx) error {
+		p := new(Request)
+		if err := c.ParamsParser(p); err != nil {
+			return err
+		}
+		return c.SendString(fmt.Sprintf("%s %d %d", p.Name, p.Page, p.PerPage))
+	})
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/users/tom/5/30", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "tom 5 30", string(body))
+}
+
+// go test -run Test_Ctx_QueryParser_Brackets
+func Test_Ctx_QueryParser_Brackets(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type Query struct {
+		IDs     []int               `query:"ids"`
+		Meta    map[string]string   `query:"meta"`
+		Filters map[string][]string `query:"filters"`
+	}
+
+	c.Request().URI().SetQueryString("ids[1]=20&ids[0]=10&meta[k]=v&filters[color]=red&filters[color]=blue")
+	q := new(Query)
+	utils.AssertEqual(t, nil, c.QueryParser(q))
+	utils.AssertEqual(t, []int{10, 20}, q.IDs)
+	utils.AssertEqual(t, map[string]string{"k": "v"}, q.Meta)
+	utils.AssertEqual(t, map[string][]string{"color": {"red", "blue"}}, q.Filters)
+}
+
 // go test -run Test_Ctx_QueryParser_WithSetParserDecoder -v
 func Test_Ctx_QueryParser_WithSetParserDecoder(t *testing.T) {
 	type NonRFCTime time.Time
@@ -3827,6 +6596,37 @@ func Test_Ctx_ReqHeaderParser(t *testing.T) {
 	utils.AssertEqual(t, "name is empty", c.ReqHeaderParser(rh).Error())
 }
 
+// go test -run Test_Ctx_ReqHeaderParser_HeaderTag
+func Test_Ctx_ReqHeaderParser_HeaderTag(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type Headers struct {
+		RequestID string   `header:"X-Request-Id"`
+		Tags      []string `header:"X-Tags"`
+		Accept    []string `header:"Accept"`
+		Legacy    string   `reqHeader:"X-Legacy"`
+		Both      string   `header:"X-Both" reqHeader:"X-Other"`
+	}
+
+	c.Request().Header.Set("x-request-id", "abc")
+	c.Request().Header.Set("X-TAGS", "go, fiber")
+	c.Request().Header.Add("Accept", "text/html")
+	c.Request().Header.Add("Accept", "application/json")
+	c.Request().Header.Set("X-Legacy", "legacy")
+	c.Request().Header.Set("X-Both", "header")
+	c.Request().Header.Set("X-Other", "reqHeader")
+
+	h := new(Headers)
+	utils.AssertEqual(t, nil, c.ReqHeaderParser(h))
+	utils.AssertEqual(t, "abc", h.RequestID)
+	utils.AssertEqual(t, []string{"go", "fiber"}, h.Tags)
+	utils.AssertEqual(t, []string{"text/html", "application/json"}, h.Accept)
+	utils.AssertEqual(t, "legacy", h.Legacy)
+	utils.AssertEqual(t, "header", h.Both)
+}
+
 // go test -run Test_Ctx_ReqHeaderParser_WithSetParserDecoder -v
 func Test_Ctx_ReqHeaderParser_WithSetParserDecoder(t *testing.T) {
 	type NonRFCTime time.Time
@@ -4244,6 +7044,72 @@ func TestCtx_ParamsInt(t *testing.T) {
 	app.Test(httptest.NewRequest(MethodGet, "/testdefault/xd", nil))
 }
 
+// go test -run Test_Ctx_ParamsInt_Bounds
+func Test_Ctx_ParamsInt_Bounds(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	app.Get("/constraint/:id<int>", func(c *Ctx) error {
+		id, err := c.ParamsInt("id")
+		utils.AssertEqual(t, nil, err)
+		return c.SendString(strconv.Itoa(id))
+	})
+	app.Get("/plain/:id?", func(c *Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.SendString(err.Error())
+		}
+		return c.SendString(strconv.Itoa(id))
+	})
+	app.Get("/default/:id?", func(c *Ctx) error {
+		id, err := c.ParamsInt("id", -1)
+		utils.AssertEqual(t, nil, err)
+		return c.SendString(strconv.Itoa(id))
+	})
+
+	tests := []struct {
+		path, body string
+	}{
+		{path: "/constraint/-42", body: "-42"},
+		{path: "/plain/42", body: "42"},
+		{path: "/plain/-7", body: "-7"},
+		{path: "/plain/abc", body: `params: failed to convert "id" to int: strconv.Atoi: parsing "abc": invalid syntax`},
+		{path: "/plain/99999999999999999999", body: `params: failed to convert "id" to int: strconv.Atoi: parsing "99999999999999999999": value out of range`},
+		{path: "/plain", body: `params: failed to convert "id" to int: strconv.Atoi: parsing "": invalid syntax`},
+		{path: "/default", body: "-1"},
+		{path: "/default/abc", body: "-1"},
+		{path: "/default/5", body: "5"},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, tt.path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tt.body, string(body), tt.path)
+	}
+
+	// constrained routes don't match non-integer values
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/constraint/abc", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+}
+
+// go test -run Test_Ctx_GetAll
+func Test_Ctx_GetAll(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	c.Request().Header.Add(HeaderVia, "1.1 proxy-a")
+	c.Request().Header.Add(HeaderVia, "1.0 proxy-b")
+	c.Request().Header.Set(HeaderAccept, "*/*")
+	utils.AssertEqual(t, []string{"1.1 proxy-a", "1.0 proxy-b"}, c.GetAll(HeaderVia))
+	utils.AssertEqual(t, []string{"1.1 proxy-a", "1.0 proxy-b"}, c.GetAll("via"))
+	utils.AssertEqual(t, []string{"*/*"}, c.GetAll(HeaderAccept))
+	utils.AssertEqual(t, []string(nil), c.GetAll(HeaderForwarded))
+}
+
 // go test -run Test_Ctx_GetRespHeader
 func Test_Ctx_GetRespHeader(t *testing.T) {
 	app := New()
@@ -4256,6 +7122,21 @@ func Test_Ctx_GetRespHeader(t *testing.T) {
 	utils.AssertEqual(t, c.GetRespHeader(HeaderContentType), "application/json")
 }
 
+// go test -run Test_Ctx_RequestID
+func Test_Ctx_RequestID(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	utils.AssertEqual(t, "", c.RequestID())
+	// the response header alone isn't used
+	c.Set(HeaderXRequestID, "0a1b2c")
+	utils.AssertEqual(t, "", c.RequestID())
+	c.Locals(LocalsKeyRequestID, "0a1b2c")
+	utils.AssertEqual(t, "0a1b2c", c.RequestID())
+}
+
 // go test -run Test_Ctx_GetRespHeaders
 func Test_Ctx_GetRespHeaders(t *testing.T) {
 	app := New()
@@ -0,0 +1,134 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+const (
+	envGracefulChildKey = "FIBER_GRACEFUL_CHILD"
+	envGracefulChildVal = "1"
+
+	// the inherited listener is the first extra file of the child process,
+	// after stdin, stdout and stderr
+	gracefulListenerFd = 3
+)
+
+// gracefulRestartCmd creates the command of the new process, replaced in tests
+var gracefulRestartCmd = func() *exec.Cmd {
+	return exec.Command(os.Args[0], os.Args[1:]...) // #nosec G204
+}
+
+// IsGracefulChild determines if the current process has been started by a graceful restart
+func IsGracefulChild() bool {
+	return os.Getenv(envGracefulChildKey) == envGracefulChildVal
+}
+
+// EnableGracefulRestart restarts the application without downtime when the given signal,
+// e.g. syscall.SIGHUP, is received: a new process is started which inherits the listening
+// socket of app.Listen, while the old process stops accepting connections
+// and shuts down after the open requests are finished. app.Listen of the old process
+// returns after it is drained. The OnFork hooks are executed with the pid of the new process.
+//
+// Graceful restarts are only supported on Unix systems, as the listening socket is passed
+// as file descriptor to the new process. Prefork is not supported.
+func (app *App) EnableGracefulRestart(sig os.Signal) {
+	app.mutex.Lock()
+	app.restartSignal = sig
+	app.mutex.Unlock()
+}
+
+// gracefulListen creates the listener for app.Listen,
+// the listener of the old process is inherited after a graceful restart
+func (app *App) gracefulListen(network, addr string) (net.Listener, error) {
+	if app.restartSignal == nil || !IsGracefulChild() {
+		return net.Listen(network, addr)
+	}
+	f := os.NewFile(gracefulListenerFd, "listener")
+	if f == nil {
+		return nil, fmt.Errorf("graceful: no inherited listener found")
+	}
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("graceful: failed to inherit listener: %w", err)
+	}
+	return ln, nil
+}
+
+// serveGraceful serves on the listener and restarts the application once the restart signal
+// is received, in this case it returns after the open requests are finished
+func (app *App) serveGraceful(ln net.Listener) error {
+	if app.restartSignal == nil {
+		return app.server.Serve(ln)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, app.restartSignal)
+	defer signal.Stop(signals)
+
+	// signal.Stop doesn't close the channel, done stops the goroutine once serving returned
+	restarting, drained, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		close(restarting)
+		if err := app.gracefulRestart(ln); err != nil {
+			log.Printf("[Warning] %v\n", err)
+		}
+		close(drained)
+	}()
+
+	err := app.server.Serve(ln)
+	select {
+	case <-restarting:
+		<-drained
+	default:
+	}
+	return err
+}
+
+// gracefulRestart starts the new process with the listening socket and drains the current one
+func (app *App) gracefulRestart(ln net.Listener) error {
+	fileListener, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("graceful: listener %T cannot be passed to a new process", ln)
+	}
+	f, err := fileListener.File()
+	if err != nil {
+		return fmt.Errorf("graceful: failed to get listener file: %w", err)
+	}
+	defer f.Close()
+
+	cmd := gracefulRestartCmd()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{f}
+	// add fiber graceful child flag into the env of the new process
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", envGracefulChildKey, envGracefulChildVal),
+	)
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("graceful: failed to start new process: %w", err)
+	}
+
+	// execute fork hook
+	if app.hooks != nil {
+		app.hooks.executeOnForkHooks(cmd.Process.Pid)
+	}
+	_ = cmd.Process.Release()
+
+	// stop accepting connections and wait for the open requests
+	return app.Shutdown()
+}
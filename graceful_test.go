@@ -0,0 +1,143 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const envGracefulTestAddr = "FIBER_GRACEFUL_TEST_ADDR"
+
+// go test -run Test_App_GracefulRestart
+func Test_App_GracefulRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("graceful restarts are only supported on Unix systems")
+	}
+
+	// 👶 new process, serving on the inherited listener 👶
+	if IsGracefulChild() {
+		app := New(Config{DisableStartupMessage: true})
+		app.EnableGracefulRestart(syscall.SIGHUP)
+		app.Get("/", func(c *Ctx) error {
+			return c.SendString(fmt.Sprintf("child %d", os.Getpid()))
+		})
+		app.Get("/stop", func(c *Ctx) error {
+			go func() {
+				_ = app.Shutdown()
+			}()
+			return nil
+		})
+		utils.AssertEqual(t, nil, app.Listen(os.Getenv(envGracefulTestAddr)))
+		return
+	}
+
+	// 👮 old process 👮
+	defer func(cmd func() *exec.Cmd) {
+		gracefulRestartCmd = cmd
+	}(gracefulRestartCmd)
+	gracefulRestartCmd = func() *exec.Cmd {
+		return exec.Command(os.Args[0], "-test.run=^Test_App_GracefulRestart$") // #nosec G204
+	}
+
+	ln, err := net.Listen(NetworkTCP4, "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	addr := ln.Addr().String()
+	utils.AssertEqual(t, nil, ln.Close())
+	utils.AssertEqual(t, nil, os.Setenv(envGracefulTestAddr, addr))
+	defer os.Unsetenv(envGracefulTestAddr)
+
+	app := New(Config{DisableStartupMessage: true})
+	app.EnableGracefulRestart(syscall.SIGHUP)
+	forked := make(chan int, 1)
+	app.Hooks().OnFork(func(pid int) error {
+		forked <- pid
+		return nil
+	})
+	started, release := make(chan struct{}), make(chan struct{})
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString("parent")
+	})
+	app.Get("/slow", func(c *Ctx) error {
+		close(started)
+		<-release
+		return c.SendString("parent slow")
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Listen(addr)
+	}()
+
+	get := func(path string) (string, error) {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+	waitFor := func(prefix string) string {
+		for i := 0; i < 100; i++ {
+			if body, err := get("/"); err == nil && strings.HasPrefix(body, prefix) {
+				return body
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("no %q response from %s", prefix, addr)
+		return ""
+	}
+	waitFor("parent")
+
+	// keep a request of the old process open during the restart
+	conn, err := net.Dial(NetworkTCP4, addr)
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+	<-started
+
+	self, err := os.FindProcess(os.Getpid())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, self.Signal(syscall.SIGHUP))
+	pid := <-forked
+	defer func() {
+		if child, err := os.FindProcess(pid); err == nil {
+			_ = child.Kill()
+		}
+	}()
+
+	// the new process serves on the inherited socket while the old one is draining
+	utils.AssertEqual(t, fmt.Sprintf("child %d", pid), waitFor("child"))
+	select {
+	case err = <-done:
+		t.Fatalf("old process stopped before it was drained: %v", err)
+	default:
+	}
+
+	close(release)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "parent slow", string(body))
+	utils.AssertEqual(t, nil, <-done)
+
+	// stop the new process
+	_, _ = get("/stop")
+}
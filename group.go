@@ -13,8 +13,12 @@ import (
 
 // Group struct
 type Group struct {
-	app  *App
-	name string
+	app       *App
+	name      string
+	host      string
+	bodyLimit int
+	noRecover bool
+	maxFiles  int
 
 	Prefix string
 }
@@ -24,8 +28,7 @@ type Group struct {
 // compose them as a single service using Mount.
 func (grp *Group) Mount(prefix string, fiber *App) Router {
 	stack := fiber.Stack()
-	groupPath := getGroupPath(grp.Prefix, prefix)
-	groupPath = strings.TrimRight(groupPath, "/")
+	groupPath := strings.TrimRight(JoinPath(grp.Prefix, prefix), "/")
 	if groupPath == "" {
 		groupPath = "/"
 	}
@@ -33,7 +36,9 @@ func (grp *Group) Mount(prefix string, fiber *App) Router {
 	for m := range stack {
 		for r := range stack[m] {
 			route := grp.app.copyRoute(stack[m][r])
-			grp.app.addRoute(route.Method, grp.app.addPrefixToRoute(groupPath, route))
+			if err := grp.app.addRoute(route.Method, grp.app.addPrefixToRoute(groupPath, route)); err != nil {
+				panic(err)
+			}
 		}
 	}
 
@@ -67,6 +72,34 @@ func (grp *Group) Name(name string) Router {
 	return grp
 }
 
+// BodyLimit overrides the body limit of the app for the routes registered
+// on the group afterwards, -1 declines any body size and 0 keeps the inherited limit.
+//
+//	upload := app.Group("/upload").BodyLimit(100 * 1024 * 1024)
+//	upload.Post("/", handler)
+func (grp *Group) BodyLimit(limit int) Router {
+	if limit != 0 {
+		grp.bodyLimit = limit
+	}
+	return grp
+}
+
+// MaxFiles limits the number of files of multipart requests to the routes registered
+// on the group afterwards, see App.MaxFiles.
+func (grp *Group) MaxFiles(n int) Router {
+	if n != 0 {
+		grp.maxFiles = n
+	}
+	return grp
+}
+
+// DisableRecover opts the routes registered on the group afterwards out of panic recovery,
+// see App.DisableRecover.
+func (grp *Group) DisableRecover() Router {
+	grp.noRecover = true
+	return grp
+}
+
 // Use registers a middleware route that will match requests
 // with the provided prefix (which is optional and defaults to "/").
 //
@@ -94,15 +127,15 @@ func (grp *Group) Use(args ...interface{}) Router {
 			panic(fmt.Sprintf("use: invalid handler %v\n", reflect.TypeOf(arg)))
 		}
 	}
-	grp.app.register(methodUse, getGroupPath(grp.Prefix, prefix), handlers...)
+	grp.app.registerHost(grp.host, methodUse, getGroupPath(grp.Prefix, prefix), handlers...)
 	return grp
 }
 
 // Get registers a route for GET methods that requests a representation
 // of the specified resource. Requests using GET should only retrieve data.
 func (grp *Group) Get(path string, handlers ...Handler) Router {
-	path = getGroupPath(grp.Prefix, path)
-	return grp.app.Add(MethodHead, path, handlers...).Add(MethodGet, path, handlers...)
+	grp.Add(MethodHead, path, handlers...)
+	return grp.Add(MethodGet, path, handlers...)
 }
 
 // Head registers a route for HEAD methods that asks for a response identical
@@ -154,12 +187,42 @@ func (grp *Group) Patch(path string, handlers ...Handler) Router {
 
 // Add allows you to specify a HTTP method to register a route
 func (grp *Group) Add(method, path string, handlers ...Handler) Router {
-	return grp.app.register(method, getGroupPath(grp.Prefix, path), handlers...)
+	router := grp.app.registerHost(grp.host, method, getGroupPath(grp.Prefix, path), handlers...)
+	grp.setRouteOptions()
+	return router
+}
+
+// setRouteOptions passes the body limit, the file limit and the recovery option of the group to the latest route
+func (grp *Group) setRouteOptions() {
+	if grp.bodyLimit == 0 && grp.maxFiles == 0 && !grp.noRecover {
+		return
+	}
+	grp.app.mutex.Lock()
+	grp.app.setRouteBodyLimit(grp.app.latestRoute, grp.bodyLimit)
+	grp.app.setRouteMaxFiles(grp.app.latestRoute, grp.maxFiles)
+	if grp.noRecover {
+		grp.app.latestRoute.noRecover = true
+	}
+	grp.app.mutex.Unlock()
+}
+
+// AddMethods registers the route for each of the given HTTP methods,
+// it panics before registering anything if one of the methods is invalid.
+// The chained route options like Name apply to the routes of all methods.
+func (grp *Group) AddMethods(methods []string, path string, handlers ...Handler) Router {
+	checkMethods(methods)
+	routes := make([]*Route, 0, len(methods))
+	for _, method := range methods {
+		_ = grp.Add(method, path, handlers...)
+		routes = append(routes, grp.app.latestRoute)
+	}
+	grp.app.setChainedRoutes(routes)
+	return grp.app
 }
 
 // Static will create a file server serving static files
 func (grp *Group) Static(prefix, root string, config ...Static) Router {
-	return grp.app.registerStatic(getGroupPath(grp.Prefix, prefix), root, config...)
+	return grp.app.registerStatic(grp.host, getGroupPath(grp.Prefix, prefix), root, config...)
 }
 
 // All will register the handler on all HTTP methods
@@ -177,9 +240,13 @@ func (grp *Group) All(path string, handlers ...Handler) Router {
 func (grp *Group) Group(prefix string, handlers ...Handler) Router {
 	prefix = getGroupPath(grp.Prefix, prefix)
 	if len(handlers) > 0 {
-		_ = grp.app.register(methodUse, prefix, handlers...)
+		_ = grp.app.registerHost(grp.host, methodUse, prefix, handlers...)
+	}
+	newGrp := &Group{Prefix: prefix, app: grp.app, host: grp.host, bodyLimit: grp.bodyLimit, noRecover: grp.noRecover, maxFiles: grp.maxFiles}
+	if err := grp.app.hooks.executeOnGroupHooks(*newGrp); err != nil {
+		panic(err)
 	}
-	return grp.app.Group(prefix)
+	return newGrp
 }
 
 // Route is used to define routes with a common prefix inside the common function.
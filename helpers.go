@@ -7,16 +7,24 @@ package fiber
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"hash/crc32"
 	"io"
+	"io/fs"
 	"log"
 	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
@@ -83,6 +91,47 @@ func getTlsConfig(ln net.Listener) *tls.Config {
 	return nil
 }
 
+// hasConflictingContentLength reports whether the raw request headers contain
+// Content-Length headers with different values
+func hasConflictingContentLength(rawHeaders []byte) bool {
+	length := ""
+	for _, line := range strings.Split(utils.UnsafeString(rawHeaders), "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 || !utils.EqualFold(utils.TrimRight(line[:i], ' '), HeaderContentLength) {
+			continue
+		}
+		value := strings.TrimSpace(line[i+1:])
+		if length == "" {
+			length = value
+		} else if value != length {
+			return true
+		}
+	}
+	return false
+}
+
+// statSize returns the remaining size of a regular file stream like *os.File from its stat
+func statSize(stream io.Reader) (int, bool) {
+	file, ok := stream.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return 0, false
+	}
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, false
+	}
+	size := info.Size()
+	// the stream may have been read partially
+	if seeker, ok := stream.(io.Seeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		size -= offset
+	}
+	return int(size), true
+}
+
 // readContent opens a named file and read content from it
 func readContent(rf io.ReaderFrom, name string) (n int64, err error) {
 	// Read file
@@ -99,6 +148,61 @@ func readContent(rf io.ReaderFrom, name string) (n int64, err error) {
 	return rf.ReadFrom(f)
 }
 
+// isCompressibleType checks if the mime type matches one of the compressible types,
+// a type ending with "/" matches all its subtypes, empty types are skipped
+func isCompressibleType(mime string, types []string) bool {
+	for _, t := range types {
+		if t == "" {
+			continue
+		}
+		if t == mime || (t[len(t)-1] == '/' && strings.HasPrefix(mime, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findIndexFile returns the path of the first existing index file in the given directory,
+// or an empty string if none of them exists
+func findIndexFile(dir string, indexNames []string) string {
+	for _, name := range indexNames {
+		file := filepath.Join(dir, name)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// findIndexFileFS returns the path of the first existing index file in the directory of the file system
+func findIndexFileFS(fsys fs.FS, dir string, indexNames []string) string {
+	for _, name := range indexNames {
+		file := path.Join(dir, name)
+		if info, err := fs.Stat(fsys, file); err == nil && !info.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// isInRoot reports whether the absolute file path is inside the root directory,
+// symlinks are resolved for both paths
+func isInRoot(root, file string) bool {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		// compare the resolved paths
+		file = resolved
+		if resolved, err = filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+	}
+	rel, err := filepath.Rel(root, filepath.Clean(file))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // quoteString escape special characters in a given string
 func (app *App) quoteString(raw string) string {
 	bb := bytebufferpool.Get()
@@ -108,6 +212,58 @@ func (app *App) quoteString(raw string) string {
 	return quoted
 }
 
+// contentDisposition returns the attachment Content-Disposition value for the given filename,
+// non-ASCII filenames get an additional RFC 5987 encoded filename* parameter
+func (app *App) contentDisposition(filename string) string {
+	if filename == "" {
+		return "attachment"
+	}
+	ascii := true
+	for i := 0; i < len(filename); i++ {
+		if filename[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return `attachment; filename="` + app.quoteString(filename) + `"`
+	}
+	// replace the non-ASCII characters for clients without RFC 5987 support
+	fallback := strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf {
+			return '_'
+		}
+		return r
+	}, filename)
+	return `attachment; filename="` + app.quoteString(fallback) + `"; filename*=UTF-8''` + encodeExtValue(filename)
+}
+
+// encodeExtValue percent-encodes all bytes which are not an attr-char as defined in RFC 5987
+func encodeExtValue(raw string) string {
+	const hex = "0123456789ABCDEF"
+	b := make([]byte, 0, len(raw)*3)
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		if ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') ||
+			strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b = append(b, ch)
+			continue
+		}
+		b = append(b, '%', hex[ch>>4], hex[ch&0x0f])
+	}
+	return string(b)
+}
+
+// escapePathSegments percent-encodes the given value for the use in a path,
+// slashes are kept as they separate the segments of wildcard parameters
+func escapePathSegments(raw string) string {
+	segments := strings.Split(raw, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
 // Scan stack if other methods match the request
 func methodExist(ctx *Ctx) (exist bool) {
 	for i := 0; i < len(intMethod); i++ {
@@ -133,8 +289,8 @@ func methodExist(ctx *Ctx) (exist bool) {
 			if route.use {
 				continue
 			}
-			// Check if it matches the request path
-			match := route.match(ctx.detectionPath, ctx.path, &ctx.values)
+			// Check if it matches the request path and host
+			match := route.match(ctx.detectionPath, ctx.path, &ctx.values) && route.matchHost(ctx)
 			// No match, next route
 			if match {
 				// We matched
@@ -173,6 +329,39 @@ func defaultString(value string, defaultValue []string) string {
 	return value
 }
 
+// defaultInt returns the parsed value, or a default value or 0 if the value couldn't be parsed
+func defaultInt(value int, err error, defaultValue []int) int {
+	if err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return 0
+	}
+	return value
+}
+
+// defaultBool returns the parsed value, or a default value or false if the value couldn't be parsed
+func defaultBool(value bool, err error, defaultValue []bool) bool {
+	if err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return false
+	}
+	return value
+}
+
+// defaultFloat returns the parsed value, or a default value or 0 if the value couldn't be parsed
+func defaultFloat(value float64, err error, defaultValue []float64) float64 {
+	if err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return 0
+	}
+	return value
+}
+
 const normalizedHeaderETag = "Etag"
 
 // Generate and set ETag header to response
@@ -181,11 +370,35 @@ func setETag(c *Ctx, weak bool) {
 	if c.fasthttp.Response.StatusCode() != StatusOK {
 		return
 	}
+	// Keep an ETag which is already set, e.g. by SendFile, without reading the body
+	if etag := c.fasthttp.Response.Header.Peek(HeaderETag); len(etag) > 0 {
+		if !c.app.isEtagStale(c.app.getString(etag), c.fasthttp.Request.Header.Peek(HeaderIfNoneMatch)) {
+			_ = c.SendStatus(StatusNotModified)
+			c.fasthttp.ResetBody()
+		}
+		return
+	}
 	body := c.fasthttp.Response.Body()
 	// Skips ETag if no response body is present
 	if len(body) == 0 {
 		return
 	}
+	// Use the custom ETag generator if set
+	if c.app.config.ETagGenerator != nil {
+		etag := c.app.config.ETagGenerator(body, weak)
+		// An empty ETag disables the header
+		if etag == "" {
+			return
+		}
+		if !c.app.isEtagStale(etag, c.fasthttp.Request.Header.Peek(HeaderIfNoneMatch)) {
+			_ = c.SendStatus(StatusNotModified)
+			c.fasthttp.ResetBody()
+			return
+		}
+		c.setCanonical(normalizedHeaderETag, etag)
+		return
+	}
+
 	// Get ETag header from request
 	clientEtag := c.Get(HeaderIfNoneMatch)
 
@@ -221,6 +434,131 @@ func setETag(c *Ctx, weak bool) {
 	c.setCanonical(normalizedHeaderETag, etag)
 }
 
+// byteRange is a range of a Range request header, the end is inclusive
+type byteRange struct {
+	start, end int64
+}
+
+func (r byteRange) length() int64 {
+	return r.end - r.start + 1
+}
+
+// maxRanges is the maximum number of byte ranges which are served for a Range header
+const maxRanges = 16
+
+// parseRange parses the byte ranges of a Range header for a file of the given size.
+// ok is false if the header is malformed and has to be ignored,
+// no ranges are returned if none of them can be satisfied.
+// Like net/http, the header is also ignored if it has more than maxRanges ranges
+// or the ranges are larger than the file, e.g. "bytes=0-,0-,0-", so that the
+// response can't be amplified by overlapping ranges.
+func parseRange(header string, size int64) (ranges []byteRange, ok bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, false
+	}
+	specs := 0
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = utils.Trim(spec, ' ')
+		if spec == "" {
+			continue
+		}
+		specs++
+		i := strings.IndexByte(spec, '-')
+		if i == -1 {
+			return nil, false
+		}
+		first, last := utils.Trim(spec[:i], ' '), utils.Trim(spec[i+1:], ' ')
+		var r byteRange
+		if first == "" {
+			// suffix range with the last n bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, false
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{start: size - n, end: size - 1}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, false
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, false
+				}
+				if end >= size {
+					end = size - 1
+				}
+			}
+			if start >= size {
+				// not satisfiable
+				continue
+			}
+			r = byteRange{start: start, end: end}
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) > maxRanges {
+		return nil, false
+	}
+	var total int64
+	for _, r := range ranges {
+		total += r.length()
+	}
+	if total > size {
+		return nil, false
+	}
+	return ranges, specs > 0
+}
+
+// fileETag generates the ETag of a file from its size and modification time,
+// the ETag of a compressed variant is weak and contains the content encoding
+func fileETag(info os.FileInfo, encoding string) string {
+	etag := fmt.Sprintf("%x-%x", info.Size(), info.ModTime().UnixNano())
+	if encoding != "" {
+		return fmt.Sprintf("W/\"%s-%s\"", etag, encoding)
+	}
+	return "\"" + etag + "\""
+}
+
+// jsonStreamEncode writes the JSON encoding of v to w, followed by a newline
+func jsonStreamEncode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// jsonStreamEncodeNoEscapeHTML works like jsonStreamEncode, but doesn't escape HTML characters
+func jsonStreamEncodeNoEscapeHTML(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// jsonMarshalNoEscapeHTML returns the JSON encoding of v like json.Marshal,
+// but doesn't escape HTML characters
+func jsonMarshalNoEscapeHTML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jsonStreamEncodeNoEscapeHTML(&buf, v); err != nil {
+		return nil, err
+	}
+	// json.Marshal doesn't add the newline of the encoder
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonUnmarshalUseNumber parses the JSON-encoded data like json.Unmarshal,
+// but decodes numbers into a json.Number instead of a float64
+func jsonUnmarshalUseNumber(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
 func getGroupPath(prefix, path string) string {
 	if len(path) == 0 || path == "/" {
 		return prefix
@@ -233,40 +571,193 @@ func getGroupPath(prefix, path string) string {
 	return utils.TrimRight(prefix, '/') + path
 }
 
-// return valid offer for header negotiation
-func getOffer(header string, offers ...string) string {
+// JoinPath joins a route prefix and a path the same way groups do:
+// the slash between them is collapsed and the result always starts with a slash.
+//
+//	fiber.JoinPath("/api/", "/users") // "/api/users"
+//	fiber.JoinPath("api", "users")    // "/api/users"
+//	fiber.JoinPath("/api", "/")       // "/api"
+func JoinPath(prefix, path string) string {
+	joined := getGroupPath(prefix, path)
+	if len(joined) == 0 || joined[0] != '/' {
+		return "/" + joined
+	}
+	return joined
+}
+
+// acceptedType is a parsed entry of an Accept-* header
+type acceptedType struct {
+	spec        string
+	quality     float64
+	specificity int
+}
+
+// parseAccept parses the entries of an Accept-* header with their quality values,
+// a missing or malformed quality value is treated as 1
+func parseAccept(header string) []acceptedType {
+	var accepted []acceptedType
+	for _, entry := range strings.Split(header, ",") {
+		spec, params := utils.Trim(entry, ' '), ""
+		if factorSign := strings.IndexByte(spec, ';'); factorSign != -1 {
+			spec, params = utils.TrimRight(spec[:factorSign], ' '), spec[factorSign+1:]
+		}
+		if spec == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			param = utils.Trim(param, ' ')
+			if len(param) < 2 || (param[0] != 'q' && param[0] != 'Q') || param[1] != '=' {
+				continue
+			}
+			if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q >= 0 && q <= 1 {
+				quality = q
+			}
+		}
+		specificity := 2
+		if spec == "*" || spec == "*/*" {
+			specificity = 0
+		} else if strings.HasSuffix(spec, "/*") {
+			specificity = 1
+		}
+		accepted = append(accepted, acceptedType{spec: spec, quality: quality, specificity: specificity})
+	}
+	return accepted
+}
+
+// acceptEncodingCacheSize limits the number of cached Accept-Encoding headers, clients send only
+// a handful of distinct values like "gzip, deflate, br", rarely used ones are evicted
+const acceptEncodingCacheSize = 128
+
+// acceptEncodingEntry is a cached Accept-Encoding header, used is set on every hit
+// and cleared by the eviction, so that recently used headers get a second chance
+type acceptEncodingEntry struct {
+	header   string
+	accepted []acceptedType
+	used     uint32
+}
+
+// acceptEncodingCache holds the parsed entries of Accept-Encoding headers by the raw header value.
+// Hits only take the read lock, the recency is approximated by a clock over the ring of entries.
+var acceptEncodingCache = struct {
+	sync.RWMutex
+	entries map[string]*acceptEncodingEntry
+	ring    []*acceptEncodingEntry
+	hand    int
+}{entries: make(map[string]*acceptEncodingEntry, acceptEncodingCacheSize)}
+
+// parseAcceptEncoding parses the Accept-Encoding header like parseAccept, the result is
+// cached by the header value and shared between requests, so it must not be modified
+func parseAcceptEncoding(header string) []acceptedType {
+	acceptEncodingCache.RLock()
+	entry, ok := acceptEncodingCache.entries[header]
+	acceptEncodingCache.RUnlock()
+	if ok {
+		// avoid writing the shared cache line if the entry is already marked
+		if atomic.LoadUint32(&entry.used) == 0 {
+			atomic.StoreUint32(&entry.used, 1)
+		}
+		return entry.accepted
+	}
+	// the header may point into the request buffer, the cached entries keep a copy
+	header = utils.CopyString(header)
+	accepted := parseAccept(header)
+	acceptEncodingCache.Lock()
+	if _, ok := acceptEncodingCache.entries[header]; !ok {
+		entry = &acceptEncodingEntry{header: header, accepted: accepted}
+		if len(acceptEncodingCache.ring) < acceptEncodingCacheSize {
+			acceptEncodingCache.ring = append(acceptEncodingCache.ring, entry)
+		} else {
+			// advance the hand to the first entry which hasn't been used since the last pass
+			for atomic.CompareAndSwapUint32(&acceptEncodingCache.ring[acceptEncodingCache.hand].used, 1, 0) {
+				acceptEncodingCache.hand = (acceptEncodingCache.hand + 1) % acceptEncodingCacheSize
+			}
+			delete(acceptEncodingCache.entries, acceptEncodingCache.ring[acceptEncodingCache.hand].header)
+			acceptEncodingCache.ring[acceptEncodingCache.hand] = entry
+			acceptEncodingCache.hand = (acceptEncodingCache.hand + 1) % acceptEncodingCacheSize
+		}
+		acceptEncodingCache.entries[header] = entry
+	}
+	acceptEncodingCache.Unlock()
+	return accepted
+}
+
+// acceptsOffer checks if the spec of an Accept-Charset, Accept-Encoding or Accept-Language header accepts the offer
+func acceptsOffer(spec, offer string) bool {
+	return spec[len(spec)-1] == '*' || strings.HasPrefix(spec, offer)
+}
+
+// acceptsLanguageOffer checks if the language range of an Accept-Language header accepts the offered
+// language tag case-insensitively, a range like "en" accepts "en-US" and the other way around
+func acceptsLanguageOffer(spec, offer string) bool {
+	if spec == "*" || utils.EqualFold(spec, offer) {
+		return true
+	}
+	return isLanguagePrefix(offer, spec) || isLanguagePrefix(spec, offer)
+}
+
+// isLanguagePrefix checks if the language tag starts with the subtags of the prefix, e.g. "en-US" with "en"
+func isLanguagePrefix(tag, prefix string) bool {
+	return len(tag) > len(prefix) && tag[len(prefix)] == '-' && utils.EqualFold(tag[:len(prefix)], prefix)
+}
+
+// acceptsOfferType checks if the spec of an Accept header accepts the offered extension or content type
+func acceptsOfferType(spec, offer string) bool {
+	if spec == "*/*" {
+		return true
+	}
+	var mimetype string
+	if strings.IndexByte(offer, '/') != -1 {
+		mimetype = offer // MIME type
+	} else {
+		mimetype = utils.GetMIME(offer) // extension
+	}
+	if spec == mimetype {
+		// Accept: <MIME_type>/<MIME_subtype>
+		return true
+	}
+	s := strings.IndexByte(mimetype, '/')
+	// Accept: <MIME_type>/*
+	return s != -1 && strings.HasPrefix(spec, mimetype[:s]) && (spec[s:] == "/*" || mimetype[s:] == "/*")
+}
+
+// return valid offer for header negotiation, the offer with the highest quality value
+// of the most specific accepting entry wins, an entry equal to the offer is the most specific.
+// Ties are resolved by the order of the header entries, then by the order of the offers.
+// Offers with a quality value of 0 are never returned.
+func getOffer(header string, isAccepted func(spec, offer string) bool, offers ...string) string {
 	if len(offers) == 0 {
 		return ""
 	} else if header == "" {
 		return offers[0]
 	}
+	return negotiateOffer(parseAccept(header), isAccepted, offers...)
+}
 
-	spec, commaPos := "", 0
-	for len(header) > 0 && commaPos != -1 {
-		commaPos = strings.IndexByte(header, ',')
-		if commaPos != -1 {
-			spec = utils.Trim(header[:commaPos], ' ')
-		} else {
-			spec = header
-		}
-		if factorSign := strings.IndexByte(spec, ';'); factorSign != -1 {
-			spec = spec[:factorSign]
+// negotiateOffer returns the best offer for the parsed entries of an Accept-* header, see getOffer
+func negotiateOffer(accepted []acceptedType, isAccepted func(spec, offer string) bool, offers ...string) string {
+	bestOffer, bestQuality, bestOrder := "", 0.0, 0
+	for _, offer := range offers {
+		if len(offer) == 0 {
+			continue
 		}
-
-		for _, offer := range offers {
-			// has star prefix
-			if len(spec) >= 1 && spec[len(spec)-1] == '*' {
-				return offer
-			} else if strings.HasPrefix(spec, offer) {
-				return offer
+		// find the most specific entry accepting the offer
+		quality, order, specificity := 0.0, 0, -1
+		for i := range accepted {
+			entrySpecificity := accepted[i].specificity
+			if utils.EqualFold(accepted[i].spec, offer) {
+				entrySpecificity++
+			}
+			if entrySpecificity > specificity && isAccepted(accepted[i].spec, offer) {
+				quality, order, specificity = accepted[i].quality, i, entrySpecificity
 			}
 		}
-		if commaPos != -1 {
-			header = header[commaPos+1:]
+		if quality > bestQuality || (quality > 0 && quality == bestQuality && order < bestOrder) {
+			bestOffer, bestQuality, bestOrder = offer, quality, order
 		}
 	}
 
-	return ""
+	return bestOffer
 }
 
 func matchEtag(s string, etag string) bool {
@@ -303,6 +794,21 @@ func (app *App) isEtagStale(etag string, noneMatchBytes []byte) bool {
 	return !matchEtag(app.getString(noneMatchBytes[start:end]), etag)
 }
 
+// isEtagMatch checks if one of the etags of the If-Match header matches the etag,
+// weak etags never match as If-Match requires the strong comparison
+func isEtagMatch(etag, ifMatch string) bool {
+	if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, s := range strings.Split(ifMatch, ",") {
+		s = utils.Trim(s, ' ')
+		if !strings.HasPrefix(s, "W/") && matchEtag(s, etag) {
+			return true
+		}
+	}
+	return false
+}
+
 func parseAddr(raw string) (host, port string) {
 	if i := strings.LastIndex(raw, ":"); i != -1 {
 		return raw[:i], raw[i+1:]
@@ -312,6 +818,55 @@ func parseAddr(raw string) (host, port string) {
 
 const noCacheValue = "no-cache"
 
+// immutableCacheControl is the Cache-Control header of immutable files, cached for one year
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// isDigits reports whether s is a non-empty string of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isToken checks if s is a non-empty token as defined in RFC 7230, section 3.2.6,
+// which excludes separators like ',', ';', '"' and whitespace or control characters
+func isToken(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", b) != -1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// hasHeaderToken checks case-insensitive if the comma-separated header value contains the token
+func hasHeaderToken(header, token string) bool {
+	for len(header) > 0 {
+		var part string
+		if i := strings.IndexByte(header, ','); i != -1 {
+			part, header = header[:i], header[i+1:]
+		} else {
+			part, header = header, ""
+		}
+		if utils.EqualFold(utils.Trim(part, ' '), token) {
+			return true
+		}
+	}
+	return false
+}
+
 // isNoCache checks if the cacheControl header value is a `no-cache`.
 func isNoCache(cacheControl string) bool {
 	i := strings.Index(cacheControl, noCacheValue)
@@ -604,6 +1159,7 @@ const (
 	HeaderVia                             = "Via"
 	HeaderXForwardedFor                   = "X-Forwarded-For"
 	HeaderXForwardedHost                  = "X-Forwarded-Host"
+	HeaderXForwardedPort                  = "X-Forwarded-Port"
 	HeaderXForwardedProto                 = "X-Forwarded-Proto"
 	HeaderXForwardedProtocol              = "X-Forwarded-Protocol"
 	HeaderXForwardedSsl                   = "X-Forwarded-Ssl"
@@ -665,6 +1221,7 @@ const (
 	HeaderSourceMap               = "SourceMap"
 	HeaderUpgrade                 = "Upgrade"
 	HeaderXDNSPrefetchControl     = "X-DNS-Prefetch-Control"
+	HeaderXHTTPMethodOverride     = "X-HTTP-Method-Override"
 	HeaderXPingback               = "X-Pingback"
 	HeaderXRequestID              = "X-Request-ID"
 	HeaderXRequestedWith          = "X-Requested-With"
@@ -672,11 +1229,15 @@ const (
 	HeaderXUACompatible           = "X-UA-Compatible"
 )
 
+// webSocketVersion is the WebSocket protocol version of RFC 6455
+const webSocketVersion = "13"
+
 // Network types that are commonly used
 const (
 	NetworkTCP  = "tcp"
 	NetworkTCP4 = "tcp4"
 	NetworkTCP6 = "tcp6"
+	NetworkUnix = "unix"
 )
 
 // Compression types
@@ -715,4 +1276,6 @@ const (
 	ConstraintRange           = "range"
 	ConstraintDatetime        = "datetime"
 	ConstraintRegex           = "regex"
+	ConstraintEnum            = "enum"
+	ConstraintEnumIgnoreCase  = "ienum"
 )
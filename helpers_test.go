@@ -5,9 +5,12 @@
 package fiber
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -58,6 +61,60 @@ func Test_Utils_ETag(t *testing.T) {
 	})
 }
 
+// go test -run Test_Utils_ETag_Generator
+func Test_Utils_ETag_Generator(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		ETagGenerator: func(body []byte, weak bool) string {
+			if string(body) == "no etag" {
+				return ""
+			}
+			sum := sha256.Sum256(body)
+			etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+			if weak {
+				etag = "W/" + etag
+			}
+			return etag
+		},
+	})
+
+	t.Run("Strong", func(t *testing.T) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		utils.AssertEqual(t, nil, c.SendString("Hello, World!"))
+		setETag(c, false)
+		utils.AssertEqual(t, `"dffd6021bb2bd5b0"`, string(c.Response().Header.Peek(HeaderETag)))
+	})
+
+	t.Run("Weak", func(t *testing.T) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		utils.AssertEqual(t, nil, c.SendString("Hello, World!"))
+		setETag(c, true)
+		utils.AssertEqual(t, `W/"dffd6021bb2bd5b0"`, string(c.Response().Header.Peek(HeaderETag)))
+	})
+
+	t.Run("Has HeaderIfNoneMatch", func(t *testing.T) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		utils.AssertEqual(t, nil, c.SendString("Hello, World!"))
+		c.Request().Header.Set(HeaderIfNoneMatch, `"other", W/"dffd6021bb2bd5b0"`)
+		setETag(c, false)
+		utils.AssertEqual(t, 304, c.Response().StatusCode())
+		utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderETag)))
+		utils.AssertEqual(t, "", string(c.Response().Body()))
+	})
+
+	t.Run("Empty ETag", func(t *testing.T) {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		defer app.ReleaseCtx(c)
+		utils.AssertEqual(t, nil, c.SendString("no etag"))
+		setETag(c, false)
+		utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderETag)))
+		utils.AssertEqual(t, 200, c.Response().StatusCode())
+	})
+}
+
 // go test -v -run=^$ -bench=Benchmark_App_ETag -benchmem -count=4
 func Benchmark_Utils_ETag(b *testing.B) {
 	app := New()
@@ -168,6 +225,29 @@ func Test_Utils_getGroupPath(t *testing.T) {
 	utils.AssertEqual(t, "/v1/api", res)
 }
 
+// go test -run Test_Utils_JoinPath
+func Test_Utils_JoinPath(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		prefix, path, expected string
+	}{
+		{prefix: "/v1", path: "/", expected: "/v1"},
+		{prefix: "/v1/", path: "/", expected: "/v1/"},
+		{prefix: "/", path: "/", expected: "/"},
+		{prefix: "/v1/api", path: "group", expected: "/v1/api/group"},
+		{prefix: "/v1/api/", path: "/group", expected: "/v1/api/group"},
+		{prefix: "/v1/api", path: "", expected: "/v1/api"},
+		{prefix: "v1", path: "api", expected: "/v1/api"},
+		{prefix: "", path: "api", expected: "/api"},
+		{prefix: "", path: "/", expected: "/"},
+		{prefix: "", path: "", expected: "/"},
+		{prefix: "/", path: "", expected: "/"},
+	}
+	for _, tc := range testCases {
+		utils.AssertEqual(t, tc.expected, JoinPath(tc.prefix, tc.path), tc.prefix+" + "+tc.path)
+	}
+}
+
 // go test -v -run=^$ -bench=Benchmark_Utils_ -benchmem -count=3
 
 func Benchmark_Utils_getGroupPath(b *testing.B) {
@@ -212,9 +292,83 @@ func Test_Utils_Parse_Address(t *testing.T) {
 }
 
 func Test_Utils_GetOffset(t *testing.T) {
-	utils.AssertEqual(t, "", getOffer("hello"))
-	utils.AssertEqual(t, "1", getOffer("", "1"))
-	utils.AssertEqual(t, "", getOffer("2", "1"))
+	utils.AssertEqual(t, "", getOffer("hello", acceptsOffer))
+	utils.AssertEqual(t, "1", getOffer("", acceptsOffer, "1"))
+	utils.AssertEqual(t, "", getOffer("2", acceptsOffer, "1"))
+}
+
+// go test -run Test_Utils_GetOffer_Quality
+func Test_Utils_GetOffer_Quality(t *testing.T) {
+	t.Parallel()
+	utils.AssertEqual(t, "json", getOffer("text/html;q=0.2, application/json;q=0.9", acceptsOfferType, "html", "json"))
+	utils.AssertEqual(t, "html", getOffer("text/html, application/json", acceptsOfferType, "html", "json"))
+	utils.AssertEqual(t, "json", getOffer("text/html;q=0.5, */*;q=0.8", acceptsOfferType, "html", "json"))
+	utils.AssertEqual(t, "html", getOffer("text/html;q=0.9, application/json;level=1;q=0.8", acceptsOfferType, "json", "html"))
+	// q=0 excludes the offer, even if a wildcard accepts it
+	utils.AssertEqual(t, "", getOffer("text/html;q=0", acceptsOfferType, "html"))
+	utils.AssertEqual(t, "json", getOffer("text/html;q=0, */*", acceptsOfferType, "html", "json"))
+	utils.AssertEqual(t, "deflate", getOffer("gzip;q=0, *;q=0.5", acceptsOffer, "gzip", "deflate"))
+	// malformed q-values count as q=1
+	utils.AssertEqual(t, "br", getOffer("gzip;q=0.5, br;q=abc", acceptsOffer, "gzip", "br"))
+	utils.AssertEqual(t, "br", getOffer("gzip;q=0.5, br;q=2", acceptsOffer, "gzip", "br"))
+	utils.AssertEqual(t, "de", getOffer("en;q=0.5, de;Q=0.7", acceptsOffer, "en", "de"))
+}
+
+// go test -run Test_Utils_parseAcceptEncoding
+func Test_Utils_parseAcceptEncoding(t *testing.T) {
+	t.Parallel()
+	header := []byte("gzip, deflate;q=0.5, br")
+	accepted := parseAcceptEncoding(utils.UnsafeString(header))
+	utils.AssertEqual(t, parseAccept("gzip, deflate;q=0.5, br"), accepted)
+
+	// the cached entries don't refer to the buffer of the header
+	copy(header, "xxxx")
+	utils.AssertEqual(t, "gzip", parseAcceptEncoding("gzip, deflate;q=0.5, br")[0].spec)
+
+	// the cache is limited, headers which aren't used again are evicted
+	for i := 0; i < 2*acceptEncodingCacheSize; i++ {
+		utils.AssertEqual(t, "gzip", parseAcceptEncoding("gzip;q=0." + strconv.Itoa(i))[0].spec)
+	}
+	acceptEncodingCache.Lock()
+	utils.AssertEqual(t, true, len(acceptEncodingCache.entries) <= acceptEncodingCacheSize)
+	utils.AssertEqual(t, len(acceptEncodingCache.entries), len(acceptEncodingCache.ring))
+	_, ok := acceptEncodingCache.entries["gzip;q=0."+strconv.Itoa(2*acceptEncodingCacheSize-1)]
+	acceptEncodingCache.Unlock()
+	utils.AssertEqual(t, true, ok)
+
+	// a header in use stays cached while other headers fill the cache
+	parseAcceptEncoding("br, zstd")
+	for i := 0; i < 2*acceptEncodingCacheSize; i++ {
+		parseAcceptEncoding("br, zstd")
+		parseAcceptEncoding("deflate;q=0." + strconv.Itoa(i))
+	}
+	acceptEncodingCache.Lock()
+	_, ok = acceptEncodingCache.entries["br, zstd"]
+	acceptEncodingCache.Unlock()
+	utils.AssertEqual(t, true, ok)
+}
+
+// go test -v -run=^$ -bench=Benchmark_Utils_parseAcceptEncoding -benchmem -count=4
+func Benchmark_Utils_parseAcceptEncoding(b *testing.B) {
+	header := "gzip, deflate, br"
+	b.Run("cached", func(b *testing.B) {
+		var res []acceptedType
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			res = parseAcceptEncoding(header)
+		}
+		utils.AssertEqual(b, 3, len(res))
+	})
+	b.Run("parsed", func(b *testing.B) {
+		var res []acceptedType
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			res = parseAccept(header)
+		}
+		utils.AssertEqual(b, 3, len(res))
+	})
 }
 
 func Test_Utils_TestConn_Deadline(t *testing.T) {
@@ -246,6 +400,16 @@ func Test_Utils_IsNoCache(t *testing.T) {
 	}
 }
 
+// go test -run Test_Utils_isCompressibleType
+func Test_Utils_isCompressibleType(t *testing.T) {
+	t.Parallel()
+	types := []string{"", "text/", MIMEApplicationJSON}
+	utils.AssertEqual(t, true, isCompressibleType(MIMETextHTML, types))
+	utils.AssertEqual(t, true, isCompressibleType(MIMEApplicationJSON, types))
+	utils.AssertEqual(t, false, isCompressibleType("image/png", types))
+	utils.AssertEqual(t, false, isCompressibleType("", types))
+}
+
 // go test -v -run=^$ -bench=Benchmark_Utils_IsNoCache -benchmem -count=4
 func Benchmark_Utils_IsNoCache(b *testing.B) {
 	var ok bool
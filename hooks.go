@@ -7,6 +7,7 @@ type OnGroupHandler = func(Group) error
 type OnGroupNameHandler = OnGroupHandler
 type OnListenHandler = func() error
 type OnShutdownHandler = OnListenHandler
+type OnPreShutdownHandler = OnListenHandler
 type OnForkHandler = func(int) error
 
 // Hooks is a struct to use it with App.
@@ -15,25 +16,27 @@ type Hooks struct {
 	app *App
 
 	// Hooks
-	onRoute     []OnRouteHandler
-	onName      []OnNameHandler
-	onGroup     []OnGroupHandler
-	onGroupName []OnGroupNameHandler
-	onListen    []OnListenHandler
-	onShutdown  []OnShutdownHandler
-	onFork      []OnForkHandler
+	onRoute       []OnRouteHandler
+	onName        []OnNameHandler
+	onGroup       []OnGroupHandler
+	onGroupName   []OnGroupNameHandler
+	onListen      []OnListenHandler
+	onShutdown    []OnShutdownHandler
+	onPreShutdown []OnPreShutdownHandler
+	onFork        []OnForkHandler
 }
 
 func newHooks(app *App) *Hooks {
 	return &Hooks{
-		app:         app,
-		onRoute:     make([]OnRouteHandler, 0),
-		onGroup:     make([]OnGroupHandler, 0),
-		onGroupName: make([]OnGroupNameHandler, 0),
-		onName:      make([]OnNameHandler, 0),
-		onListen:    make([]OnListenHandler, 0),
-		onShutdown:  make([]OnShutdownHandler, 0),
-		onFork:      make([]OnForkHandler, 0),
+		app:           app,
+		onRoute:       make([]OnRouteHandler, 0),
+		onGroup:       make([]OnGroupHandler, 0),
+		onGroupName:   make([]OnGroupNameHandler, 0),
+		onName:        make([]OnNameHandler, 0),
+		onListen:      make([]OnListenHandler, 0),
+		onShutdown:    make([]OnShutdownHandler, 0),
+		onPreShutdown: make([]OnPreShutdownHandler, 0),
+		onFork:        make([]OnForkHandler, 0),
 	}
 }
 
@@ -87,6 +90,14 @@ func (h *Hooks) OnShutdown(handler ...OnShutdownHandler) {
 	h.app.mutex.Unlock()
 }
 
+// OnPreShutdown is a hook to execute user functions once Shutdown or ShutdownWithTimeout begins
+// draining the connections, while handlers may still be running, e.g. to fail readiness checks.
+func (h *Hooks) OnPreShutdown(handler ...OnPreShutdownHandler) {
+	h.app.mutex.Lock()
+	h.onPreShutdown = append(h.onPreShutdown, handler...)
+	h.app.mutex.Unlock()
+}
+
 // OnFork is a hook to execute user function after fork process.
 func (h *Hooks) OnFork(handler ...OnForkHandler) {
 	h.app.mutex.Lock()
@@ -150,6 +161,12 @@ func (h *Hooks) executeOnShutdownHooks() {
 	}
 }
 
+func (h *Hooks) executeOnPreShutdownHooks() {
+	for _, v := range h.onPreShutdown {
+		_ = v()
+	}
+}
+
 func (h *Hooks) executeOnForkHooks(pid int) {
 	for _, v := range h.onFork {
 		_ = v(pid)
@@ -30,6 +30,8 @@ type cache struct {
 	m       map[reflect.Type]*structInfo
 	regconv map[reflect.Type]Converter
 	tag     string
+	// fallbackTags are used for fields without the tag
+	fallbackTags []string
 }
 
 // registerConverter registers a converter function for a custom type.
@@ -152,7 +154,7 @@ func (c *cache) create(t reflect.Type, parentAlias string) *structInfo {
 
 // createField creates a fieldInfo for the given field.
 func (c *cache) createField(field reflect.StructField, parentAlias string) *fieldInfo {
-	alias, options := fieldAlias(field, c.tag)
+	alias, options := fieldAlias(field, c.tag, c.fallbackTags...)
 	if alias == "-" {
 		// Ignore this field.
 		return nil
@@ -272,9 +274,14 @@ func indirectType(typ reflect.Type) reflect.Type {
 	return typ
 }
 
-// fieldAlias parses a field tag to get a field alias.
-func fieldAlias(field reflect.StructField, tagName string) (alias string, options tagOptions) {
-	if tag := field.Tag.Get(tagName); tag != "" {
+// fieldAlias parses a field tag to get a field alias,
+// the first of the tag names which is present is used.
+func fieldAlias(field reflect.StructField, tagName string, fallbackTags ...string) (alias string, options tagOptions) {
+	tag := field.Tag.Get(tagName)
+	for i := 0; tag == "" && i < len(fallbackTags); i++ {
+		tag = field.Tag.Get(fallbackTags[i])
+	}
+	if tag != "" {
 		alias, options = parseTag(tag)
 	}
 	if alias == "" {
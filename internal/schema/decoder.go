@@ -25,9 +25,11 @@ type Decoder struct {
 }
 
 // SetAliasTag changes the tag used to locate custom field aliases.
-// The default tag is "schema".
-func (d *Decoder) SetAliasTag(tag string) {
+// The default tag is "schema". The fallback tags are used in order
+// for fields without the tag.
+func (d *Decoder) SetAliasTag(tag string, fallbackTags ...string) {
 	d.cache.tag = tag
+	d.cache.fallbackTags = fallbackTags
 }
 
 // ZeroEmpty controls the behaviour when the decoder encounters empty values
@@ -59,6 +61,11 @@ func (d *Decoder) RegisterConverter(value interface{}, converterFunc Converter)
 	d.cache.registerConverter(value, converterFunc)
 }
 
+// Converter returns the converter function registered for the type of value, or nil.
+func (d *Decoder) Converter(value interface{}) Converter {
+	return d.cache.converter(reflect.TypeOf(value))
+}
+
 // Decode decodes a map[string][]string to a struct.
 //
 // The first parameter must be a pointer to a struct.
@@ -59,8 +59,8 @@ func (app *App) Listen(addr string) error {
 		return app.prefork(app.config.Network, addr, nil)
 	}
 
-	// Setup listener
-	ln, err := net.Listen(app.config.Network, addr)
+	// Setup listener, inherited from the old process after a graceful restart
+	ln, err := app.gracefulListen(app.config.Network, addr)
 	if err != nil {
 		return err
 	}
@@ -78,8 +78,75 @@ func (app *App) Listen(addr string) error {
 		app.printRoutesMessage()
 	}
 
-	// Start listening
-	return app.server.Serve(ln)
+	// Start listening, restart on signal if enabled
+	return app.serveGraceful(ln)
+}
+
+// ListenUnix serves HTTP requests on the Unix domain socket at the given path, e.g. behind
+// a proxy or for a sidecar. A stale socket file of a previous run is replaced on startup, a
+// socket in use by another process is not. The socket is created with the permissions set
+// to mode before it's accessible at path, the file is removed on shutdown.
+// Prefork is not supported.
+//
+//	app.ListenUnix("/var/run/fiber.sock", 0o660)
+func (app *App) ListenUnix(path string, mode os.FileMode) error {
+	if app.config.Prefork {
+		return errors.New("unix: prefork is not supported")
+	}
+
+	// Only stale sockets are replaced, other files are never removed
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("unix: %q exists and is not a socket", path)
+		}
+		if conn, err := net.Dial(NetworkUnix, path); err == nil {
+			_ = conn.Close()
+			return fmt.Errorf("unix: %q is in use by another process", path)
+		}
+	}
+
+	// The socket is created in a private directory next to path and moved into place once
+	// its permissions are set, so that it's never accessible with the default permissions
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".fiber-unix-")
+	if err != nil {
+		return fmt.Errorf("unix: cannot create socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	tmpPath := filepath.Join(dir, "fiber.sock")
+	ln, err := net.ListenUnix(NetworkUnix, &net.UnixAddr{Name: tmpPath, Net: NetworkUnix})
+	if err != nil {
+		return err
+	}
+	// the socket is removed at its final path, see unixListener
+	ln.SetUnlinkOnClose(false)
+	if err = os.Chmod(tmpPath, mode); err != nil {
+		_ = ln.Close()
+		return fmt.Errorf("unix: cannot set permissions of %q: %w", path, err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		_ = ln.Close()
+		return fmt.Errorf("unix: cannot move socket to %q: %w", path, err)
+	}
+
+	return app.Listener(&unixListener{UnixListener: ln, path: path})
+}
+
+// unixListener is the listener of ListenUnix, which reports and removes the socket at its final path
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+// Addr returns the final path of the socket
+func (ln *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: ln.path, Net: NetworkUnix}
+}
+
+// Close stops listening and removes the socket file
+func (ln *unixListener) Close() error {
+	err := ln.UnixListener.Close()
+	_ = os.Remove(ln.path)
+	return err
 }
 
 // ListenTLS serves HTTPS requests from the given addr.
@@ -289,7 +356,10 @@ func (app *App) startupMessage(addr string, tls bool, pids string) {
 	}
 	mainLogo += " │ " + centerValue("Fiber v"+Version, 49) + " │\n"
 
-	if host == "0.0.0.0" {
+	if port == "" {
+		// unix domain socket
+		mainLogo += " │ " + center(NetworkUnix+":"+host, 49) + " │\n"
+	} else if host == "0.0.0.0" {
 		mainLogo +=
 			" │ " + center(fmt.Sprintf("%s://127.0.0.1:%s", scheme, port), 49) + " │\n" +
 				" │ " + center(fmt.Sprintf("(bound on host 0.0.0.0 and port %s)", port), 49) + " │\n"
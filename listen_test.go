@@ -5,12 +5,17 @@
 package fiber
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/tls"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -114,6 +119,71 @@ func Test_App_Listener(t *testing.T) {
 	utils.AssertEqual(t, nil, app.Listener(ln))
 }
 
+// go test -run Test_App_ListenUnix
+func Test_App_ListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fiber.sock")
+
+	// leave a stale socket file behind
+	stale, err := net.Listen(NetworkUnix, path)
+	utils.AssertEqual(t, nil, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	utils.AssertEqual(t, nil, stale.Close())
+
+	app := New(Config{DisableStartupMessage: true})
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString("unix")
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- app.ListenUnix(path, 0o600)
+	}()
+
+	var conn net.Conn
+	for i := 0; i < 100; i++ {
+		if conn, err = net.Dial(NetworkUnix, path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	utils.AssertEqual(t, nil, err)
+	defer conn.Close()
+
+	info, err := os.Stat(path)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "unix", string(body))
+	utils.AssertEqual(t, nil, resp.Body.Close())
+	utils.AssertEqual(t, nil, conn.Close())
+
+	// the socket file is removed on shutdown
+	utils.AssertEqual(t, nil, app.Shutdown())
+	utils.AssertEqual(t, nil, <-done)
+	_, err = os.Stat(path)
+	utils.AssertEqual(t, true, os.IsNotExist(err))
+
+	// sockets in use are not replaced
+	other, err := net.Listen(NetworkUnix, path)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fmt.Sprintf("unix: %q is in use by another process", path), New().ListenUnix(path, 0o600).Error())
+	utils.AssertEqual(t, nil, other.Close())
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(files))
+
+	// other files are not replaced
+	utils.AssertEqual(t, nil, ioutil.WriteFile(path, []byte("data"), 0o600))
+	utils.AssertEqual(t, fmt.Sprintf("unix: %q exists and is not a socket", path), New().ListenUnix(path, 0o600).Error())
+}
+
 // go test -run Test_App_Listener_Prefork
 func Test_App_Listener_Prefork(t *testing.T) {
 	testPreforkMaster = true
@@ -188,6 +258,13 @@ func Test_App_Master_Process_Show_Startup_Message(t *testing.T) {
 	utils.AssertEqual(t, true, strings.Contains(startupMessage, "Prefork ........ Enabled"))
 }
 
+func Test_App_Master_Process_Show_Startup_Message_Unix(t *testing.T) {
+	startupMessage := captureOutput(func() {
+		New().startupMessage("/var/run/fiber.sock", false, "")
+	})
+	utils.AssertEqual(t, true, strings.Contains(startupMessage, "unix:/var/run/fiber.sock"))
+}
+
 func Test_App_Master_Process_Show_Startup_MessageWithAppName(t *testing.T) {
 	app := New(Config{Prefork: true, AppName: "Test App v1.0.1"})
 	startupMessage := captureOutput(func() {
@@ -0,0 +1,28 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+//go:build go1.21
+// +build go1.21
+
+// The module supports Go versions without generics, this file is only
+// compiled by Go versions which upgrade the language version of the file.
+
+package fiber
+
+// Locals returns the value stored with c.Locals under the key as type T.
+// The default value, or the zero value of T, is returned if the key is missing
+// or the stored value is not a T. It is a function as methods cannot have type parameters.
+//
+//	fiber.Locals[*User](c, userKey)
+//	fiber.Locals[string](c, "requestid", "unknown")
+func Locals[T any](c *Ctx, key interface{}, defaultValue ...T) T {
+	if v, ok := c.Locals(key).(T); ok {
+		return v
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	var zero T
+	return zero
+}
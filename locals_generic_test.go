@@ -0,0 +1,40 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+//go:build go1.21
+// +build go1.21
+
+package fiber
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// go test -run Test_Locals_Generic
+func Test_Locals_Generic(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type user struct {
+		Name string
+	}
+	type userKey struct{}
+	john := &user{Name: "john"}
+	c.Locals(userKey{}, john)
+	c.Locals("count", 3)
+
+	utils.AssertEqual(t, john, Locals[*user](c, userKey{}))
+	utils.AssertEqual(t, 3, Locals[int](c, "count"))
+
+	// missing keys and mismatching types
+	utils.AssertEqual(t, (*user)(nil), Locals[*user](c, "missing"))
+	utils.AssertEqual(t, "", Locals[string](c, "count"))
+	utils.AssertEqual(t, "default", Locals[string](c, "count", "default"))
+	utils.AssertEqual(t, 0, Locals[int](c, userKey{}))
+}
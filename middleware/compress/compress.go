@@ -2,6 +2,7 @@ package compress
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
 )
 
@@ -12,36 +13,32 @@ func New(config ...Config) fiber.Handler {
 
 	// Setup request handlers
 	var (
-		fctx       = func(c *fasthttp.RequestCtx) {}
-		compressor fasthttp.RequestHandler
+		fctx        = func(c *fasthttp.RequestCtx) {}
+		brotliLevel int
+		otherLevel  int
 	)
 
 	// Setup compression algorithm
 	switch cfg.Level {
 	case LevelDefault:
 		// LevelDefault
-		compressor = fasthttp.CompressHandlerBrotliLevel(fctx,
-			fasthttp.CompressBrotliDefaultCompression,
-			fasthttp.CompressDefaultCompression,
-		)
+		brotliLevel, otherLevel = fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression
 	case LevelBestSpeed:
 		// LevelBestSpeed
-		compressor = fasthttp.CompressHandlerBrotliLevel(fctx,
-			fasthttp.CompressBrotliBestSpeed,
-			fasthttp.CompressBestSpeed,
-		)
+		brotliLevel, otherLevel = fasthttp.CompressBrotliBestSpeed, fasthttp.CompressBestSpeed
 	case LevelBestCompression:
 		// LevelBestCompression
-		compressor = fasthttp.CompressHandlerBrotliLevel(fctx,
-			fasthttp.CompressBrotliBestCompression,
-			fasthttp.CompressBestCompression,
-		)
+		brotliLevel, otherLevel = fasthttp.CompressBrotliBestCompression, fasthttp.CompressBestCompression
 	default:
 		// LevelDisabled
 		return func(c *fiber.Ctx) error {
 			return c.Next()
 		}
 	}
+	if cfg.BrotliLevel > 0 {
+		brotliLevel = cfg.BrotliLevel
+	}
+	compressor := fasthttp.CompressHandlerBrotliLevel(fctx, brotliLevel, otherLevel)
 
 	// Return new handler
 	return func(c *fiber.Ctx) error {
@@ -55,8 +52,30 @@ func New(config ...Config) fiber.Handler {
 			return err
 		}
 
-		// Compress response
+		// Skip short bodies, the length of streamed bodies is unknown
+		if cfg.MinLength > 0 && !c.Response().IsBodyStream() && len(c.Response().Body()) < cfg.MinLength {
+			return nil
+		}
+
+		// Select the encoding with the quality values of the Accept-Encoding header
+		acceptEncoding := c.Get(fiber.HeaderAcceptEncoding)
+		if acceptEncoding == "" {
+			return nil
+		}
+		encoding := c.AcceptsEncodings(fiber.StrBr, fiber.StrGzip, fiber.StrDeflate)
+		if encoding == "" {
+			return nil
+		}
+
+		// Compress response, the compressor only sees the selected encoding
+		acceptEncoding = utils.CopyString(acceptEncoding)
+		compressed := len(c.Response().Header.Peek(fiber.HeaderContentEncoding)) > 0
+		c.Request().Header.Set(fiber.HeaderAcceptEncoding, encoding)
 		compressor(c.Context())
+		c.Request().Header.Set(fiber.HeaderAcceptEncoding, acceptEncoding)
+		if !compressed && len(c.Response().Header.Peek(fiber.HeaderContentEncoding)) > 0 {
+			c.Vary(fiber.HeaderAcceptEncoding)
+		}
 
 		// Return from handler
 		return nil
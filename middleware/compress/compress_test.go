@@ -122,6 +122,95 @@ func Test_Compress_Brotli(t *testing.T) {
 	utils.AssertEqual(t, true, len(body) < len(filedata))
 }
 
+// go test -run Test_Compress_AcceptEncoding_Quality
+func Test_Compress_AcceptEncoding_Quality(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.Send(filedata)
+	})
+
+	testCases := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{acceptEncoding: "gzip;q=0.5, br", encoding: "br"},
+		{acceptEncoding: "br;q=0.1, gzip", encoding: "gzip"},
+		{acceptEncoding: "br;q=0, deflate", encoding: "deflate"},
+		{acceptEncoding: "identity", encoding: ""},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+
+		resp, err := app.Test(req, 10000)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+		utils.AssertEqual(t, tc.encoding, resp.Header.Get(fiber.HeaderContentEncoding), tc.acceptEncoding)
+		if tc.encoding != "" {
+			utils.AssertEqual(t, fiber.HeaderAcceptEncoding, resp.Header.Get(fiber.HeaderVary), tc.acceptEncoding)
+		} else {
+			utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderVary), tc.acceptEncoding)
+		}
+	}
+}
+
+// go test -run Test_Compress_BrotliLevel
+func Test_Compress_BrotliLevel(t *testing.T) {
+	compressedLength := func(level int) int {
+		app := fiber.New()
+
+		app.Use(New(Config{BrotliLevel: level}))
+
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.Send(filedata)
+		})
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "br")
+
+		resp, err := app.Test(req, 10000)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, "br", resp.Header.Get(fiber.HeaderContentEncoding))
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return len(body)
+	}
+
+	utils.AssertEqual(t, true, compressedLength(11) < compressedLength(1))
+}
+
+// go test -run Test_Compress_MinLength
+func Test_Compress_MinLength(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{MinLength: 1024}))
+
+	app.Get("/short", func(c *fiber.Ctx) error {
+		return c.Send(filedata[:1000])
+	})
+	app.Get("/long", func(c *fiber.Ctx) error {
+		return c.Send(filedata[:2000])
+	})
+
+	req := httptest.NewRequest("GET", "/short", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderContentEncoding))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, filedata[:1000], body)
+
+	req = httptest.NewRequest("GET", "/long", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "gzip", resp.Header.Get(fiber.HeaderContentEncoding))
+}
+
 func Test_Compress_Disabled(t *testing.T) {
 	app := fiber.New()
 
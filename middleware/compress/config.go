@@ -2,6 +2,7 @@ package compress
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 )
 
 // Config defines the config for middleware.
@@ -19,6 +20,18 @@ type Config struct {
 	// LevelBestSpeed:        1
 	// LevelBestCompression:  2
 	Level Level
+
+	// BrotliLevel sets the quality of the brotli encoder from 1 (best speed)
+	// to 11 (best compression), overriding the brotli quality derived from Level.
+	//
+	// Optional. Default: 0
+	BrotliLevel int
+
+	// MinLength is the minimum length of the response body in bytes,
+	// shorter bodies are sent uncompressed.
+	//
+	// Optional. Default: 0
+	MinLength int
 }
 
 // Level is numeric representation of compression level
@@ -52,5 +65,8 @@ func configDefault(config ...Config) Config {
 	if cfg.Level < LevelDisabled || cfg.Level > LevelBestCompression {
 		cfg.Level = ConfigDefault.Level
 	}
+	if cfg.BrotliLevel < 0 || cfg.BrotliLevel > fasthttp.CompressBrotliBestCompression {
+		cfg.BrotliLevel = ConfigDefault.BrotliLevel
+	}
 	return cfg
 }
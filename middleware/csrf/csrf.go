@@ -47,7 +47,7 @@ func New(config ...Config) fiber.Handler {
 			// if token does not exist in Storage
 			if manager.getRaw(token) == nil {
 				// Expire cookie
-				c.Cookie(&fiber.Cookie{
+				if err := c.SetCookie(&fiber.Cookie{
 					Name:        cfg.CookieName,
 					Domain:      cfg.CookieDomain,
 					Path:        cfg.CookiePath,
@@ -56,7 +56,9 @@ func New(config ...Config) fiber.Handler {
 					HTTPOnly:    cfg.CookieHTTPOnly,
 					SameSite:    cfg.CookieSameSite,
 					SessionOnly: cfg.CookieSessionOnly,
-				})
+				}); err != nil {
+					return err
+				}
 				return cfg.ErrorHandler(c, errTokenNotFound)
 			}
 		}
@@ -82,8 +84,10 @@ func New(config ...Config) fiber.Handler {
 			SameSite:    cfg.CookieSameSite,
 			SessionOnly: cfg.CookieSessionOnly,
 		}
-		// Set cookie to response
-		c.Cookie(cookie)
+		// Set cookie to response, an insecure SameSite=None cookie may be rejected by the app config
+		if err := c.SetCookie(cookie); err != nil {
+			return err
+		}
 
 		// Protect clients from caching the response by telling the browser
 		// a new header value is generated
@@ -340,3 +340,24 @@ func Test_CSRF_ErrorHandler_EmptyToken(t *testing.T) {
 	utils.AssertEqual(t, 419, ctx.Response.StatusCode())
 	utils.AssertEqual(t, "empty CSRF token", string(ctx.Response.Body()))
 }
+
+// go test -run Test_CSRF_InsecureSameSiteNone
+func Test_CSRF_InsecureSameSiteNone(t *testing.T) {
+	app := fiber.New(fiber.Config{RejectInsecureSameSiteNone: true})
+
+	app.Use(New(Config{CookieSameSite: fiber.CookieSameSiteNoneMode}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	h := app.Handler()
+	ctx := &fasthttp.RequestCtx{}
+
+	// the rejected cookie isn't dropped silently
+	ctx.Request.Header.SetMethod("GET")
+	h(ctx)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, ctx.Response.StatusCode())
+	utils.AssertEqual(t, fiber.ErrCookieSameSiteNoneInsecure.Error(), string(ctx.Response.Body()))
+	utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderSetCookie)))
+}
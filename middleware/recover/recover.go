@@ -29,14 +29,20 @@ func New(config ...Config) fiber.Handler {
 		// Catch panics
 		defer func() {
 			if r := recover(); r != nil {
+				// the panicking route opted out of the recovery
+				if c.Route().RecoverDisabled() {
+					panic(r)
+				}
 				if cfg.EnableStackTrace {
 					cfg.StackTraceHandler(c, r)
 				}
 
+				// Errors created with fiber.NewError are meant for the client,
+				// any other panic value is hidden by the default error handler
 				var ok bool
-				if err, ok = r.(error); !ok {
+				if err, ok = r.(*fiber.Error); !ok {
 					// Set error that will call the global error handler
-					err = fmt.Errorf("%v", r)
+					err = &fiber.PanicError{Value: r}
 				}
 			}
 		}()
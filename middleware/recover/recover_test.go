@@ -1,7 +1,9 @@
 package recover
 
 import (
+	"io/ioutil"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -56,3 +58,88 @@ func Test_Recover_EnableStackTrace(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
 }
+
+// go test -run Test_Recover_PanicResponseBody
+func Test_Recover_PanicResponseBody(t *testing.T) {
+	t.Parallel()
+	panicHandler := func(c *fiber.Ctx) error {
+		panic("secret: db password is hunter2")
+	}
+
+	app := fiber.New()
+	app.Use(New())
+	app.Get("/panic", panicHandler)
+	app.Get("/error", func(c *fiber.Ctx) error {
+		panic(fiber.NewError(fiber.StatusTeapot, "I'm a teapot"))
+	})
+	app.Get("/runtime", func(c *fiber.Ctx) error {
+		var m map[string]int
+		m["nil"] = 1
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/panic", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "Internal Server Error", string(body))
+	utils.AssertEqual(t, false, strings.Contains(string(body), "secret"))
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/runtime", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "Internal Server Error", string(body))
+
+	// errors created with fiber.NewError are still sent
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/error", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusTeapot, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "I'm a teapot", string(body))
+
+	app = fiber.New(fiber.Config{PanicResponseBody: "Something went wrong"})
+	app.Use(New())
+	app.Get("/panic", panicHandler)
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/panic", nil))
+	utils.AssertEqual(t, nil, err)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "Something went wrong", string(body))
+
+	app = fiber.New(fiber.Config{ExposePanicMessage: true})
+	app.Use(New())
+	app.Get("/panic", panicHandler)
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/panic", nil))
+	utils.AssertEqual(t, nil, err)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "secret: db password is hunter2", string(body))
+}
+
+// go test -run Test_Recover_DisableRecover
+func Test_Recover_DisableRecover(t *testing.T) {
+	t.Parallel()
+	app := fiber.New()
+	app.Use(New())
+
+	app.Get("/recovered", func(c *fiber.Ctx) error {
+		panic("recovered")
+	})
+	app.Get("/crash", func(c *fiber.Ctx) error {
+		panic("crash")
+	}).DisableRecover()
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/recovered", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	defer func() {
+		utils.AssertEqual(t, "crash", recover())
+	}()
+	_, _ = app.Test(httptest.NewRequest(fiber.MethodGet, "/crash", nil))
+	t.Fatal("panic of the route was recovered")
+}
@@ -27,6 +27,12 @@ type Config struct {
 	//
 	// Optional. Default: requestid
 	ContextKey string
+
+	// MaxLength is the maximum length of an incoming request ID,
+	// longer IDs are replaced by a generated one to avoid header abuse.
+	//
+	// Optional. Default: 128
+	MaxLength int
 }
 
 // ConfigDefault is the default config
@@ -35,6 +41,7 @@ var ConfigDefault = Config{
 	Header:     fiber.HeaderXRequestID,
 	Generator:  utils.UUID,
 	ContextKey: "requestid",
+	MaxLength:  128,
 }
 
 // Helper function to set default values
@@ -57,5 +64,8 @@ func configDefault(config ...Config) Config {
 	if cfg.ContextKey == "" {
 		cfg.ContextKey = ConfigDefault.ContextKey
 	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = ConfigDefault.MaxLength
+	}
 	return cfg
 }
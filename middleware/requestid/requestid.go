@@ -2,6 +2,7 @@ package requestid
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
 // New creates a new middleware handler
@@ -16,13 +17,19 @@ func New(config ...Config) fiber.Handler {
 			return c.Next()
 		}
 		// Get id from request, else we generate one
-		rid := c.Get(cfg.Header, cfg.Generator())
+		rid := c.Get(cfg.Header)
+		if rid == "" || len(rid) > cfg.MaxLength {
+			rid = cfg.Generator()
+		} else {
+			rid = utils.CopyString(rid)
+		}
 
 		// Set new id to response header
 		c.Set(cfg.Header, rid)
 
 		// Add the request ID to locals
 		c.Locals(cfg.ContextKey, rid)
+		c.Locals(fiber.LocalsKeyRequestID, rid)
 
 		// Continue stack
 		return c.Next()
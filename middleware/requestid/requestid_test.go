@@ -2,6 +2,7 @@ package requestid
 
 import (
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -73,3 +74,65 @@ func Test_RequestID_Locals(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, reqId, ctxVal)
 }
+
+// go test -run Test_RequestID_MaxLength
+func Test_RequestID_MaxLength(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(Config{
+		Generator: func() string {
+			return "generated"
+		},
+		MaxLength: 16,
+	}))
+
+	var ctxID string
+	app.Get("/", func(c *fiber.Ctx) error {
+		ctxID = c.RequestID()
+		return nil
+	})
+
+	// inbound IDs are kept
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "inbound-id")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "inbound-id", resp.Header.Get(fiber.HeaderXRequestID))
+	utils.AssertEqual(t, "inbound-id", ctxID)
+
+	// too long inbound IDs are replaced
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, strings.Repeat("x", 17))
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "generated", resp.Header.Get(fiber.HeaderXRequestID))
+	utils.AssertEqual(t, "generated", ctxID)
+}
+
+// go test -run Test_RequestID_Header
+func Test_RequestID_Header(t *testing.T) {
+	generated := 0
+	app := fiber.New()
+	app.Use(New(Config{
+		Header: "X-Trace-ID",
+		Generator: func() string {
+			generated++
+			return "generated"
+		},
+	}))
+
+	var ctxID string
+	app.Get("/", func(c *fiber.Ctx) error {
+		ctxID = c.RequestID()
+		return nil
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Trace-ID", "trace")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "trace", resp.Header.Get("X-Trace-ID"))
+	utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderXRequestID))
+	utils.AssertEqual(t, "trace", ctxID)
+	// no ID is generated for requests which have one
+	utils.AssertEqual(t, 0, generated)
+}
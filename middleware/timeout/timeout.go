@@ -12,6 +12,12 @@ var once sync.Once
 
 // New wraps a handler and aborts the process of the handler if the timeout is reached
 func New(handler fiber.Handler, timeout time.Duration) fiber.Handler {
+	return NewWithCode(handler, timeout, fiber.StatusRequestTimeout)
+}
+
+// NewWithCode works like New, but responds with the given status code if the timeout is reached,
+// e.g. 503 Service Unavailable or 504 Gateway Timeout when the server, not the client, is too slow
+func NewWithCode(handler fiber.Handler, timeout time.Duration, statusCode int) fiber.Handler {
 	once.Do(func() {
 		fmt.Println("[Warning] timeout contains data race issues, not ready for production!")
 	})
@@ -35,7 +41,7 @@ func New(handler fiber.Handler, timeout time.Duration) fiber.Handler {
 		select {
 		case <-ch:
 		case <-time.After(timeout):
-			return fiber.ErrRequestTimeout
+			return fiber.NewError(statusCode)
 		}
 
 		return nil
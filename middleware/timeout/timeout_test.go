@@ -1,5 +1,43 @@
 package timeout
 
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// go test -run Test_Timeout_StatusCode
+func Test_Timeout_StatusCode(t *testing.T) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+
+	// the slow handler doesn't touch the ctx, it is used after the timeout
+	slow := func(c *fiber.Ctx) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+	app.Get("/default", New(slow, 5*time.Millisecond))
+	app.Get("/unavailable", NewWithCode(slow, 5*time.Millisecond, fiber.StatusServiceUnavailable))
+	app.Get("/gateway", NewWithCode(slow, 5*time.Millisecond, fiber.StatusGatewayTimeout))
+
+	testStatus := func(path string, status int) {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, status, resp.StatusCode, "Status code")
+
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, utils.StatusMessage(status), string(body))
+	}
+
+	testStatus("/default", fiber.StatusRequestTimeout)
+	testStatus("/unavailable", fiber.StatusServiceUnavailable)
+	testStatus("/gateway", fiber.StatusGatewayTimeout)
+}
+
 // // go test -run Test_Middleware_Timeout
 // func Test_Middleware_Timeout(t *testing.T) {
 // 	app := fiber.New(fiber.Config{DisableStartupMessage: true})
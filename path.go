@@ -38,16 +38,17 @@ type routeSegment struct {
 	IsGreedy    bool   // indicates whether the parameter is greedy or not, is used with wildcard and plus
 	IsOptional  bool   // indicates whether the parameter is optional or not
 	// common information
-	IsLast           bool          // shows if the segment is the last one for the route
-	HasOptionalSlash bool          // segment has the possibility of an optional slash
-	Constraints      []*Constraint // Constraint type if segment is a parameter, if not it will be set to noConstraint by default
-	Length           int           // length of the parameter for segment, when its 0 then the length is undetermined
+	IsLast           bool            // shows if the segment is the last one for the route
+	HasOptionalSlash bool            // segment has the possibility of an optional slash
+	Constraints      []*Constraint   // Constraint type if segment is a parameter, if not it will be set to noConstraint by default
+	Length           int             // length of the parameter for segment, when its 0 then the length is undetermined
+	Captures         []*regexCapture // capture groups of regex constraints, exposed as additional parameters
 	// future TODO: add support for optional groups "/abc(/def)?"
 }
 
 // different special routing signs
 const (
-	wildcardParam                byte = '*'  // indicates a optional greedy parameter
+	wildcardParam                byte = '*'  // indicates a optional greedy parameter, also concludes a named parameter as wildcard
 	plusParam                    byte = '+'  // indicates a required greedy parameter
 	optionalParam                byte = '?'  // concludes a parameter by name and makes it optional
 	paramStarterChar             byte = ':'  // start character for a parameter with name
@@ -68,6 +69,40 @@ type Constraint struct {
 	ID            TypeConstraint
 	RegexCompiler *regexp.Regexp
 	Data          []string
+
+	custom CustomConstraint
+}
+
+// CompiledConstraint holds parsed constraints, which can be checked repeatedly, see App.CompileConstraint.
+type CompiledConstraint struct {
+	raw         string
+	constraints []*Constraint
+}
+
+// Check reports whether the value satisfies all constraints.
+func (cc *CompiledConstraint) Check(value string) bool {
+	for _, cons := range cc.constraints {
+		if !cons.CheckConstraint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraints as they were written.
+func (cc *CompiledConstraint) String() string {
+	return cc.raw
+}
+
+// CustomConstraint checks a route parameter with the data of the constraint,
+// e.g. "2" and "8" for ":code<code(2,8)>", see App.RegisterCustomConstraint.
+type CustomConstraint func(param string, constraintData ...string) bool
+
+// regexCapture holds a capture group of a regex constraint which is exposed as route parameter
+type regexCapture struct {
+	Name  string         // name of the parameter
+	Index int            // index of the submatch
+	Regex *regexp.Regexp // regex of the constraint
 }
 
 const (
@@ -86,6 +121,9 @@ const (
 	maxConstraint
 	rangeConstraint
 	regexConstraint
+	enumConstraint
+	enumIgnoreCaseConstraint
+	customConstraint
 )
 
 // list of possible parameter and segment delimiter
@@ -99,7 +137,7 @@ var (
 	// list of chars of delimiters and the starting parameter name char
 	parameterDelimiterChars = append([]byte{paramStarterChar, escapeChar}, routeDelimiter...)
 	// list of chars to find the end of a parameter
-	parameterEndChars = append([]byte{optionalParam}, parameterDelimiterChars...)
+	parameterEndChars = append([]byte{optionalParam, wildcardParam}, parameterDelimiterChars...)
 	// list of parameter constraint start
 	parameterConstraintStartChars = []byte{paramConstraintStart}
 	// list of parameter constraint end
@@ -112,20 +150,48 @@ var (
 	parameterConstraintDataEndChars = []byte{paramConstraintDataEnd}
 	// list of parameter constraint data separator
 	parameterConstraintDataSeparatorChars = []byte{paramConstraintDataSeparator}
+	// list of constraints which need one data
+	constraintsNeedOneData = []TypeConstraint{minLenConstraint, maxLenConstraint, lenConstraint, minConstraint, maxConstraint, datetimeConstraint, regexConstraint, enumConstraint, enumIgnoreCaseConstraint}
+	// list of constraints which need two data
+	constraintsNeedTwoData = []TypeConstraint{betweenLenConstraint, rangeConstraint}
 )
 
 // parseRoute analyzes the route and divides it into segments for constant areas and parameters,
-// this information is needed later when assigning the requests to the declared routes
-func parseRoute(pattern string) routeParser {
+// this information is needed later when assigning the requests to the declared routes.
+// The custom constraints are consulted for constraint names which aren't built in.
+// An invalid route panics, use tryParseRoute to get the error instead.
+func parseRoute(pattern string, customConstraints ...map[string]CustomConstraint) routeParser {
+	var custom map[string]CustomConstraint
+	if len(customConstraints) > 0 {
+		custom = customConstraints[0]
+	}
+	parser, err := tryParseRoute(pattern, custom)
+	if err != nil {
+		panic(err.Error() + "\n")
+	}
+	return parser
+}
+
+// tryParseRoute works like parseRoute, but returns an error for an invalid regex constraint,
+// an unknown constraint name or too many parameters
+func tryParseRoute(pattern string, customConstraints map[string]CustomConstraint) (routeParser, error) {
 	parser := routeParser{}
+	routePattern := pattern
 
 	part := ""
 	for len(pattern) > 0 {
 		nextParamPosition := findNextParamPosition(pattern)
 		// handle the parameter part
 		if nextParamPosition == 0 {
-			processedPart, seg := parser.analyseParameterPart(pattern)
+			processedPart, seg, err := parser.analyseParameterPart(pattern, customConstraints)
+			if err != nil {
+				return parser, err
+			}
 			parser.params, parser.segs, part = append(parser.params, seg.ParamName), append(parser.segs, seg), processedPart
+			// the capture groups follow the parameter they belong to
+			for _, capture := range seg.Captures {
+				parser.params = append(parser.params, capture.Name)
+			}
 		} else {
 			processedPart, seg := parser.analyseConstantPart(pattern, nextParamPosition)
 			parser.segs, part = append(parser.segs, seg), processedPart
@@ -142,8 +208,12 @@ func parseRoute(pattern string) routeParser {
 		parser.segs[len(parser.segs)-1].IsLast = true
 	}
 	parser.segs = addParameterMetaInfo(parser.segs)
+	// the parameters and capture groups are matched into a fixed size array
+	if len(parser.params) > maxParams {
+		return parser, fmt.Errorf("route: %q has %d parameters including capture groups, the maximum is %d", routePattern, len(parser.params), maxParams)
+	}
 
-	return parser
+	return parser, nil
 }
 
 // addParameterMetaInfo add important meta information to the parameter segments
@@ -225,7 +295,7 @@ func (routeParser *routeParser) analyseConstantPart(pattern string, nextParamPos
 }
 
 // analyseParameterPart find the parameter end and create the route segment
-func (routeParser *routeParser) analyseParameterPart(pattern string) (string, *routeSegment) {
+func (routeParser *routeParser) analyseParameterPart(pattern string, customConstraints map[string]CustomConstraint) (string, *routeSegment, error) {
 	isWildCard := pattern[0] == wildcardParam
 	isPlusParam := pattern[0] == plusParam
 
@@ -261,46 +331,17 @@ func (routeParser *routeParser) analyseParameterPart(pattern string) (string, *r
 	var constraints []*Constraint
 
 	if hasConstraint := (parameterConstraintStart != -1 && parameterConstraintEnd != -1); hasConstraint {
-		constraintString := pattern[parameterConstraintStart+1 : parameterConstraintEnd]
-		userconstraints := splitNonEscaped(constraintString, string(parameterConstraintSeparatorChars))
-		constraints = make([]*Constraint, 0, len(userconstraints))
-
-		for _, c := range userconstraints {
-			start := findNextNonEscapedCharsetPosition(c, parameterConstraintDataStartChars)
-			end := findNextNonEscapedCharsetPosition(c, parameterConstraintDataEndChars)
-
-			// Assign constraint
-			if start != -1 && end != -1 {
-				constraint := &Constraint{
-					ID:   getParamConstraintType(c[:start]),
-					Data: splitNonEscaped(c[start+1:end], string(parameterConstraintDataSeparatorChars)),
-				}
-
-				// remove escapes from data
-				if len(constraint.Data) == 1 {
-					constraint.Data[0] = RemoveEscapeChar(constraint.Data[0])
-				} else if len(constraint.Data) == 2 {
-					constraint.Data[0] = RemoveEscapeChar(constraint.Data[0])
-					constraint.Data[1] = RemoveEscapeChar(constraint.Data[1])
-				}
-
-				// Precompile regex if has regex constraint
-				if constraint.ID == regexConstraint {
-					constraint.RegexCompiler = regexp.MustCompile(constraint.Data[0])
-				}
-
-				constraints = append(constraints, constraint)
-			} else {
-				constraints = append(constraints, &Constraint{
-					ID:   getParamConstraintType(c),
-					Data: []string{},
-				})
-			}
+		var err error
+		if constraints, err = parseConstraints(pattern[parameterConstraintStart+1:parameterConstraintEnd], customConstraints); err != nil {
+			return "", nil, err
 		}
 
 		paramName = RemoveEscapeChar(GetTrimmedParam(pattern[0:parameterConstraintStart]))
 	}
 
+	// named wildcard like ":path*"
+	isNamedWildCard := !isWildCard && !isPlusParam && pattern[parameterEndPosition] == wildcardParam
+
 	// add access iterator to wildcard and plus
 	if isWildCard {
 		routeParser.wildCardCount++
@@ -313,15 +354,37 @@ func (routeParser *routeParser) analyseParameterPart(pattern string) (string, *r
 	segment := &routeSegment{
 		ParamName:  paramName,
 		IsParam:    true,
-		IsOptional: isWildCard || pattern[parameterEndPosition] == optionalParam,
-		IsGreedy:   isWildCard || isPlusParam,
+		IsOptional: isWildCard || isNamedWildCard || pattern[parameterEndPosition] == optionalParam,
+		IsGreedy:   isWildCard || isNamedWildCard || isPlusParam,
 	}
 
 	if len(constraints) > 0 {
 		segment.Constraints = constraints
+		segment.Captures = getRegexCaptures(paramName, constraints)
 	}
 
-	return processedPart, segment
+	return processedPart, segment, nil
+}
+
+// getRegexCaptures collects the capture groups of the regex constraints, named groups are exposed
+// by their name and unnamed groups by the parameter name and their number, e.g. "date.1"
+func getRegexCaptures(paramName string, constraints []*Constraint) []*regexCapture {
+	var captures []*regexCapture
+	for _, c := range constraints {
+		if c.ID != regexConstraint || c.RegexCompiler == nil {
+			continue
+		}
+		for index, name := range c.RegexCompiler.SubexpNames() {
+			if index == 0 {
+				continue
+			}
+			if name == "" {
+				name = paramName + "." + strconv.Itoa(index)
+			}
+			captures = append(captures, &regexCapture{Name: name, Index: index, Regex: c.RegexCompiler})
+		}
+	}
+	return captures
 }
 
 // isInCharset check is the given character in the charset list
@@ -349,29 +412,20 @@ func findNextCharsetPosition(search string, charset []byte) int {
 // findNextCharsetPositionConstraint search the next char position from the charset
 // unlike findNextCharsetPosition, it takes care of constraint start-end chars to parse route pattern
 func findNextCharsetPositionConstraint(search string, charset []byte) int {
-	nextPosition := -1
-	constraintStart := -1
-	constraintEnd := -1
+	constraintStart := findNextNonEscapedCharsetPosition(search, parameterConstraintStartChars)
+	constraintEnd := findNextNonEscapedCharsetPosition(search, parameterConstraintEndChars)
 
-	for _, char := range charset {
-		pos := strings.IndexByte(search, char)
-
-		if char == paramConstraintStart {
-			constraintStart = pos
+	for pos := 0; pos < len(search); pos++ {
+		// the chars of the constraint part belong to the parameter
+		if constraintStart != -1 && constraintEnd > constraintStart && pos >= constraintStart && pos <= constraintEnd {
+			continue
 		}
-
-		if char == paramConstraintEnd {
-			constraintEnd = pos
-		}
-		//fmt.Println(string(char))
-		if pos != -1 && (pos < nextPosition || nextPosition == -1) {
-			if pos > constraintStart && pos < constraintEnd {
-				nextPosition = pos
-			}
+		if (pos == 0 || search[pos-1] != escapeChar) && isInCharset(search[pos], charset) {
+			return pos
 		}
 	}
 
-	return nextPosition
+	return -1
 }
 
 // findNextNonEscapedCharsetPosition search the next char position from the charset and skip the escaped characters
@@ -431,15 +485,26 @@ func (routeParser *routeParser) getMatch(detectionPath, path string, params *[ma
 			}
 			// take over the params positions
 			params[paramsIterator] = path[:i]
+			// a trailing wildcard captures the remaining path without leading slashes
+			if segment.IsLast && segment.IsGreedy && segment.IsOptional {
+				params[paramsIterator] = utils.TrimLeft(params[paramsIterator], slashDelimiter)
+			}
 
-			// check constraint
-			for _, c := range segment.Constraints {
-				if matched := c.CheckConstraint(params[paramsIterator]); !matched {
-					return false
+			// check constraint, except for an absent optional parameter
+			if i > 0 {
+				for _, c := range segment.Constraints {
+					if matched := c.CheckConstraint(params[paramsIterator]); !matched {
+						return false
+					}
 				}
 			}
 
 			paramsIterator++
+
+			// take over the capture groups of the regex constraints
+			if len(segment.Captures) > 0 {
+				paramsIterator = segment.setCaptures(params, paramsIterator, params[paramsIterator-1])
+			}
 		}
 
 		// reduce founded part from the string
@@ -454,6 +519,27 @@ func (routeParser *routeParser) getMatch(detectionPath, path string, params *[ma
 	return true
 }
 
+// setCaptures assigns the submatches of the regex constraints to the params following the parameter
+func (segment *routeSegment) setCaptures(params *[maxParams]string, paramsIterator int, value string) int {
+	var regex *regexp.Regexp
+	var matches []string
+	for _, capture := range segment.Captures {
+		if capture.Regex != regex {
+			regex = capture.Regex
+			matches = nil
+			if value != "" {
+				matches = regex.FindStringSubmatch(value)
+			}
+		}
+		params[paramsIterator] = ""
+		if capture.Index < len(matches) {
+			params[paramsIterator] = matches[capture.Index]
+		}
+		paramsIterator++
+	}
+	return paramsIterator
+}
+
 // findParamLen for the expressjs wildcard behavior (right to left greedy)
 // look at the other segments and take what is left for the wildcard from right to left
 func findParamLen(s string, segment *routeSegment) int {
@@ -514,7 +600,7 @@ func findGreedyParamLen(s string, searchCount int, segment *routeSegment) int {
 	return len(s)
 }
 
-// GetTrimmedParam trims the ':' & '?' from a string
+// GetTrimmedParam trims the ':' & '?' or '*' from a string
 func GetTrimmedParam(param string) string {
 	start := 0
 	end := len(param)
@@ -523,13 +609,39 @@ func GetTrimmedParam(param string) string {
 		return param
 	}
 	start++
-	if param[end-1] == optionalParam { // is ?
+	if param[end-1] == optionalParam || param[end-1] == wildcardParam { // is ? or *
 		end--
 	}
 
 	return param[start:end]
 }
 
+// toLowerRoute lowercases the route pattern for case-insensitive routing except for the
+// parameter constraints, whose data like regex patterns is checked against the original values
+func toLowerRoute(pattern string) string {
+	if strings.IndexByte(pattern, paramConstraintStart) == -1 {
+		return utils.ToLower(pattern)
+	}
+	b := []byte(pattern)
+	inConstraint := false
+	for i := 0; i < len(b); i++ {
+		if b[i] == escapeChar && i+1 < len(b) {
+			// the escaped character is taken over like any other character
+			i++
+		} else if b[i] == paramConstraintStart && !inConstraint {
+			inConstraint = true
+			continue
+		} else if b[i] == paramConstraintEnd && inConstraint {
+			inConstraint = false
+			continue
+		}
+		if !inConstraint && b[i] >= 'A' && b[i] <= 'Z' {
+			b[i] += 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
 // RemoveEscapeChar remove escape characters
 func RemoveEscapeChar(word string) string {
 	if strings.IndexByte(word, escapeChar) != -1 {
@@ -538,6 +650,67 @@ func RemoveEscapeChar(word string) string {
 	return word
 }
 
+// parseConstraints parses the constraints of a parameter like "int;min(1)",
+// an invalid regex constraint and an unknown constraint name return an error
+func parseConstraints(constraintString string, customConstraints map[string]CustomConstraint) ([]*Constraint, error) {
+	userconstraints := splitNonEscaped(constraintString, string(parameterConstraintSeparatorChars))
+	constraints := make([]*Constraint, 0, len(userconstraints))
+
+	for _, c := range userconstraints {
+		start := findNextNonEscapedCharsetPosition(c, parameterConstraintDataStartChars)
+		end := findNextNonEscapedCharsetPosition(c, parameterConstraintDataEndChars)
+
+		// Assign constraint
+		if start != -1 && end != -1 {
+			constraint, err := newConstraint(c[:start], customConstraints)
+			if err != nil {
+				return nil, err
+			}
+			constraint.Data = splitNonEscaped(c[start+1:end], string(parameterConstraintDataSeparatorChars))
+
+			// remove escapes from data
+			for i := range constraint.Data {
+				constraint.Data[i] = RemoveEscapeChar(constraint.Data[i])
+			}
+
+			// Precompile regex once at registration, an invalid pattern fails here and not at request time
+			if constraint.ID == regexConstraint {
+				if constraint.RegexCompiler, err = regexp.Compile(constraint.Data[0]); err != nil {
+					return nil, fmt.Errorf("regexp: Compile(`%s`): %w", constraint.Data[0], err)
+				}
+			}
+
+			// Split the options of an enum constraint
+			if constraint.ID == enumConstraint || constraint.ID == enumIgnoreCaseConstraint {
+				constraint.Data = strings.Split(constraint.Data[0], "|")
+			}
+
+			constraints = append(constraints, constraint)
+		} else {
+			constraint, err := newConstraint(c, customConstraints)
+			if err != nil {
+				return nil, err
+			}
+			constraint.Data = []string{}
+			constraints = append(constraints, constraint)
+		}
+	}
+
+	return constraints, nil
+}
+
+// newConstraint creates the constraint for the name, built-in constraints take precedence
+// over the custom constraints
+func newConstraint(name string, customConstraints map[string]CustomConstraint) (*Constraint, error) {
+	if id := getParamConstraintType(name); id != noConstraint || name == "" {
+		return &Constraint{ID: id}, nil
+	}
+	if fn, ok := customConstraints[utils.ToLower(name)]; ok {
+		return &Constraint{ID: customConstraint, custom: fn}, nil
+	}
+	return nil, fmt.Errorf("constraint: unknown constraint %q", name)
+}
+
 func getParamConstraintType(constraintPart string) TypeConstraint {
 	switch constraintPart {
 	case ConstraintInt:
@@ -568,6 +741,10 @@ func getParamConstraintType(constraintPart string) TypeConstraint {
 		return datetimeConstraint
 	case ConstraintRegex:
 		return regexConstraint
+	case ConstraintEnum:
+		return enumConstraint
+	case ConstraintEnumIgnoreCase:
+		return enumIgnoreCaseConstraint
 	default:
 		return noConstraint
 	}
@@ -579,23 +756,18 @@ func (c *Constraint) CheckConstraint(param string) bool {
 	var num int
 
 	// check data exists
-	needOneData := []TypeConstraint{minLenConstraint, maxLenConstraint, lenConstraint, minConstraint, maxConstraint, datetimeConstraint, regexConstraint}
-	needTwoData := []TypeConstraint{betweenLenConstraint, rangeConstraint}
-
-	for _, data := range needOneData {
+	for _, data := range constraintsNeedOneData {
 		if c.ID == data && len(c.Data) == 0 {
 			return false
 		}
 	}
 
-	for _, data := range needTwoData {
+	for _, data := range constraintsNeedTwoData {
 		if c.ID == data && len(c.Data) < 2 {
 			return false
 		}
 	}
 
-	fmt.Print(c.Data)
-
 	// check constraints
 	switch c.ID {
 	case intConstraint:
@@ -603,7 +775,8 @@ func (c *Constraint) CheckConstraint(param string) bool {
 	case boolConstraint:
 		_, err = strconv.ParseBool(param)
 	case floatConstraint:
-		_, err = strconv.ParseFloat(param, 32)
+		// parsed like c.QueryFloat and c.CookieFloat, so that both accept the same values
+		_, err = strconv.ParseFloat(param, 64)
 	case alphaConstraint:
 		for _, r := range param {
 			if !unicode.IsLetter(r) {
@@ -665,6 +838,22 @@ func (c *Constraint) CheckConstraint(param string) bool {
 		if match := c.RegexCompiler.MatchString(param); !match {
 			return false
 		}
+	case enumConstraint:
+		for _, option := range c.Data {
+			if param == option {
+				return true
+			}
+		}
+		return false
+	case enumIgnoreCaseConstraint:
+		for _, option := range c.Data {
+			if utils.EqualFold(param, option) {
+				return true
+			}
+		}
+		return false
+	case customConstraint:
+		return c.custom(param, c.Data...)
 	}
 
 	return err == nil
@@ -6,6 +6,7 @@ package fiber
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2/utils"
@@ -119,6 +120,15 @@ func Test_Path_parseRoute(t *testing.T) {
 		params: []string{"day", "month", "year"},
 	}, rp)
 
+	rp = parseRoute("/files/:path*")
+	utils.AssertEqual(t, routeParser{
+		segs: []*routeSegment{
+			{Const: "/files/", Length: 7, HasOptionalSlash: true},
+			{IsParam: true, ParamName: "path", IsGreedy: true, IsOptional: true, IsLast: true},
+		},
+		params: []string{"path"},
+	}, rp)
+
 	rp = parseRoute("/*v1*/proxy")
 	utils.AssertEqual(t, routeParser{
 		segs: []*routeSegment{
@@ -195,6 +205,7 @@ func Test_Path_matchParams(t *testing.T) {
 		{url: "/api/v1/entity", params: []string{"entity"}, match: true},
 		{url: "/api/v1/entity/1/2", params: []string{"entity/1/2"}, match: true},
 		{url: "/api/v1/Entity/1/2", params: []string{"Entity/1/2"}, match: true},
+		{url: "/api/v1//entity/1", params: []string{"entity/1"}, match: true},
 		{url: "/api/v", params: nil, match: false},
 		{url: "/api/v2", params: nil, match: false},
 		{url: "/api/abc", params: nil, match: false},
@@ -351,9 +362,21 @@ func Test_Path_matchParams(t *testing.T) {
 		{url: "/api/joker", params: []string{"joker"}, match: true},
 		{url: "/api", params: []string{""}, match: true},
 		{url: "/api/v1/entity", params: []string{"v1/entity"}, match: true},
+		{url: "/api//v1/entity", params: []string{"v1/entity"}, match: true},
 		{url: "/api2/v1/entity", params: nil, match: false},
 		{url: "/api_ignore/v1/entity", params: nil, match: false},
 	})
+	testCase("/files/:path*", []testparams{
+		{url: "/files", params: []string{""}, match: true},
+		{url: "/files/", params: []string{""}, match: true},
+		{url: "/files/readme.md", params: []string{"readme.md"}, match: true},
+		{url: "/files/docs/api/readme.md", params: []string{"docs/api/readme.md"}, match: true},
+		{url: "/filesystem", params: nil, match: false},
+	})
+	testCase("/files/:path*/edit", []testparams{
+		{url: "/files/docs/readme.md/edit", params: []string{"docs/readme.md"}, match: true},
+		{url: "/files/docs/readme.md", params: nil, match: false},
+	})
 	testCase("/partialCheck/foo/bar/:param", []testparams{
 		{url: "/partialCheck/foo/bar/test", params: []string{"test"}, match: true, partialCheck: true},
 		{url: "/partialCheck/foo/bar/test/test2", params: []string{"test"}, match: true, partialCheck: true},
@@ -436,6 +459,12 @@ func Test_Path_matchParams(t *testing.T) {
 		{url: "/api/v1/8728382", params: []string{"8728382"}, match: true},
 		{url: "/api/v1/true", params: []string{"true"}, match: false},
 	})
+	testCase("/api/v1/:param<int>?", []testparams{
+		{url: "/api/v1", params: []string{""}, match: true},
+		{url: "/api/v1/", params: []string{""}, match: true},
+		{url: "/api/v1/8728382", params: []string{"8728382"}, match: true},
+		{url: "/api/v1/entity", params: nil, match: false},
+	})
 	testCase("/api/v1/:param<bool>", []testparams{
 		{url: "/api/v1/entity", params: []string{"entity"}, match: false},
 		{url: "/api/v1/8728382", params: []string{"8728382"}, match: false},
@@ -523,13 +552,30 @@ func Test_Path_matchParams(t *testing.T) {
 	testCase("/api/v1/:param<regex(p\\([a\\-z]\\+\\)ch)>", []testparams{
 		{url: "/api/v1/ent", params: []string{"ent"}, match: false},
 		{url: "/api/v1/15", params: []string{"15"}, match: false},
-		{url: "/api/v1/peach", params: []string{"peach"}, match: true},
+		{url: "/api/v1/peach", params: []string{"peach", "ea"}, match: true},
 		{url: "/api/v1/p34ch", params: []string{"p34ch"}, match: false},
 	})
-	testCase("/api/v1/:param<int;bool((>", []testparams{
-		{url: "/api/v1/entity", params: []string{"entity"}, match: false},
-		{url: "/api/v1/8728382", params: []string{"8728382"}, match: true},
-		{url: "/api/v1/true", params: []string{"true"}, match: false},
+	testCase("/api/v1/:param<regex(v\\(\\?P\\<major\\>[0-9]+\\)_\\(\\?P\\<minor\\>[0-9]+\\))>", []testparams{
+		{url: "/api/v1/v1_2", params: []string{"v1_2", "1", "2"}, match: true},
+		{url: "/api/v1/v12_0", params: []string{"v12_0", "12", "0"}, match: true},
+		{url: "/api/v1/v1", params: []string{"v1", "", ""}, match: false},
+	})
+	testCase("/api/v1/:param<int>/:tab?", []testparams{
+		{url: "/api/v1/12/info", params: []string{"12", "info"}, match: true},
+		{url: "/api/v1/12", params: []string{"12", ""}, match: true},
+		{url: "/api/v1/ent/info", params: []string{"ent", "info"}, match: false},
+	})
+	testCase("/api/v1/:param<enum(active|archived)>", []testparams{
+		{url: "/api/v1/active", params: []string{"active"}, match: true},
+		{url: "/api/v1/archived", params: []string{"archived"}, match: true},
+		{url: "/api/v1/Active", params: []string{"Active"}, match: false},
+		{url: "/api/v1/deleted", params: []string{"deleted"}, match: false},
+		{url: "/api/v1/activ", params: []string{"activ"}, match: false},
+	})
+	testCase("/api/v1/:param<ienum(active|archived)>", []testparams{
+		{url: "/api/v1/active", params: []string{"active"}, match: true},
+		{url: "/api/v1/ARCHIVED", params: []string{"ARCHIVED"}, match: true},
+		{url: "/api/v1/deleted", params: []string{"deleted"}, match: false},
 	})
 	testCase("/api/v1/:param<int;max(3000)>", []testparams{
 		{url: "/api/v1/entity", params: []string{"entity"}, match: false},
@@ -549,12 +595,6 @@ func Test_Path_matchParams(t *testing.T) {
 		{url: "/api/v1/25", params: []string{"25"}, match: true},
 		{url: "/api/v1/true", params: []string{"true"}, match: false},
 	})
-	testCase("/api/v1/:param<int\\;range(10,30)>", []testparams{
-		{url: "/api/v1/entity", params: []string{"entity"}, match: true},
-		{url: "/api/v1/87283827683", params: []string{"87283827683"}, match: true},
-		{url: "/api/v1/25", params: []string{"25"}, match: true},
-		{url: "/api/v1/true", params: []string{"true"}, match: true},
-	})
 	testCase("/api/v1/:param<range(10\\,30,1500)>", []testparams{
 		{url: "/api/v1/entity", params: []string{"entity"}, match: false},
 		{url: "/api/v1/87283827683", params: []string{"8728382"}, match: false},
@@ -574,6 +614,8 @@ func Test_Utils_GetTrimmedParam(t *testing.T) {
 	utils.AssertEqual(t, "param", res)
 	res = GetTrimmedParam(":param1?")
 	utils.AssertEqual(t, "param1", res)
+	res = GetTrimmedParam(":path*")
+	utils.AssertEqual(t, "path", res)
 	res = GetTrimmedParam("noParam")
 	utils.AssertEqual(t, "noParam", res)
 }
@@ -588,6 +630,76 @@ func Test_Utils_RemoveEscapeChar(t *testing.T) {
 	utils.AssertEqual(t, "noEscapeChar", res)
 }
 
+// go test -race -run Test_Path_RegexConstraint_Precompiled
+func Test_Path_RegexConstraint_Precompiled(t *testing.T) {
+	t.Parallel()
+	rp := parseRoute("/api/v1/:param<regex(p\\([a\\-z]\\+\\)ch)>")
+	constraint := rp.segs[1].Constraints[0]
+	utils.AssertEqual(t, regexConstraint, constraint.ID)
+	utils.AssertEqual(t, true, constraint.RegexCompiler != nil)
+	regex := constraint.RegexCompiler
+
+	var ctxParams [maxParams]string
+	utils.AssertEqual(t, true, rp.getMatch("/api/v1/peach", "/api/v1/peach", &ctxParams, false))
+	utils.AssertEqual(t, false, rp.getMatch("/api/v1/p34ch", "/api/v1/p34ch", &ctxParams, false))
+	// the compiled regex is reused for every match
+	utils.AssertEqual(t, regex, constraint.RegexCompiler)
+
+	// an invalid pattern fails at registration and not at request time
+	app := New()
+	defer func() {
+		r := recover()
+		utils.AssertEqual(t, true, r != nil)
+		utils.AssertEqual(t, "regexp: Compile(`[a-z`): error parsing regexp: missing closing ]: `[a-z`\n", fmt.Sprint(r))
+		utils.AssertEqual(t, uint32(0), app.HandlersCount())
+	}()
+	app.Get("/api/v1/:param<regex([a-z)>", func(c *Ctx) error {
+		return nil
+	})
+}
+
+// go test -run Test_Path_toLowerRoute
+func Test_Path_toLowerRoute(t *testing.T) {
+	t.Parallel()
+	for pattern, expected := range map[string]string{
+		"/API/Users":         "/api/users",
+		"/API/:ID<int>/Name": "/api/:id<int>/name",
+		"/V1/:p<regex(\\(\\?P\\<N\\>[A-Z]\\))>/X": "/v1/:p<regex(\\(\\?P\\<N\\>[A-Z]\\))>/x",
+		"/A\\<B/:Enum<enum(On|Off)>":              "/a\\<b/:enum<enum(On|Off)>",
+	} {
+		utils.AssertEqual(t, expected, toLowerRoute(pattern), pattern)
+	}
+}
+
+// go test -run Test_Path_UnknownConstraint
+func Test_Path_UnknownConstraint(t *testing.T) {
+	t.Parallel()
+	for pattern, name := range map[string]string{
+		"/api/v1/:param<int;bool((>":         "bool((",
+		"/api/v1/:param<int\\;range(10,30)>": "int\\;range",
+		"/api/v1/:param<slug>":               "slug",
+	} {
+		func() {
+			defer func() {
+				utils.AssertEqual(t, fmt.Sprintf("constraint: unknown constraint %q\n", name), recover())
+			}()
+			parseRoute(pattern)
+		}()
+	}
+}
+
+// go test -run Test_Path_MaxParams
+func Test_Path_MaxParams(t *testing.T) {
+	t.Parallel()
+	groups := "<regex(" + strings.Repeat("\\(a\\)", maxParams) + ")>"
+	utils.AssertEqual(t, maxParams, len(parseRoute("/:p"+"<regex("+strings.Repeat("\\(a\\)", maxParams-1)+")>").params))
+
+	defer func() {
+		utils.AssertEqual(t, fmt.Sprintf("route: %q has %d parameters including capture groups, the maximum is %d\n", "/:p"+groups, maxParams+1, maxParams), recover())
+	}()
+	parseRoute("/:p" + groups)
+}
+
 // go test -race -run Test_Path_matchParams
 func Benchmark_Path_matchParams(t *testing.B) {
 	type testparams struct {
@@ -745,11 +857,6 @@ func Benchmark_Path_matchParams(t *testing.B) {
 		{url: "/api/v1/peach", params: []string{"peach"}, match: true},
 		{url: "/api/v1/p34ch", params: []string{"p34ch"}, match: false},
 	})
-	benchCase("/api/v1/:param<int;bool((>", []testparams{
-		{url: "/api/v1/entity", params: []string{"entity"}, match: false},
-		{url: "/api/v1/8728382", params: []string{"8728382"}, match: true},
-		{url: "/api/v1/true", params: []string{"true"}, match: false},
-	})
 	benchCase("/api/v1/:param<int;max(3000)>", []testparams{
 		{url: "/api/v1/entity", params: []string{"entity"}, match: false},
 		{url: "/api/v1/8728382", params: []string{"8728382"}, match: false},
@@ -768,12 +875,6 @@ func Benchmark_Path_matchParams(t *testing.B) {
 		{url: "/api/v1/25", params: []string{"25"}, match: true},
 		{url: "/api/v1/true", params: []string{"true"}, match: false},
 	})
-	benchCase("/api/v1/:param<int\\;range(10,30)>", []testparams{
-		{url: "/api/v1/entity", params: []string{"entity"}, match: true},
-		{url: "/api/v1/87283827683", params: []string{"87283827683"}, match: true},
-		{url: "/api/v1/25", params: []string{"25"}, match: true},
-		{url: "/api/v1/true", params: []string{"true"}, match: true},
-	})
 	benchCase("/api/v1/:param<range(10\\,30,1500)>", []testparams{
 		{url: "/api/v1/entity", params: []string{"entity"}, match: false},
 		{url: "/api/v1/87283827683", params: []string{"8728382"}, match: false},
@@ -782,3 +883,16 @@ func Benchmark_Path_matchParams(t *testing.B) {
 		{url: "/api/v1/true", params: []string{"true"}, match: false},
 	})
 }
+
+// go test -v -run=^$ -bench=Benchmark_Path_RegexConstraint -benchmem -count=4
+func Benchmark_Path_RegexConstraint(b *testing.B) {
+	rp := parseRoute("/api/v1/:param<regex(p\\([a\\-z]\\+\\)ch)>")
+	var ctxParams [maxParams]string
+	var match bool
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		match = rp.getMatch("/api/v1/peach", "/api/v1/peach", &ctxParams, false)
+	}
+	utils.AssertEqual(b, true, match)
+}
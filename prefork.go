@@ -15,8 +15,9 @@ import (
 )
 
 const (
-	envPreforkChildKey = "FIBER_PREFORK_CHILD"
-	envPreforkChildVal = "1"
+	envPreforkChildKey   = "FIBER_PREFORK_CHILD"
+	envPreforkChildVal   = "1"
+	envPreforkChildIDKey = "FIBER_PREFORK_CHILD_ID"
 )
 
 var testPreforkMaster = false
@@ -27,6 +28,25 @@ func IsChild() bool {
 	return os.Getenv(envPreforkChildKey) == envPreforkChildVal
 }
 
+// IsChild determines if the current process is a child of Prefork,
+// it's false if Prefork is disabled for the app.
+func (app *App) IsChild() bool {
+	return app.config.Prefork && IsChild()
+}
+
+// ChildID returns the index of the current child process of Prefork, starting at 0,
+// e.g. for logging. It's -1 in the master process or if Prefork is disabled for the app.
+func (app *App) ChildID() int {
+	if !app.IsChild() {
+		return -1
+	}
+	id, err := strconv.Atoi(os.Getenv(envPreforkChildIDKey))
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
 // prefork manages child processes to make use of the OS REUSEPORT or REUSEADDR feature
 func (app *App) prefork(network, addr string, tlsConfig *tls.Config) (err error) {
 	// 👶 child process 👶
@@ -93,6 +113,7 @@ func (app *App) prefork(network, addr string, tlsConfig *tls.Config) (err error)
 		// add fiber prefork child flag into child proc env
 		cmd.Env = append(os.Environ(),
 			fmt.Sprintf("%s=%s", envPreforkChildKey, envPreforkChildVal),
+			fmt.Sprintf("%s=%d", envPreforkChildIDKey, i),
 		)
 		if err = cmd.Start(); err != nil {
 			return fmt.Errorf("failed to start a child prefork process, error: %v", err)
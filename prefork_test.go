@@ -99,3 +99,23 @@ func teardownIsChild(t *testing.T) {
 
 	utils.AssertEqual(t, nil, os.Setenv(envPreforkChildKey, ""))
 }
+
+// go test -run Test_App_Prefork_ChildID
+func Test_App_Prefork_ChildID(t *testing.T) {
+	app := New(Config{Prefork: true})
+	utils.AssertEqual(t, false, app.IsChild())
+	utils.AssertEqual(t, -1, app.ChildID())
+
+	setupIsChild(t)
+	defer teardownIsChild(t)
+	utils.AssertEqual(t, nil, os.Setenv(envPreforkChildIDKey, "3"))
+	defer os.Unsetenv(envPreforkChildIDKey)
+
+	utils.AssertEqual(t, true, app.IsChild())
+	utils.AssertEqual(t, 3, app.ChildID())
+
+	// single-process mode
+	app = New()
+	utils.AssertEqual(t, false, app.IsChild())
+	utils.AssertEqual(t, -1, app.ChildID())
+}
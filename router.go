@@ -5,7 +5,13 @@
 package fiber
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"mime/multipart"
 	"sort"
 	"strconv"
 	"strings"
@@ -31,6 +37,7 @@ type Router interface {
 	Patch(path string, handlers ...Handler) Router
 
 	Add(method, path string, handlers ...Handler) Router
+	AddMethods(methods []string, path string, handlers ...Handler) Router
 	Static(prefix, root string, config ...Static) Router
 	All(path string, handlers ...Handler) Router
 
@@ -41,6 +48,12 @@ type Router interface {
 	Mount(prefix string, fiber *App) Router
 
 	Name(name string) Router
+
+	BodyLimit(limit int) Router
+
+	MaxFiles(n int) Router
+
+	DisableRecover() Router
 }
 
 // Route is a struct that holds all metadata for each registered handler
@@ -52,6 +65,13 @@ type Route struct {
 	root        bool        // Path equals '/'
 	path        string      // Prettified path
 	routeParser routeParser // Parameter parser
+	host        string      // Host pattern, empty if the route matches all hosts
+	hostParser  routeParser // Host parameter parser
+	bodyLimit   int         // Body limit of the route, 0 inherits the body limit of the app
+	noRecover   bool        // Panics of the route are not recovered, see DisableRecover
+	maxFiles    int         // Maximum number of files of multipart requests, 0 for no limit
+
+	customConstraints map[string]CustomConstraint // Custom constraints the path was parsed with
 
 	// Public fields
 	Method   string    `json:"method"` // HTTP method
@@ -61,17 +81,39 @@ type Route struct {
 	Handlers []Handler `json:"-"`      // Ctx handlers
 }
 
+// RecoverDisabled reports whether panics of the route must not be recovered,
+// e.g. by the recover middleware, see DisableRecover
+func (r *Route) RecoverDisabled() bool {
+	return r.noRecover
+}
+
+// matchHost checks if the request host matches the host pattern of the route,
+// the host parameter values are stored after the path parameter values
+func (r *Route) matchHost(c *Ctx) bool {
+	if r.host == "" {
+		return true
+	}
+	host := utils.ToLower(c.Hostname())
+	// strip the port
+	if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+		host = host[:i]
+	}
+	var values [maxParams]string
+	if !r.hostParser.getMatch(host, host, &values, false) {
+		return false
+	}
+	copy(c.values[len(r.routeParser.params):], values[:len(r.hostParser.params)])
+	return true
+}
+
 func (r *Route) match(detectionPath, path string, params *[maxParams]string) (match bool) {
 	// root detectionPath check
 	if r.root && detectionPath == "/" {
 		return true
 		// '*' wildcard matches any detectionPath
 	} else if r.star {
-		if len(path) > 1 {
-			params[0] = path[1:]
-		} else {
-			params[0] = ""
-		}
+		// the wildcard captures the remaining path without leading slashes
+		params[0] = utils.TrimLeft(path, '/')
 		return true
 	}
 	// Does this route have parameters
@@ -112,8 +154,8 @@ func (app *App) next(c *Ctx) (match bool, err error) {
 		// Get *Route
 		route := tree[c.indexRoute]
 
-		// Check if it matches the request path
-		match = route.match(c.detectionPath, c.path, &c.values)
+		// Check if it matches the request path and host
+		match = route.match(c.detectionPath, c.path, &c.values) && route.matchHost(c)
 
 		// No match, next route
 		if !match {
@@ -138,13 +180,33 @@ func (app *App) next(c *Ctx) (match bool, err error) {
 
 	// If no match, scan stack again if other methods match the request
 	// Moved from app.handler because middleware may break the route chain
+	status := StatusNotFound
 	if !c.matched && methodExist(c) {
 		err = ErrMethodNotAllowed
+		status = StatusMethodNotAllowed
+	}
+
+	// Let the custom handler answer the unmatched request, the Allow header is already set
+	if app.config.NotFoundHandler != nil {
+		c.Status(status)
+		err = app.config.NotFoundHandler(c)
 	}
 	return
 }
 
 func (app *App) handler(rctx *fasthttp.RequestCtx) {
+	// Count the request for app.Stats, the fasthttp request stays valid after the Ctx is released
+	defer app.requestDone(rctx)
+	// Admit the request by its own position, a shed request leaves the count right away,
+	// so that it doesn't count against other requests while it is answered
+	shed := false
+	if n := atomic.AddInt32(&app.inFlight, 1); app.config.MaxConcurrentRequests > 0 && n > int32(app.config.MaxConcurrentRequests) {
+		shed = true
+		atomic.AddInt32(&app.inFlight, -1)
+	} else {
+		defer atomic.AddInt32(&app.inFlight, -1)
+	}
+
 	// Acquire Ctx with fasthttp request from pool
 	c := app.AcquireCtx(rctx)
 
@@ -155,15 +217,92 @@ func (app *App) handler(rctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	// Find match in stack
-	match, err := app.next(c)
+	// reject overly long URLs before routing
+	if app.config.MaxURLLength > 0 && len(rctx.Request.RequestURI()) > app.config.MaxURLLength {
+		if catch := app.ErrorHandler(c, ErrRequestURITooLong); catch != nil {
+			_ = c.SendStatus(StatusRequestURITooLong)
+		}
+		app.ReleaseCtx(c)
+		return
+	}
+
+	// shed load instead of queueing when too many requests are in flight
+	if shed {
+		c.Set(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(app.config.ConcurrencyRetryAfter.Seconds()))))
+		if catch := app.ErrorHandler(c, ErrServiceUnavailable); catch != nil {
+			_ = c.SendStatus(StatusServiceUnavailable)
+		}
+		app.ReleaseCtx(c)
+		return
+	}
+
+	// reject requests with ambiguous body framing before anything else
+	if app.config.RejectConflictingContentLength && hasConflictingContentLength(c.fasthttp.Request.Header.RawHeaders()) {
+		c.fasthttp.SetConnectionClose()
+		if catch := app.ErrorHandler(c, ErrBadRequest); catch != nil {
+			_ = c.SendStatus(StatusBadRequest)
+		}
+		app.ReleaseCtx(c)
+		return
+	}
+
+	// emulate other methods with POST requests before routing, the header doesn't need the body
+	if app.config.MethodOverride.Enable {
+		app.overrideMethod(c)
+	}
+
+	// reject too large bodies and too many files before any handler is executed
+	if app.hasRouteBodyLimit || app.hasRouteMaxFiles {
+		route := app.firstRoute(c)
+		if app.hasRouteBodyLimit && !app.checkBodyLimit(c, route) {
+			if catch := app.ErrorHandler(c, ErrRequestEntityTooLarge); catch != nil {
+				_ = c.SendStatus(StatusRequestEntityTooLarge)
+			}
+			app.ReleaseCtx(c)
+			return
+		}
+		if app.hasRouteMaxFiles {
+			if err := app.checkMaxFiles(c, route); err != nil {
+				if catch := app.ErrorHandler(c, err); catch != nil {
+					_ = c.SendStatus(err.Code)
+				}
+				app.ReleaseCtx(c)
+				return
+			}
+		}
+	}
+
+	// the form field is read after the limits have been checked
+	if app.config.MethodOverride.Enable {
+		app.overrideMethodForm(c)
+	}
+
+	// answer CORS preflight requests without invoking handlers
+	if app.config.CORSPreflight.Enable && app.preflight(c) {
+		app.ReleaseCtx(c)
+		return
+	}
+
+	var (
+		match bool
+		err   error
+	)
+	if app.preRoute != nil {
+		// Run the pre-routing handlers, c.Next() continues with the router
+		c.route = app.preRoute
+		err = app.preRoute.Handlers[0](c)
+		match = c.route != app.preRoute
+	} else {
+		// Find match in stack
+		match, err = app.next(c)
+	}
 	if err != nil {
 		if catch := c.app.ErrorHandler(c, err); catch != nil {
 			_ = c.SendStatus(StatusInternalServerError)
 		}
 	}
 	// Generate ETag if enabled
-	if match && app.config.ETag {
+	if match && app.config.ETag && !c.skipETag {
 		setETag(c, false)
 	}
 
@@ -171,12 +310,198 @@ func (app *App) handler(rctx *fasthttp.RequestCtx) {
 	app.ReleaseCtx(c)
 }
 
+// firstRoute returns the first matching route of the request, which is not a middleware,
+// or nil if no route matches
+func (app *App) firstRoute(c *Ctx) *Route {
+	tree, ok := app.treeStack[c.methodINT][c.treePath]
+	if !ok {
+		tree = app.treeStack[c.methodINT][""]
+	}
+	var values [maxParams]string
+	for _, route := range tree {
+		if !route.use && route.match(c.detectionPath, c.path, &values) && route.matchHost(c) {
+			return route
+		}
+	}
+	return nil
+}
+
+// checkBodyLimit reports whether the request body fits into the body limit of the given route
+// or the body limit of the app. The Content-Length header is checked, so that with
+// StreamRequestBody too large bodies are rejected without reading them. Otherwise fasthttp
+// has already read the body, up to the limit of the route, when the check runs.
+func (app *App) checkBodyLimit(c *Ctx, route *Route) bool {
+	limit := app.config.BodyLimit
+	if route != nil && route.bodyLimit != 0 {
+		limit = route.bodyLimit
+	}
+
+	size := c.fasthttp.Request.Header.ContentLength()
+	if size < 0 && !app.config.StreamRequestBody {
+		size = len(c.fasthttp.Request.Body())
+	}
+	if limit < 0 {
+		return size <= 0
+	}
+	return size <= limit
+}
+
+// ErrTooManyFiles is returned to the error handler if a multipart request contains
+// more files than allowed by the route, see MaxFiles.
+var ErrTooManyFiles = NewError(StatusBadRequest, "multipart: too many files")
+
+// checkMaxFiles returns ErrTooManyFiles if a multipart request contains more files than allowed by the given route.
+// Without a body stream, fasthttp has already read the body and usually parsed the form, so the files
+// of the form are counted, which is kept for the handler. With StreamRequestBody and
+// DisablePreParseMultipartForm the streamed parts are read one by one, so that the body isn't read
+// beyond the first file above the limit, an accepted body is buffered for the handler. The buffered
+// body is bounded by the body limit of the route, larger bodies return ErrRequestEntityTooLarge.
+// Malformed bodies are left to the handler.
+func (app *App) checkMaxFiles(c *Ctx, route *Route) *Error {
+	if route == nil || route.maxFiles == 0 {
+		return nil
+	}
+	ctype, params, err := mime.ParseMediaType(app.getString(c.fasthttp.Request.Header.ContentType()))
+	if err != nil || ctype != MIMEMultipartForm || params["boundary"] == "" {
+		return nil
+	}
+	limit := route.maxFiles
+	if limit < 0 {
+		limit = 0
+	}
+	if !c.fasthttp.Request.IsBodyStream() {
+		form, err := c.fasthttp.MultipartForm()
+		if err != nil {
+			return nil
+		}
+		files := 0
+		for _, headers := range form.File {
+			files += len(headers)
+		}
+		if files > limit {
+			return ErrTooManyFiles
+		}
+		return nil
+	}
+
+	bodyLimit := app.config.BodyLimit
+	if route.bodyLimit != 0 {
+		bodyLimit = route.bodyLimit
+	}
+	if bodyLimit < 0 {
+		bodyLimit = 0
+	}
+	// at most one byte above the limit is buffered, so that too large bodies can be detected
+	var consumed bytes.Buffer
+	stream := io.TeeReader(io.LimitReader(c.fasthttp.RequestBodyStream(), int64(bodyLimit)+1), &consumed)
+	reader := multipart.NewReader(stream, params["boundary"])
+	files := 0
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		if part.FileName() != "" {
+			files++
+			if files > limit {
+				return ErrTooManyFiles
+			}
+		}
+	}
+	// the stream can't be rewound, so the handler gets the buffered body
+	_, _ = io.Copy(ioutil.Discard, stream)
+	if consumed.Len() > bodyLimit {
+		return ErrRequestEntityTooLarge
+	}
+	c.fasthttp.Request.SetBodyRaw(consumed.Bytes())
+	return nil
+}
+
+// overrideMethod replaces the method of a POST request by the method of the
+// override header, unknown methods keep the original method
+func (app *App) overrideMethod(c *Ctx) {
+	if c.methodINT != methodInt(MethodPost) {
+		return
+	}
+	if method := c.Get(app.config.MethodOverride.Header); method != "" {
+		c.Method(method)
+	}
+}
+
+// overrideMethodForm replaces the method of a POST request without override header by the
+// method of the form field. Only urlencoded bodies are read, so that multipart bodies are
+// neither parsed nor consumed before the handler.
+func (app *App) overrideMethodForm(c *Ctx) {
+	if c.methodINT != methodInt(MethodPost) || len(c.fasthttp.Request.Header.Peek(app.config.MethodOverride.Header)) > 0 {
+		return
+	}
+	ctype := utils.ToLower(utils.UnsafeString(c.fasthttp.Request.Header.ContentType()))
+	if !strings.HasPrefix(utils.ParseVendorSpecificContentType(ctype), MIMEApplicationForm) {
+		return
+	}
+	if method := c.fasthttp.PostArgs().Peek(app.config.MethodOverride.FormField); len(method) > 0 {
+		c.Method(c.app.getString(method))
+	}
+}
+
+// preflight answers a CORS preflight request if routes for other methods match
+// the path and reports whether the request has been answered. Paths with an
+// OPTIONS route are left to its handler.
+func (app *App) preflight(c *Ctx) bool {
+	if c.methodINT != methodInt(MethodOptions) ||
+		len(c.fasthttp.Request.Header.Peek(HeaderAccessControlRequestMethod)) == 0 {
+		return false
+	}
+	origin := c.Get(HeaderOrigin)
+	if origin == "" || app.firstRoute(c) != nil {
+		return false
+	}
+	// methodExist sets the Allow header to the methods of the matching routes
+	exist := methodExist(c)
+	c.indexRoute = -1
+	if !exist {
+		return false
+	}
+
+	cfg := app.config.CORSPreflight
+	c.Vary(HeaderOrigin, HeaderAccessControlRequestMethod, HeaderAccessControlRequestHeaders)
+	allowed := ""
+	for _, o := range cfg.AllowOrigins {
+		if o == "*" || utils.EqualFold(o, origin) {
+			// the wildcard is never combined with credentials, see New
+			allowed = o
+			break
+		}
+	}
+	if allowed != "" {
+		if allowed == "*" {
+			c.Set(HeaderAccessControlAllowOrigin, "*")
+		} else {
+			c.Set(HeaderAccessControlAllowOrigin, origin)
+		}
+		if cfg.AllowCredentials {
+			c.Set(HeaderAccessControlAllowCredentials, "true")
+		}
+		c.Set(HeaderAccessControlAllowMethods, string(c.fasthttp.Response.Header.Peek(HeaderAllow)))
+		if len(cfg.AllowHeaders) > 0 {
+			c.Set(HeaderAccessControlAllowHeaders, strings.Join(cfg.AllowHeaders, ","))
+		} else if headers := c.Get(HeaderAccessControlRequestHeaders); headers != "" {
+			c.Set(HeaderAccessControlAllowHeaders, headers)
+		}
+		if cfg.MaxAge > 0 {
+			c.Set(HeaderAccessControlMaxAge, strconv.Itoa(cfg.MaxAge))
+		}
+	}
+	c.Status(StatusNoContent)
+	return true
+}
+
 func (app *App) addPrefixToRoute(prefix string, route *Route) *Route {
 	prefixedPath := getGroupPath(prefix, route.Path)
 	prettyPath := prefixedPath
 	// Case sensitive routing, all to lowercase
 	if !app.config.CaseSensitive {
-		prettyPath = utils.ToLower(prettyPath)
+		prettyPath = toLowerRoute(prettyPath)
 	}
 	// Strict routing, remove trailing slashes
 	if !app.config.StrictRouting && len(prettyPath) > 1 {
@@ -185,7 +510,9 @@ func (app *App) addPrefixToRoute(prefix string, route *Route) *Route {
 
 	route.Path = prefixedPath
 	route.path = RemoveEscapeChar(prettyPath)
-	route.routeParser = parseRoute(prettyPath)
+	// the constraints of the app the route was registered on are kept, so that a custom
+	// constraint with the same name can't change the meaning of a mounted route
+	route.routeParser = parseRoute(prettyPath, route.customConstraints)
 	route.root = false
 	route.star = false
 
@@ -202,8 +529,15 @@ func (app *App) copyRoute(route *Route) *Route {
 		// Path data
 		path:        route.path,
 		routeParser: route.routeParser,
+		host:        route.host,
+		hostParser:  route.hostParser,
+		bodyLimit:   route.bodyLimit,
+		noRecover:   route.noRecover,
+		maxFiles:    route.maxFiles,
 		Params:      route.Params,
 
+		customConstraints: route.customConstraints,
+
 		// Public data
 		Path:     route.Path,
 		Method:   route.Method,
@@ -212,15 +546,47 @@ func (app *App) copyRoute(route *Route) *Route {
 }
 
 func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
+	return app.registerHost("", method, pathRaw, handlers...)
+}
+
+// checkMethods panics if one of the HTTP methods is invalid
+func checkMethods(methods []string) {
+	if len(methods) == 0 {
+		panic("add: missing http methods\n")
+	}
+	for _, method := range methods {
+		if methodInt(utils.ToUpper(method)) == -1 {
+			panic(fmt.Sprintf("add: invalid http method %s\n", method))
+		}
+	}
+}
+
+// registerHost registers a route that only matches requests for the given host pattern,
+// an empty host pattern matches all hosts
+func (app *App) registerHost(host, method, pathRaw string, handlers ...Handler) Router {
+	routes, err := app.newRoutes(host, method, pathRaw, handlers)
+	if err != nil {
+		panic(err.Error() + "\n")
+	}
+	if err := app.addRoutes(routes); err != nil {
+		panic(err)
+	}
+	// Increment global handler count
+	atomic.AddUint32(&app.handlersCount, uint32(len(handlers)))
+	return app
+}
+
+// newRoutes validates and parses the route, a middleware route is returned for every HTTP method
+func (app *App) newRoutes(host, method, pathRaw string, handlers []Handler) ([]*Route, error) {
 	// Uppercase HTTP methods
 	method = utils.ToUpper(method)
 	// Check if the HTTP method is valid unless it's USE
 	if method != methodUse && methodInt(method) == -1 {
-		panic(fmt.Sprintf("add: invalid http method %s\n", method))
+		return nil, fmt.Errorf("add: invalid http method %s", method)
 	}
 	// A route requires atleast one ctx handler
 	if len(handlers) == 0 {
-		panic(fmt.Sprintf("missing handler in route: %s\n", pathRaw))
+		return nil, fmt.Errorf("missing handler in route: %s", pathRaw)
 	}
 	// Cannot have an empty path
 	if pathRaw == "" {
@@ -234,7 +600,7 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 	pathPretty := pathRaw
 	// Case sensitive routing, all to lowercase
 	if !app.config.CaseSensitive {
-		pathPretty = utils.ToLower(pathPretty)
+		pathPretty = toLowerRoute(pathPretty)
 	}
 	// Strict routing, remove trailing slashes
 	if !app.config.StrictRouting && len(pathPretty) > 1 {
@@ -247,8 +613,14 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 	// Is path a root slash?
 	isRoot := pathPretty == "/"
 	// Parse path parameters
-	parsedRaw := parseRoute(pathRaw)
-	parsedPretty := parseRoute(pathPretty)
+	parsedRaw, err := tryParseRoute(pathRaw, app.customConstraints)
+	if err != nil {
+		return nil, err
+	}
+	parsedPretty, err := tryParseRoute(pathPretty, app.customConstraints)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create route metadata without pointer
 	route := Route{
@@ -262,30 +634,56 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 		routeParser: parsedPretty,
 		Params:      parsedRaw.params,
 
+		customConstraints: app.customConstraints,
+
 		// Public data
 		Path:     pathRaw,
 		Method:   method,
 		Handlers: handlers,
 	}
-	// Increment global handler count
-	atomic.AddUint32(&app.handlersCount, uint32(len(handlers)))
+	route.setHost(host)
+	// Check for a duplicate route before anything is registered
+	if app.config.DetectRouteConflicts && !isUse {
+		if err := app.checkRouteConflict(&route); err != nil {
+			return nil, err
+		}
+	}
 
 	// Middleware route matches all HTTP methods
 	if isUse {
-		// Add route to all HTTP methods stack
+		routes := make([]*Route, 0, len(intMethod))
 		for _, m := range intMethod {
 			// Create a route copy to avoid duplicates during compression
 			r := route
-			app.addRoute(m, &r)
+			r.Method = m
+			routes = append(routes, &r)
 		}
-	} else {
-		// Add route to stack
-		app.addRoute(method, &route)
+		return routes, nil
 	}
-	return app
+	return []*Route{&route}, nil
+}
+
+// checkRouteConflict returns an error if a route with the same method, normalized path and host is registered
+func (app *App) checkRouteConflict(route *Route) error {
+	for _, r := range app.stack[methodInt(route.Method)] {
+		if !r.use && r.path == route.path && r.host == route.host {
+			return fmt.Errorf("add: route conflict, %s %s is already registered", route.Method, route.Path)
+		}
+	}
+	return nil
+}
+
+// setHost restricts the route to the given host pattern and adds the host parameters to the route parameters
+func (r *Route) setHost(host string) {
+	if host == "" {
+		return
+	}
+	r.host = utils.ToLower(host)
+	r.hostParser = parseRoute(r.host)
+	r.Params = append(append(make([]string, 0, len(r.Params)+len(r.hostParser.params)), r.Params...), r.hostParser.params...)
 }
 
-func (app *App) registerStatic(prefix, root string, config ...Static) Router {
+func (app *App) registerStatic(host, prefix, root string, config ...Static) Router {
 	// For security we want to restrict to the current work directory.
 	if root == "" {
 		root = "."
@@ -409,22 +807,55 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 		Path:     prefix,
 		Handlers: []Handler{handler},
 	}
+	route.setHost(host)
 	// Increment global handler count
 	atomic.AddUint32(&app.handlersCount, 1)
 	// Add route to stack
-	app.addRoute(MethodGet, &route)
+	if err := app.addRoute(MethodGet, &route); err != nil {
+		panic(err)
+	}
 	// Add HEAD route
-	app.addRoute(MethodHead, &route)
+	if err := app.addRoute(MethodHead, &route); err != nil {
+		panic(err)
+	}
 	return app
 }
 
-func (app *App) addRoute(method string, route *Route) {
+// addRoute runs the OnRoute hooks and adds the route to the stack of the method if they pass
+func (app *App) addRoute(method string, route *Route) error {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	if err := app.hooks.executeOnRouteHooks(*route); err != nil {
+		return err
+	}
+	app.stackRoute(method, route)
+	return nil
+}
+
+// addRoutes adds the routes to the stacks of their methods, the OnRoute hooks of all routes
+// run before, so that a failing hook leaves the stacks unchanged
+func (app *App) addRoutes(routes []*Route) error {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	for _, route := range routes {
+		if err := app.hooks.executeOnRouteHooks(*route); err != nil {
+			return err
+		}
+	}
+	for _, route := range routes {
+		app.stackRoute(route.Method, route)
+	}
+	return nil
+}
+
+// stackRoute adds the route to the stack of the method, the caller has to hold app.mutex
+func (app *App) stackRoute(method string, route *Route) {
 	// Get unique HTTP method identifier
 	m := methodInt(method)
 
 	// prevent identically route registration
 	l := len(app.stack[m])
-	if l > 0 && app.stack[m][l-1].Path == route.Path && route.use == app.stack[m][l-1].use {
+	if l > 0 && app.stack[m][l-1].Path == route.Path && route.use == app.stack[m][l-1].use && route.host == app.stack[m][l-1].host {
 		preRoute := app.stack[m][l-1]
 		preRoute.Handlers = append(preRoute.Handlers, route.Handlers...)
 	} else {
@@ -436,12 +867,11 @@ func (app *App) addRoute(method string, route *Route) {
 		app.routesRefreshed = true
 	}
 
-	app.mutex.Lock()
 	app.latestRoute = route
-	if err := app.hooks.executeOnRouteHooks(*route); err != nil {
-		panic(err)
-	}
-	app.mutex.Unlock()
+	app.latestRoutes = nil
+	// keep the body limit and the file limit of mounted routes
+	app.setRouteBodyLimit(route, route.bodyLimit)
+	app.setRouteMaxFiles(route, route.maxFiles)
 }
 
 // buildTree build the prefix tree from the previously registered routes
@@ -12,6 +12,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http/httptest"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -102,6 +104,62 @@ func Test_Route_Match_Star(t *testing.T) {
 	utils.AssertEqual(t, [maxParams]string{}, params)
 }
 
+func Test_Route_Match_WildcardValue(t *testing.T) {
+	testWildcard := func(app *App, url, expected string) {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, url, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, expected, app.getString(body), url)
+	}
+	handler := func(c *Ctx) error {
+		return c.SendString(c.Params("*"))
+	}
+
+	app := New()
+	app.Get("/static/*", handler)
+	app.Get("/*", handler)
+
+	testWildcard(app, "/static", "")
+	testWildcard(app, "/static/", "")
+	testWildcard(app, "/static/css/app.css", "css/app.css")
+	testWildcard(app, "/static//css/app.css", "css/app.css")
+	testWildcard(app, "/static/a%20b/c", "a%20b/c")
+	testWildcard(app, "//favicon.ico", "favicon.ico")
+
+	app = New(Config{UnescapePath: true})
+	app.Get("/static/*", handler)
+
+	testWildcard(app, "/static/a%20b/c", "a b/c")
+}
+
+func Test_Route_Match_NamedWildcard(t *testing.T) {
+	app := New()
+
+	app.Get("/files/:path*", func(c *Ctx) error {
+		return c.SendString(c.Params("path"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/files/docs/api/readme.md", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "docs/api/readme.md", app.getString(body))
+
+	// without parameter
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/files", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "", app.getString(body))
+}
+
 func Test_Route_Match_Root(t *testing.T) {
 	app := New()
 
@@ -145,6 +203,211 @@ func Test_Route_Match_Parser(t *testing.T) {
 	utils.AssertEqual(t, "test", app.getString(body))
 }
 
+func Test_Route_Match_OptionalParam(t *testing.T) {
+	app := New()
+
+	app.Get("/users/:id?", func(c *Ctx) error {
+		return c.SendString("users:" + c.Params("id"))
+	})
+	app.Get("/orders/:id<int>?", func(c *Ctx) error {
+		return c.SendString("orders:" + c.Params("id"))
+	})
+
+	testOptional := func(url string, status int, expected string) {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, url, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, status, resp.StatusCode, url)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		if status == StatusOK {
+			utils.AssertEqual(t, expected, app.getString(body), url)
+		}
+	}
+
+	// present
+	testOptional("/users/123", StatusOK, "users:123")
+	testOptional("/orders/123", StatusOK, "orders:123")
+	// absent
+	testOptional("/users", StatusOK, "users:")
+	testOptional("/users/", StatusOK, "users:")
+	testOptional("/orders", StatusOK, "orders:")
+	// constraint is still checked when present
+	testOptional("/orders/abc", StatusNotFound, "")
+}
+
+func Test_Route_Match_EnumConstraint(t *testing.T) {
+	app := New()
+
+	app.Get("/orders/:status<enum(active|archived)>", func(c *Ctx) error {
+		return c.SendString("status:" + c.Params("status"))
+	})
+	app.Get("/orders/:id", func(c *Ctx) error {
+		return c.SendString("id:" + c.Params("id"))
+	})
+
+	for url, expected := range map[string]string{
+		"/orders/active":   "status:active",
+		"/orders/archived": "status:archived",
+		// falls through to the next route
+		"/orders/Active": "id:Active",
+		"/orders/123":    "id:123",
+	} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, url, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, url)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, expected, app.getString(body), url)
+	}
+}
+
+// go test -run Test_Route_Match_RegexCaptures
+func Test_Route_Match_RegexCaptures(t *testing.T) {
+	t.Parallel()
+	// the "(?P<name>" syntax of named groups must not be lowercased by case-insensitive routing
+	for _, config := range []Config{{}, {CaseSensitive: true}} {
+		app := New(config)
+
+		app.Get("/API/:resource/:ver<regex(v\\(\\?P\\<major\\>[0-9]+\\)_\\(\\?P\\<minor\\>[0-9]+\\))>", func(c *Ctx) error {
+			return c.SendString(c.Params("ver") + ":" + c.Params("major") + ":" + c.Params("minor") + ":" + c.Params("resource"))
+		})
+		app.Get("/files/:name<regex(\\(\\?P\\<base\\>[A-Z]+\\)txt)>?", func(c *Ctx) error {
+			return c.SendString(c.Params("name") + ":" + c.Params("base", "none"))
+		})
+
+		for url, expected := range map[string]string{
+			"/API/users/v1_2":   "v1_2:1:2:users",
+			"/API/posts/v10_20": "v10_20:10:20:posts",
+			"/files/READMEtxt":  "READMEtxt:README",
+			"/files":            ":none",
+		} {
+			resp, err := app.Test(httptest.NewRequest(MethodGet, url, nil))
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, StatusOK, resp.StatusCode, url)
+
+			body, err := ioutil.ReadAll(resp.Body)
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, expected, app.getString(body), url)
+		}
+
+		resp, err := app.Test(httptest.NewRequest(MethodGet, "/API/users/vx_2", nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+
+		// the regex is checked against the original case of the value
+		resp, err = app.Test(httptest.NewRequest(MethodGet, "/files/readmetxt", nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+
+		utils.AssertEqual(t, []string{"resource", "ver", "major", "minor"}, app.stack[methodInt(MethodGet)][0].Params)
+	}
+}
+
+// go test -run Test_Route_Match_RegexNumberedCaptures
+func Test_Route_Match_RegexNumberedCaptures(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	app.Get("/events/:date<regex(\\([0-9]{4}\\)\\-\\([0-9]{2}\\)\\-\\([0-9]{2}\\))>", func(c *Ctx) error {
+		return c.SendString(c.Params("date") + ":" + c.Params("date.1") + ":" + c.Params("date.2") + ":" + c.Params("date.3"))
+	})
+	// groups without captures keep the params unchanged
+	app.Get("/slugs/:slug<regex([a-z]+\\(\\?:\\-[a-z]+\\)*)>", func(c *Ctx) error {
+		return c.SendString(c.Params("slug") + ":" + c.Params("slug.1", "none"))
+	})
+
+	for url, expected := range map[string]string{
+		"/events/2022-10-15": "2022-10-15:2022:10:15",
+		"/slugs/hello-world": "hello-world:none",
+	} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, url, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, url)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, expected, app.getString(body), url)
+	}
+
+	utils.AssertEqual(t, []string{"date", "date.1", "date.2", "date.3"}, app.stack[methodInt(MethodGet)][0].Params)
+	utils.AssertEqual(t, []string{"slug"}, app.stack[methodInt(MethodGet)][1].Params)
+}
+
+// go test -run Test_Route_CustomConstraint
+func Test_Route_CustomConstraint(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.RegisterCustomConstraint("slug", func(param string, _ ...string) bool {
+		return regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`).MatchString(param)
+	})
+	app.RegisterCustomConstraint("code", func(param string, data ...string) bool {
+		min, _ := strconv.Atoi(data[0])
+		max, _ := strconv.Atoi(data[1])
+		return len(param) >= min && len(param) <= max
+	})
+	// built-in constraints take precedence
+	app.RegisterCustomConstraint("Int", func(param string, _ ...string) bool {
+		return true
+	})
+
+	handler := func(c *Ctx) error {
+		return c.SendString(c.Params("param"))
+	}
+	app.Get("/posts/:param<slug>", handler)
+	app.Get("/codes/:param<code(2,4)>", handler)
+	app.Get("/ids/:param<int>", handler)
+
+	sub := New()
+	sub.RegisterCustomConstraint("upper", func(param string, _ ...string) bool {
+		return strings.ToUpper(param) == param
+	})
+	sub.Get("/:param<upper>", handler)
+	// the mounted routes keep the constraint of the sub app
+	app.RegisterCustomConstraint("upper", func(param string, _ ...string) bool {
+		return false
+	})
+	app.Mount("/sub", sub)
+
+	testCases := []struct {
+		path string
+		code int
+	}{
+		{"/posts/hello-world", StatusOK},
+		{"/posts/hello--world", StatusNotFound},
+		{"/codes/abc", StatusOK},
+		{"/codes/abcde", StatusNotFound},
+		{"/ids/12", StatusOK},
+		{"/ids/abc", StatusNotFound},
+		{"/sub/ABC", StatusOK},
+		{"/sub/abc", StatusNotFound},
+	}
+	for _, tc := range testCases {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, tc.path, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.code, resp.StatusCode, tc.path)
+	}
+
+	// a name can't be registered twice
+	func() {
+		defer func() {
+			utils.AssertEqual(t, "constraint: custom constraint \"slug\" is already registered\n", recover())
+		}()
+		app.RegisterCustomConstraint("Slug", func(param string, _ ...string) bool {
+			return true
+		})
+	}()
+
+	// unknown constraints fail at registration
+	count := app.HandlersCount()
+	defer func() {
+		utils.AssertEqual(t, "constraint: unknown constraint \"ulid\"\n", recover())
+		utils.AssertEqual(t, count, app.HandlersCount())
+	}()
+	app.Get("/users/:param<ulid>", handler)
+}
+
 func Test_Route_Match_Middleware(t *testing.T) {
 	app := New()
 